@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"filesystem/pkg/approval"
+	"filesystem/pkg/config"
+)
+
+const (
+	// approvalsCommand is the subcommand that talks to a running server's
+	// approval queue
+	approvalsCommand = "approvals"
+
+	// approvalTimeout bounds a control request, including running an
+	// approved operation
+	approvalTimeout = 5 * time.Minute
+
+	// maxArgumentPreview is the longest argument value shown by list
+	maxArgumentPreview = 80
+)
+
+// runApprovals implements "approvals list|approve ID|deny ID" and returns
+// the process exit code
+func runApprovals(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet(approvalsCommand, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", "", "configuration file naming the approval socket (optional)")
+	socketPath := flags.String("socket", "", "path to the approval control socket (optional)")
+	full := flags.Bool("full", false, "show complete argument values when listing")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s %s [options] list | approve <id> | deny <id>\n", os.Args[0], approvalsCommand)
+		fmt.Fprintf(stderr, "\nOptions:\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitCodeError
+	}
+
+	socket, err := resolveApprovalSocket(*socketPath, *configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to load configuration: %v\n", err)
+		return exitCodeError
+	}
+
+	req, err := approvalRequest(flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		flags.Usage()
+		return exitCodeError
+	}
+
+	resp, err := approval.Send(socket, req, approvalTimeout)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitCodeError
+	}
+	if !resp.OK {
+		fmt.Fprintf(stderr, "Error: %s\n", resp.Error)
+		return exitCodeError
+	}
+
+	switch req.Action {
+	case approval.ActionList:
+		printOperations(stdout, resp.Operations, *full)
+	case approval.ActionApprove:
+		fmt.Fprintln(stdout, resp.Result)
+		if resp.IsError {
+			return exitCodeError
+		}
+	case approval.ActionDeny:
+		fmt.Fprintf(stdout, "Denied operation %s\n", req.ID)
+	}
+	return exitCodeSuccess
+}
+
+// resolveApprovalSocket picks the socket from the flag, then the
+// configuration file, then the default location
+func resolveApprovalSocket(socketPath, configPath string) (string, error) {
+	if socketPath != "" {
+		return socketPath, nil
+	}
+	if configPath != "" {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return "", err
+		}
+		return cfg.Approval.Socket, nil
+	}
+	return config.DefaultApprovalSocket(), nil
+}
+
+// approvalRequest builds the control request from the positional arguments
+func approvalRequest(args []string) (approval.Request, error) {
+	if len(args) == 0 {
+		return approval.Request{}, fmt.Errorf("an action is required")
+	}
+	switch action := args[0]; action {
+	case approval.ActionList:
+		if len(args) != 1 {
+			return approval.Request{}, fmt.Errorf("list takes no arguments")
+		}
+		return approval.Request{Action: action}, nil
+	case approval.ActionApprove, approval.ActionDeny:
+		if len(args) != 2 || args[1] == "" {
+			return approval.Request{}, fmt.Errorf("%s requires exactly one operation id", action)
+		}
+		return approval.Request{Action: action, ID: args[1]}, nil
+	default:
+		return approval.Request{}, fmt.Errorf("unknown action: %s", action)
+	}
+}
+
+// printOperations writes one block per pending operation with its
+// arguments, shortening long values such as file contents unless full
+func printOperations(w io.Writer, ops []approval.Operation, full bool) {
+	if len(ops) == 0 {
+		fmt.Fprintln(w, "No pending operations")
+		return
+	}
+	for _, op := range ops {
+		fmt.Fprintf(w, "%s  %s  expires %s\n", op.ID, op.Tool, op.Expires.Local().Format(time.RFC3339))
+		keys := make([]string, 0, len(op.Arguments))
+		for key := range op.Arguments {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(w, "    %s: %s\n", key, previewValue(op.Arguments[key], full))
+		}
+	}
+}
+
+// previewValue renders an argument value on one line
+func previewValue(value interface{}, full bool) string {
+	text, ok := value.(string)
+	if !ok {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprintf("%v", value)
+		}
+		text = string(data)
+	}
+	quoted := fmt.Sprintf("%q", text)
+	if !full && len(quoted) > maxArgumentPreview {
+		return fmt.Sprintf("%s... (%d bytes)", quoted[:maxArgumentPreview], len(text))
+	}
+	return quoted
+}
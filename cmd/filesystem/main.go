@@ -9,9 +9,11 @@ import (
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
 	"filesystem/internal/server"
 	"filesystem/pkg/config"
+	"filesystem/pkg/sandbox"
 	"filesystem/pkg/security"
 )
 
@@ -21,12 +23,32 @@ const (
 
 	// exitCodeError indicates error termination
 	exitCodeError = 1
+
+	// configWatchInterval is how often the configuration file is polled for changes
+	configWatchInterval = 2 * time.Second
+
+	// transportStopTimeout bounds the wait for the transport to stop once
+	// tool calls have drained
+	transportStopTimeout = 10 * time.Second
 )
 
 // main initializes and runs the secure filesystem MCP server
 func main() {
+	// Subcommands are dispatched before the server flags are parsed
+	if len(os.Args) > 1 && os.Args[1] == approvalsCommand {
+		os.Exit(runApprovals(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == tokenCommand {
+		os.Exit(runToken(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	var configPath string
+	var overrides flagOverrides
 	flag.StringVar(&configPath, "config", "", "path to configuration file (optional)")
+	flag.StringVar(&overrides.auditPath, "audit-log", "", "path to JSONL audit log of tool calls (optional)")
+	flag.BoolVar(&overrides.clientRoots, "client-roots", false, "limit each client to the roots it advertises (optional)")
+	flag.StringVar(&overrides.transport, "transport", "", "transport to serve: stdio, sse, http or unix (optional)")
+	flag.StringVar(&overrides.listen, "listen", "", "listen address of the sse and http transports, or socket path of the unix transport (optional)")
 	flag.Parse()
 
 	// Get allowed directories from command line arguments (compatible with TS version)
@@ -45,7 +67,7 @@ func main() {
 	} else if len(args) > 0 {
 		// Create configuration from command line arguments (TypeScript compatibility)
 		cfg = config.Default()
-		cfg.AllowedDirectories = args
+		cfg.AllowedDirectories = config.NewAllowedDirectories(args)
 
 		// Validate and normalize directories
 		if err := validateCommandLineDirectories(cfg); err != nil {
@@ -56,6 +78,8 @@ func main() {
 		// Show usage information
 		fmt.Fprintf(os.Stderr, "Usage: %s [options] <allowed-directory> [additional-directories...]\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "   or: %s -config <config-file>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "   or: %s %s [-socket <path>] list | approve <id> | deny <id>\n", os.Args[0], approvalsCommand)
+		fmt.Fprintf(os.Stderr, "   or: %s %s [-stdin]\n", os.Args[0], tokenCommand)
 		fmt.Fprintf(os.Stderr, "\nOptions:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExample:\n")
@@ -63,12 +87,19 @@ func main() {
 		os.Exit(exitCodeError)
 	}
 
+	// Command line flags take precedence over the configuration file
+	if err := overrides.apply(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		os.Exit(exitCodeError)
+	}
+
 	// Initialize structured logger per custom instructions
 	logger := initializeLogger(cfg.LogLevel)
 	logger.Info("Starting secure filesystem MCP server",
 		"version", cfg.Server.Version,
 		"config_source", getConfigSource(configPath, args),
-		"allowed_directories", cfg.AllowedDirectories)
+		"allowed_directories", cfg.DirectoryPaths(),
+		"deny_patterns", cfg.DenyPatterns)
 
 	// Create server instance with dependency injection
 	srv, err := server.New(cfg, logger)
@@ -77,6 +108,14 @@ func main() {
 		os.Exit(exitCodeError)
 	}
 
+	// Restrict the process before serving any request
+	if cfg.Landlock.Enabled {
+		if err := applySandbox(cfg, configPath, logger); err != nil {
+			logger.Error("Failed to apply Landlock sandbox", "error", err)
+			os.Exit(exitCodeError)
+		}
+	}
+
 	// Setup signal handling for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -84,6 +123,17 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// Reload on SIGHUP and whenever the configuration file changes
+	reloadChan := make(chan struct{}, 1)
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go forwardReloadSignals(ctx, hupChan, reloadChan)
+	if configPath != "" {
+		go config.Watch(ctx, configPath, configWatchInterval, func() {
+			requestReload(reloadChan)
+		})
+	}
+
 	// Start server in goroutine
 	errChan := make(chan error, 1)
 	go func() {
@@ -91,24 +141,53 @@ func main() {
 	}()
 
 	// Log startup complete to stderr (compatible with TS version)
-	fmt.Fprintf(os.Stderr, "Secure MCP Filesystem Server running on stdio\n")
-	fmt.Fprintf(os.Stderr, "Allowed directories: %v\n", cfg.AllowedDirectories)
+	switch cfg.Server.Transport {
+	case config.TransportStdio:
+		fmt.Fprintf(os.Stderr, "Secure MCP Filesystem Server running on stdio\n")
+	case config.TransportUnix:
+		fmt.Fprintf(os.Stderr, "Secure MCP Filesystem Server running on unix socket %s\n", cfg.Server.Unix.Path)
+	default:
+		fmt.Fprintf(os.Stderr, "Secure MCP Filesystem Server running on %s at %s\n", cfg.Server.Transport, cfg.Server.HTTP.Address)
+	}
+	fmt.Fprintf(os.Stderr, "Allowed directories: %v\n", cfg.DirectoryPaths())
 
-	// Wait for shutdown signal or error
-	select {
-	case sig := <-sigChan:
-		logger.Info("Received shutdown signal", "signal", sig)
-		cancel()
-	case err := <-errChan:
-		if err != nil {
-			logger.Error("Server error", "error", err)
-			cancel()
+	// Wait for shutdown signal or error, applying reloads in between
+	running := true
+	serving := true
+	for running {
+		select {
+		case <-reloadChan:
+			logger = reloadConfiguration(srv, configPath, overrides, logger)
+		case sig := <-sigChan:
+			logger.Info("Received shutdown signal", "signal", sig)
+			running = false
+		case err := <-errChan:
+			if err != nil {
+				logger.Error("Server error", "error", err)
+			}
+			running = false
+			serving = false
 		}
 	}
 
-	// Graceful shutdown
-	if err := srv.Shutdown(ctx); err != nil {
-		logger.Error("Server shutdown error", "error", err)
+	// Graceful shutdown: the transports keep running while calls in flight
+	// drain, and are stopped afterwards. A second signal skips the wait.
+	go func() {
+		sig := <-sigChan
+		logger.Warn("Received second shutdown signal; exiting now", "signal", sig)
+		os.Exit(exitCodeError)
+	}()
+	shutdownErr := srv.Shutdown(context.Background())
+	cancel()
+	if serving {
+		select {
+		case <-errChan:
+		case <-time.After(transportStopTimeout):
+			logger.Warn("Transport did not stop in time")
+		}
+	}
+	if shutdownErr != nil {
+		logger.Error("Server shutdown error", "error", shutdownErr)
 		os.Exit(exitCodeError)
 	}
 
@@ -127,7 +206,8 @@ func validateCommandLineDirectories(cfg *config.Config) error {
 	}
 
 	// Validate each directory
-	for i, dir := range cfg.AllowedDirectories {
+	for i, entry := range cfg.AllowedDirectories {
+		dir := entry.Path
 
 		// Expand home directory if needed
 		dir = security.ExpandHomePath(dir)
@@ -138,7 +218,7 @@ func validateCommandLineDirectories(cfg *config.Config) error {
 			return fmt.Errorf("failed to get absolute path for %s: %w", dir, err)
 		}
 
-		cfg.AllowedDirectories[i] = absDir
+		cfg.AllowedDirectories[i].Path = absDir
 		dir = absDir
 
 		// Check if directory exists and is accessible
@@ -155,6 +235,119 @@ func validateCommandLineDirectories(cfg *config.Config) error {
 	return nil
 }
 
+// applySandbox restricts the process with Landlock to the allowed
+// directories, the admin grant parents, the audit log directory and the
+// configuration file
+func applySandbox(cfg *config.Config, configPath string, logger *slog.Logger) error {
+	rules := make([]sandbox.Rule, 0, len(cfg.AllowedDirectories)+len(cfg.Admin.Parents)+2)
+	for _, dir := range cfg.AllowedDirectories {
+		rules = append(rules, sandbox.Rule{Path: dir.Path, Mode: dir.Mode})
+	}
+
+	// Directories granted at runtime lie beneath the admin parents
+	if cfg.Admin.Enabled {
+		for _, dir := range cfg.Admin.Parents {
+			rules = append(rules, sandbox.Rule{Path: dir.Path, Mode: dir.Mode})
+		}
+	}
+
+	// Rotation creates and renames files next to the audit log
+	if cfg.Audit.Path != "" {
+		auditDir, err := filepath.Abs(filepath.Dir(cfg.Audit.Path))
+		if err != nil {
+			return fmt.Errorf("failed to resolve audit log directory: %w", err)
+		}
+		rules = append(rules, sandbox.Rule{Path: auditDir, Mode: security.ModeReadWrite})
+	}
+
+	// Reload re-reads the configuration file in place
+	if configPath != "" {
+		rules = append(rules, sandbox.Rule{Path: configPath, Mode: security.ModeReadOnly})
+	}
+
+	_, err := sandbox.Restrict(sandbox.Options{
+		Rules:      rules,
+		MinABI:     cfg.Landlock.ABI,
+		BestEffort: cfg.Landlock.BestEffort,
+	}, logger)
+	return err
+}
+
+// forwardReloadSignals turns SIGHUP into reload requests until ctx is done
+func forwardReloadSignals(ctx context.Context, hupChan <-chan os.Signal, reloadChan chan<- struct{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hupChan:
+			requestReload(reloadChan)
+		}
+	}
+}
+
+// requestReload queues a reload unless one is already pending
+func requestReload(reloadChan chan<- struct{}) {
+	select {
+	case reloadChan <- struct{}{}:
+	default:
+	}
+}
+
+// reloadConfiguration re-reads the configuration file and applies it to the
+// running server. On any error the current configuration stays in effect.
+// It returns the logger to use from now on.
+func reloadConfiguration(srv *server.Server, configPath string, overrides flagOverrides, logger *slog.Logger) *slog.Logger {
+	if configPath == "" {
+		logger.Info("Reload requested but no configuration file is in use")
+		return logger
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		logger.Error("Configuration reload failed; keeping current configuration", "error", err)
+		return logger
+	}
+	if err := overrides.apply(cfg); err != nil {
+		logger.Error("Configuration reload failed; keeping current configuration", "error", err)
+		return logger
+	}
+
+	newLogger := initializeLogger(cfg.LogLevel)
+	if err := srv.Reload(cfg, newLogger); err != nil {
+		logger.Error("Configuration reload failed; keeping current configuration", "error", err)
+		return logger
+	}
+	return newLogger
+}
+
+// flagOverrides holds command line flags that override the configuration
+// file, including after a reload
+type flagOverrides struct {
+	auditPath   string
+	clientRoots bool
+	transport   string
+	listen      string
+}
+
+// apply sets the overridden values in cfg
+func (o flagOverrides) apply(cfg *config.Config) error {
+	if o.auditPath != "" {
+		cfg.Audit.Path = o.auditPath
+	}
+	if o.clientRoots {
+		cfg.ClientRoots.Enabled = true
+	}
+	if o.transport != "" {
+		cfg.Server.Transport = o.transport
+	}
+	if o.listen != "" && cfg.Server.Transport == config.TransportUnix {
+		cfg.Server.Unix.Path = o.listen
+	} else if o.listen != "" {
+		cfg.Server.HTTP.Address = o.listen
+	}
+	return cfg.Server.Validate()
+}
+
 // getConfigSource returns a string indicating how configuration was loaded
 func getConfigSource(configPath string, args []string) string {
 	if configPath != "" {
@@ -21,14 +21,14 @@ func TestValidateCommandLineDirectoriesDot(t *testing.T) {
 	t.Cleanup(func() { os.Chdir(wd) })
 
 	cfg := config.Default()
-	cfg.AllowedDirectories = []string{"."}
+	cfg.AllowedDirectories = config.NewAllowedDirectories([]string{"."})
 
 	if err := validateCommandLineDirectories(cfg); err != nil {
 		t.Fatalf("validate: %v", err)
 	}
 
 	expect, _ := filepath.Abs(".")
-	if len(cfg.AllowedDirectories) != 1 || cfg.AllowedDirectories[0] != expect {
+	if len(cfg.AllowedDirectories) != 1 || cfg.AllowedDirectories[0].Path != expect {
 		t.Fatalf("expected %s got %v", expect, cfg.AllowedDirectories)
 	}
 }
@@ -38,7 +38,7 @@ func TestValidateCommandLineDirectoriesNonexistent(t *testing.T) {
 	missing := filepath.Join(base, "no_such")
 
 	cfg := config.Default()
-	cfg.AllowedDirectories = []string{missing}
+	cfg.AllowedDirectories = config.NewAllowedDirectories([]string{missing})
 
 	if err := validateCommandLineDirectories(cfg); err == nil {
 		t.Fatalf("expected error for nonexistent directory")
@@ -53,7 +53,7 @@ func TestValidateCommandLineDirectoriesFile(t *testing.T) {
 	}
 
 	cfg := config.Default()
-	cfg.AllowedDirectories = []string{file}
+	cfg.AllowedDirectories = config.NewAllowedDirectories([]string{file})
 
 	if err := validateCommandLineDirectories(cfg); err == nil {
 		t.Fatalf("expected error for non-directory path")
@@ -62,7 +62,7 @@ func TestValidateCommandLineDirectoriesFile(t *testing.T) {
 
 func TestValidateCommandLineDirectoriesEmpty(t *testing.T) {
 	cfg := config.Default()
-	cfg.AllowedDirectories = []string{}
+	cfg.AllowedDirectories = config.NewAllowedDirectories([]string{})
 
 	if err := validateCommandLineDirectories(cfg); err == nil {
 		t.Fatalf("expected error for empty slice")
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filesystem/pkg/auth"
+)
+
+// tokenCommand is the subcommand that creates bearer tokens for the HTTP
+// transports
+const tokenCommand = "token"
+
+// runToken implements "token [-stdin]". It prints a new token and the hash
+// to configure for it, or only the hash of a token read from stdin, and
+// returns the process exit code.
+func runToken(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet(tokenCommand, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	fromStdin := flags.Bool("stdin", false, "hash a token read from standard input instead of creating one")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s %s [-stdin]\n", os.Args[0], tokenCommand)
+		fmt.Fprintf(stderr, "\nOptions:\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitCodeError
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return exitCodeError
+	}
+
+	if *fromStdin {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			fmt.Fprintf(stderr, "Failed to read token: %v\n", err)
+			return exitCodeError
+		}
+		token := strings.TrimSpace(line)
+		if err := auth.CheckToken(token); err != nil {
+			fmt.Fprintf(stderr, "Invalid token: %v\n", err)
+			return exitCodeError
+		}
+		fmt.Fprintf(stdout, "hash: %s\n", auth.Hash(token))
+		return exitCodeSuccess
+	}
+
+	token, err := auth.Generate()
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitCodeError
+	}
+	fmt.Fprintf(stdout, "token: %s\n", token)
+	fmt.Fprintf(stdout, "hash: %s\n", auth.Hash(token))
+	return exitCodeSuccess
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"filesystem/pkg/audit"
+	"filesystem/pkg/security"
+	"filesystem/pkg/toolresult"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Admin tools grant and revoke allowed directories at runtime. They are
+// only registered when the configuration enables them.
+
+func (th *ToolHandlers) createAddAllowedDirectoryTool() mcp.Tool {
+	return mcp.NewTool("add_allowed_directory",
+		writeTool("Add Allowed Directory", false, true),
+		mcp.WithDescription("Grant access to an existing directory until it is removed or expires. "+
+			"Only directories beneath the parents configured by the administrator can be granted; "+
+			"the grant takes the access mode of its parent. Granting a directory again replaces "+
+			"its expiry."),
+		mcp.WithString("path", mcp.Required(), mcp.Description("Path of the directory to grant")),
+		mcp.WithString("expiresIn", mcp.Description("How long the grant lasts, such as 30m or 2h; "+
+			"defaults to the longest grant allowed")))
+}
+
+func (th *ToolHandlers) createRemoveAllowedDirectoryTool() mcp.Tool {
+	return mcp.NewTool("remove_allowed_directory",
+		writeTool("Remove Allowed Directory", false, true),
+		mcp.WithDescription("Revoke access to a directory granted with add_allowed_directory. "+
+			"Directories from the server configuration cannot be removed."),
+		mcp.WithString("path", mcp.Required(), mcp.Description("Path of the granted directory")))
+}
+
+func (th *ToolHandlers) handleAddAllowedDirectory(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, errRes := getArguments(req)
+	if errRes != nil {
+		return errRes, nil
+	}
+
+	path, errRes := getRequiredString(args, "path")
+	if errRes != nil {
+		return errRes, nil
+	}
+	ttl, errRes := getOptionalDuration(args, "expiresIn")
+	if errRes != nil {
+		return errRes, nil
+	}
+
+	dir, err := filepath.Abs(security.ExpandHomePath(path))
+	if err != nil {
+		return toolresult.Error(err), nil
+	}
+	root, err := th.grants.Grant(dir, ttl, time.Now())
+	if err != nil {
+		th.logger.WarnContext(ctx, "Directory grant refused", "path", path, "error", err)
+		return toolresult.Error(err), nil
+	}
+	audit.RecordPath(ctx, root.Path)
+	if err := th.checkPolicy(ctx, "add_allowed_directory", root.Path, sizeUnknown); err != nil {
+		return toolresult.Error(err), nil
+	}
+
+	root, err = th.pathValidator.AddRoot(root)
+	if err != nil {
+		return toolresult.Error(err), nil
+	}
+
+	text := fmt.Sprintf("Granted %s access to %s", root.Mode, root.Path)
+	if !root.Expires.IsZero() {
+		text += " until " + root.Expires.Format(time.RFC3339)
+	}
+	return toolresult.Text(text, newAllowedDirectory(root)), nil
+}
+
+func (th *ToolHandlers) handleRemoveAllowedDirectory(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, errRes := getArguments(req)
+	if errRes != nil {
+		return errRes, nil
+	}
+
+	path, errRes := getRequiredString(args, "path")
+	if errRes != nil {
+		return errRes, nil
+	}
+
+	dir, err := filepath.Abs(security.ExpandHomePath(path))
+	if err != nil {
+		return toolresult.Error(err), nil
+	}
+	audit.RecordPath(ctx, dir)
+	if err := th.checkPolicy(ctx, "remove_allowed_directory", dir, sizeUnknown); err != nil {
+		return toolresult.Error(err), nil
+	}
+
+	if err := th.pathValidator.RemoveRoot(dir); err != nil {
+		return toolresult.Error(err), nil
+	}
+	return toolresult.Text(fmt.Sprintf("Removed allowed directory %s", dir), map[string]interface{}{
+		"path": dir,
+	}), nil
+}
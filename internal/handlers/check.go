@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"filesystem/pkg/audit"
+	"filesystem/pkg/security"
+	"filesystem/pkg/toolresult"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Check runs the argument, path and policy checks of a call without
+// performing it. It returns the result the call would fail with, or nil
+// when the call would go ahead. Calls held for approval are checked when
+// they are submitted, so only calls that could run are queued.
+func (th *ToolHandlers) Check(ctx context.Context, req mcp.CallToolRequest) *mcp.CallToolResult {
+	args, errRes := getArguments(req)
+	if errRes != nil {
+		return errRes
+	}
+
+	tool := req.Params.Name
+	switch tool {
+	case "list_allowed_directories":
+		return nil
+	case "read_multiple_files":
+		return th.checkPaths(ctx, tool, args)
+	case "move_file":
+		return th.checkMove(ctx, args)
+	case "add_allowed_directory", "remove_allowed_directory":
+		return th.checkGrant(ctx, tool, args)
+	}
+
+	path, errRes := getRequiredString(args, "path")
+	if errRes != nil {
+		return errRes
+	}
+	op := security.OpRead
+	size := int64(sizeUnknown)
+	switch tool {
+	case "write_file":
+		content, errRes := getRequiredString(args, "content")
+		if errRes != nil {
+			return errRes
+		}
+		op, size = security.OpWrite, int64(len(content))
+	case "edit_file":
+		if _, errRes := getEditOperations(args); errRes != nil {
+			return errRes
+		}
+		op = security.OpWrite
+	case "create_directory":
+		op = security.OpWrite
+	}
+
+	validPath, err := th.validatePath(ctx, path, op)
+	if err != nil {
+		th.logger.WarnContext(ctx, "Path validation failed", "path", path, "error", err)
+		return toolresult.Error(err)
+	}
+	switch tool {
+	case "read_file", "edit_file", "get_file_info":
+		size = th.fileSize(validPath)
+	}
+	if err := th.checkPolicy(ctx, tool, validPath, size); err != nil {
+		return toolresult.Error(err)
+	}
+	return nil
+}
+
+// checkPaths checks a read of several paths, which goes ahead while any
+// one of them may be read
+func (th *ToolHandlers) checkPaths(ctx context.Context, tool string, args map[string]interface{}) *mcp.CallToolResult {
+	paths, errRes := getRequiredStringSlice(args, "paths")
+	if errRes != nil {
+		return errRes
+	}
+
+	var firstErr error
+	for i := 0; i < len(paths) && i < 100; i++ {
+		validPath, err := th.validatePath(ctx, paths[i], security.OpRead)
+		if err == nil {
+			err = th.checkPolicy(ctx, tool, validPath, th.fileSize(validPath))
+		}
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		return toolresult.Errorf(toolresult.CodeInvalidArgument, "Error: no paths given")
+	}
+	return toolresult.Error(firstErr)
+}
+
+// checkMove checks both ends of a move as handleMoveFile does
+func (th *ToolHandlers) checkMove(ctx context.Context, args map[string]interface{}) *mcp.CallToolResult {
+	source, errRes := getRequiredString(args, "source")
+	if errRes != nil {
+		return errRes
+	}
+	destination, errRes := getRequiredString(args, "destination")
+	if errRes != nil {
+		return errRes
+	}
+
+	validSource, err := th.validatePath(ctx, source, security.OpDelete)
+	if err != nil {
+		th.logger.WarnContext(ctx, "Source path validation failed", "path", source, "error", err)
+		return toolresult.Error(err)
+	}
+	validDestination, err := th.validatePath(ctx, destination, security.OpWrite)
+	if err != nil {
+		th.logger.WarnContext(ctx, "Destination path validation failed", "path", destination, "error", err)
+		return toolresult.Error(err)
+	}
+
+	size := th.fileSize(validSource)
+	for _, validPath := range []string{validSource, validDestination} {
+		if err := th.checkPolicy(ctx, "move_file", validPath, size); err != nil {
+			return toolresult.Error(err)
+		}
+	}
+	return nil
+}
+
+// checkGrant checks a change to the allowed directories. A grant is
+// checked against the grant limits as they stand now.
+func (th *ToolHandlers) checkGrant(ctx context.Context, tool string, args map[string]interface{}) *mcp.CallToolResult {
+	path, errRes := getRequiredString(args, "path")
+	if errRes != nil {
+		return errRes
+	}
+
+	dir, err := filepath.Abs(security.ExpandHomePath(path))
+	if err != nil {
+		return toolresult.Error(err)
+	}
+	if tool == "add_allowed_directory" {
+		ttl, errRes := getOptionalDuration(args, "expiresIn")
+		if errRes != nil {
+			return errRes
+		}
+		root, err := th.grants.Grant(dir, ttl, time.Now())
+		if err != nil {
+			th.logger.WarnContext(ctx, "Directory grant refused", "path", path, "error", err)
+			return toolresult.Error(err)
+		}
+		dir = root.Path
+	}
+	audit.RecordPath(ctx, dir)
+	if err := th.checkPolicy(ctx, tool, dir, sizeUnknown); err != nil {
+		return toolresult.Error(err)
+	}
+	return nil
+}
@@ -3,17 +3,24 @@ package handlers
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"filesystem/pkg/filesystem"
+	"filesystem/pkg/toolresult"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
+// invalidArgument returns the failed result for malformed arguments
+func invalidArgument(msg string) *mcp.CallToolResult {
+	return toolresult.Errorf(toolresult.CodeInvalidArgument, "%s", msg)
+}
+
 // getArguments extracts the argument map from the request and validates its presence.
 func getArguments(req mcp.CallToolRequest) (map[string]interface{}, *mcp.CallToolResult) {
 	args := req.Params.Arguments
 	if args == nil {
-		return nil, mcp.NewToolResultError("Invalid arguments format")
+		return nil, invalidArgument("Invalid arguments format")
 	}
 	return args, nil
 }
@@ -24,7 +31,7 @@ func getRequiredString(args map[string]interface{}, key string) (string, *mcp.Ca
 		return val, nil
 	}
 	msg := fmt.Sprintf("%s parameter is required", strings.Title(key))
-	return "", mcp.NewToolResultError(msg)
+	return "", invalidArgument(msg)
 }
 
 // getRequiredStringSlice extracts a required string slice from the argument map.
@@ -32,7 +39,7 @@ func getRequiredStringSlice(args map[string]interface{}, key string) ([]string,
 	raw, ok := args[key].([]interface{})
 	if !ok {
 		msg := fmt.Sprintf("%s parameter is required", strings.Title(key))
-		return nil, mcp.NewToolResultError(msg)
+		return nil, invalidArgument(msg)
 	}
 	result := make([]string, 0, len(raw))
 	for i := 0; i < len(raw) && i < 100; i++ {
@@ -42,7 +49,7 @@ func getRequiredStringSlice(args map[string]interface{}, key string) ([]string,
 	}
 	if len(result) == 0 {
 		msg := fmt.Sprintf("%s parameter is required", strings.Title(key))
-		return nil, mcp.NewToolResultError(msg)
+		return nil, invalidArgument(msg)
 	}
 	return result, nil
 }
@@ -72,11 +79,25 @@ func getOptionalBool(args map[string]interface{}, key string, defaultVal bool) b
 	return defaultVal
 }
 
+// getOptionalDuration extracts an optional duration such as "30m" from the
+// argument map, returning zero when it is absent.
+func getOptionalDuration(args map[string]interface{}, key string) (time.Duration, *mcp.CallToolResult) {
+	raw, ok := args[key].(string)
+	if !ok || raw == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		return 0, invalidArgument(fmt.Sprintf("%s must be a positive duration such as 30m or 2h", key))
+	}
+	return d, nil
+}
+
 // getEditOperations parses edit operations from the argument map.
 func getEditOperations(args map[string]interface{}) ([]filesystem.EditOperation, *mcp.CallToolResult) {
 	raw, ok := args["edits"].([]interface{})
 	if !ok {
-		return nil, mcp.NewToolResultError("Edits parameter is required")
+		return nil, invalidArgument("Edits parameter is required")
 	}
 	edits := make([]filesystem.EditOperation, 0, len(raw))
 	for i := 0; i < len(raw) && i < 100; i++ {
@@ -91,7 +112,7 @@ func getEditOperations(args map[string]interface{}) ([]filesystem.EditOperation,
 		}
 	}
 	if len(edits) == 0 {
-		return nil, mcp.NewToolResultError("No valid edits provided")
+		return nil, invalidArgument("No valid edits provided")
 	}
 	return edits, nil
 }
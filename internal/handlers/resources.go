@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"filesystem/pkg/audit"
+	"filesystem/pkg/security"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// directoryMIMEType is the type of a directory resource, served as its
+// listing
+const directoryMIMEType = "text/plain"
+
+// ReadResource serves the file:// resource uri naming path: the content of
+// a file, or the listing of a directory. It is validated and checked
+// against the policy like read_file and list_directory.
+func (th *ToolHandlers) ReadResource(ctx context.Context, uri, path string) ([]mcp.ResourceContents, error) {
+	// Input validation per Rule 7
+	if uri == "" || path == "" {
+		return nil, fmt.Errorf("resource URI is required")
+	}
+
+	validPath, err := th.validatePath(ctx, path, security.OpRead)
+	if err != nil {
+		th.logger.WarnContext(ctx, "Path validation failed", "uri", uri, "path", path, "error", err)
+		return nil, err
+	}
+	info, err := os.Stat(validPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat resource: %w", err)
+	}
+
+	if info.IsDir() {
+		if err := th.checkPolicy(ctx, "list_directory", validPath, sizeUnknown); err != nil {
+			return nil, err
+		}
+		listing, err := th.fsOps.ListDirectory(ctx, validPath)
+		if err != nil {
+			return nil, err
+		}
+		return []mcp.ResourceContents{mcp.TextResourceContents{
+			URI:      uri,
+			MIMEType: directoryMIMEType,
+			Text:     listing,
+		}}, nil
+	}
+
+	if err := th.checkPolicy(ctx, "read_file", validPath, th.fileSize(validPath)); err != nil {
+		return nil, err
+	}
+	content, err := th.fsOps.ReadFile(ctx, validPath)
+	if err != nil {
+		return nil, err
+	}
+	audit.RecordBytesRead(ctx, int64(len(content)))
+
+	mimeType := MIMEType(validPath, content)
+	if isText(mimeType) && utf8.ValidString(content) {
+		return []mcp.ResourceContents{mcp.TextResourceContents{
+			URI:      uri,
+			MIMEType: mimeType,
+			Text:     content,
+		}}, nil
+	}
+	return []mcp.ResourceContents{mcp.BlobResourceContents{
+		URI:      uri,
+		MIMEType: mimeType,
+		Blob:     base64.StdEncoding.EncodeToString([]byte(content)),
+	}}, nil
+}
+
+// MIMEType returns the type of a file by its extension, or sniffed from
+// its content when the extension is unknown
+func MIMEType(path, content string) string {
+	if mimeType := mime.TypeByExtension(filepath.Ext(path)); mimeType != "" {
+		return mimeType
+	}
+	return http.DetectContentType([]byte(content))
+}
+
+// isText reports whether content of mimeType is served as text rather than
+// base64
+func isText(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/x-yaml",
+		"application/yaml", "application/toml":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
@@ -4,66 +4,170 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"os"
 	"strings"
 	"time"
 
+	"filesystem/pkg/audit"
 	"filesystem/pkg/filesystem"
+	"filesystem/pkg/policy"
 	"filesystem/pkg/security"
+	"filesystem/pkg/toolresult"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
+// sizeUnknown marks calls whose size policy rules cannot match
+const sizeUnknown = -1
+
 // ToolHandlers provides MCP tool implementations for filesystem operations
 type ToolHandlers struct {
 	pathValidator *security.PathValidator
 	fsOps         *filesystem.Operations
+	policy        *policy.Policy
+	grants        *security.GrantPolicy
 	logger        *slog.Logger
 }
 
+// Options configures optional tool handler behavior
+type Options struct {
+	// Policy is checked before every operation; nil allows everything
+	// the path validator allows
+	Policy *policy.Policy
+
+	// Grants enables the add_allowed_directory and remove_allowed_directory
+	// tools within its limits; nil leaves them out
+	Grants *security.GrantPolicy
+}
+
 // NewToolHandlers creates a new tool handlers instance
 func NewToolHandlers(pathValidator *security.PathValidator, fsOps *filesystem.Operations, logger *slog.Logger) *ToolHandlers {
+	return NewToolHandlersWithOptions(pathValidator, fsOps, Options{}, logger)
+}
+
+// NewToolHandlersWithOptions creates a tool handlers instance with options
+func NewToolHandlersWithOptions(pathValidator *security.PathValidator, fsOps *filesystem.Operations, opts Options, logger *slog.Logger) *ToolHandlers {
 	return &ToolHandlers{
 		pathValidator: pathValidator,
 		fsOps:         fsOps,
+		policy:        opts.Policy,
+		grants:        opts.Grants,
 		logger:        logger,
 	}
 }
 
+// Tools returns all filesystem tools bound to this handler instance
+func (th *ToolHandlers) Tools() []server.ServerTool {
+	// Define all tools with proper schema validation per Rule 5
+	tools := []server.ServerTool{
+		{Tool: th.createReadFileTool(), Handler: th.handleReadFile},
+		{Tool: th.createReadMultipleFilesTool(), Handler: th.handleReadMultipleFiles},
+		{Tool: th.createWriteFileTool(), Handler: th.handleWriteFile},
+		{Tool: th.createEditFileTool(), Handler: th.handleEditFile},
+		{Tool: th.createCreateDirectoryTool(), Handler: th.handleCreateDirectory},
+		{Tool: th.createListDirectoryTool(), Handler: th.handleListDirectory},
+		{Tool: th.createDirectoryTreeTool(), Handler: th.handleDirectoryTree},
+		{Tool: th.createMoveFileTool(), Handler: th.handleMoveFile},
+		{Tool: th.createSearchFilesTool(), Handler: th.handleSearchFiles},
+		{Tool: th.createGetFileInfoTool(), Handler: th.handleGetFileInfo},
+		{Tool: th.createListAllowedDirectoriesTool(), Handler: th.handleListAllowedDirectories},
+	}
+	if th.grants != nil {
+		tools = append(tools,
+			server.ServerTool{Tool: th.createAddAllowedDirectoryTool(), Handler: th.handleAddAllowedDirectory},
+			server.ServerTool{Tool: th.createRemoveAllowedDirectoryTool(), Handler: th.handleRemoveAllowedDirectory})
+	}
+	return tools
+}
+
 // RegisterTools registers all filesystem tools with the MCP server
 func (th *ToolHandlers) RegisterTools(srv *server.MCPServer) error {
-	// Define all tools with proper schema validation per Rule 5
-	tools := []struct {
-		tool    mcp.Tool
-		handler server.ToolHandlerFunc
-	}{
-		{th.createReadFileTool(), th.handleReadFile},
-		{th.createReadMultipleFilesTool(), th.handleReadMultipleFiles},
-		{th.createWriteFileTool(), th.handleWriteFile},
-		{th.createEditFileTool(), th.handleEditFile},
-		{th.createCreateDirectoryTool(), th.handleCreateDirectory},
-		{th.createListDirectoryTool(), th.handleListDirectory},
-		{th.createDirectoryTreeTool(), th.handleDirectoryTree},
-		{th.createMoveFileTool(), th.handleMoveFile},
-		{th.createSearchFilesTool(), th.handleSearchFiles},
-		{th.createGetFileInfoTool(), th.handleGetFileInfo},
-		{th.createListAllowedDirectoriesTool(), th.handleListAllowedDirectories},
-	}
+	tools := th.Tools()
 
 	// Register each tool
 	for _, tool := range tools {
-		srv.AddTool(tool.tool, tool.handler)
-		th.logger.Debug("Tool registered successfully", "tool", tool.tool.Name)
+		srv.AddTool(tool.Tool, tool.Handler)
+		th.logger.Debug("Tool registered successfully", "tool", tool.Tool.Name)
 	}
 
 	th.logger.Info("All filesystem tools registered successfully", "count", len(tools))
 	return nil
 }
 
+// validatePath validates a requested path and records the resolved path
+// for the audit log
+func (th *ToolHandlers) validatePath(ctx context.Context, path string, op security.Operation) (string, error) {
+	validPath, err := th.pathValidator.ValidatePath(path, op)
+	if err != nil {
+		return "", err
+	}
+	audit.RecordPath(ctx, validPath)
+	return validPath, nil
+}
+
+// checkPolicy evaluates the policy rules for one validated path of a call,
+// recording matched rules for the audit log. size is the content size for
+// writes, the file size otherwise, or sizeUnknown.
+func (th *ToolHandlers) checkPolicy(ctx context.Context, tool, validPath string, size int64) error {
+	if th.policy == nil {
+		return nil
+	}
+
+	root, _ := th.pathValidator.RootOf(validPath)
+	decision := th.policy.Evaluate(policy.Request{
+		Tool: tool,
+		Path: validPath,
+		Root: root,
+		Size: size,
+		Time: time.Now(),
+	})
+	for _, rule := range decision.Audited {
+		th.logger.InfoContext(ctx, "Policy audit rule matched", "rule", rule, "tool", tool, "path", validPath, "size", size)
+		audit.RecordPolicyRule(ctx, rule)
+	}
+	if decision.Rule != "" {
+		audit.RecordPolicyRule(ctx, decision.Rule)
+	}
+	if decision.Allowed {
+		return nil
+	}
+
+	th.logger.WarnContext(ctx, "Policy denied operation", "rule", decision.Rule, "tool", tool, "path", validPath, "size", size)
+	return &policy.DeniedError{Rule: decision.Rule, Tool: tool, Path: validPath}
+}
+
+// fileSize returns the size of a regular file for policy checks, or
+// sizeUnknown when there is no policy or the path is not a regular file
+func (th *ToolHandlers) fileSize(validPath string) int64 {
+	if th.policy == nil {
+		return sizeUnknown
+	}
+	info, err := os.Stat(validPath)
+	if err != nil || !info.Mode().IsRegular() {
+		return sizeUnknown
+	}
+	return info.Size()
+}
+
 // Tool creation methods
 
+// readOnlyTool annotates a tool that only reads. Tools never reach beyond
+// the filesystem, so none is marked open world. The server lists false
+// hints too, which mcp-go would omit.
+func readOnlyTool(title string) mcp.ToolOption {
+	return mcp.WithToolAnnotation(mcp.ToolAnnotation{Title: title, ReadOnlyHint: true, IdempotentHint: true})
+}
+
+// writeTool annotates a tool that changes the filesystem or the allowed
+// directories
+func writeTool(title string, destructive, idempotent bool) mcp.ToolOption {
+	return mcp.WithToolAnnotation(mcp.ToolAnnotation{Title: title, DestructiveHint: destructive, IdempotentHint: idempotent})
+}
+
 func (th *ToolHandlers) createReadFileTool() mcp.Tool {
 	return mcp.NewTool("read_file",
+		readOnlyTool("Read File"),
 		mcp.WithDescription("Read the complete contents of a file from the file system. "+
 			"Handles various text encodings and provides detailed error messages "+
 			"if the file cannot be read. Use this tool when you need to examine "+
@@ -73,6 +177,7 @@ func (th *ToolHandlers) createReadFileTool() mcp.Tool {
 
 func (th *ToolHandlers) createReadMultipleFilesTool() mcp.Tool {
 	return mcp.NewTool("read_multiple_files",
+		readOnlyTool("Read Multiple Files"),
 		mcp.WithDescription("Read the contents of multiple files simultaneously. This is more "+
 			"efficient than reading files one by one when you need to analyze "+
 			"or compare multiple files. Each file's content is returned with its "+
@@ -84,6 +189,7 @@ func (th *ToolHandlers) createReadMultipleFilesTool() mcp.Tool {
 
 func (th *ToolHandlers) createWriteFileTool() mcp.Tool {
 	return mcp.NewTool("write_file",
+		writeTool("Write File", true, true),
 		mcp.WithDescription("Create a new file or completely overwrite an existing file with new content. "+
 			"Use with caution as it will overwrite existing files without warning. "+
 			"Handles text content with proper encoding. Only works within allowed directories."),
@@ -93,6 +199,7 @@ func (th *ToolHandlers) createWriteFileTool() mcp.Tool {
 
 func (th *ToolHandlers) createEditFileTool() mcp.Tool {
 	return mcp.NewTool("edit_file",
+		writeTool("Edit File", true, false),
 		mcp.WithDescription("Make line-based edits to a text file. Each edit replaces exact line sequences "+
 			"with new content. Returns a git-style diff showing the changes made. "+
 			"Only works within allowed directories."),
@@ -117,6 +224,7 @@ func (th *ToolHandlers) createEditFileTool() mcp.Tool {
 
 func (th *ToolHandlers) createCreateDirectoryTool() mcp.Tool {
 	return mcp.NewTool("create_directory",
+		writeTool("Create Directory", false, true),
 		mcp.WithDescription("Create a new directory or ensure a directory exists. Can create multiple "+
 			"nested directories in one operation. If the directory already exists, "+
 			"this operation will succeed silently. Perfect for setting up directory "+
@@ -126,6 +234,7 @@ func (th *ToolHandlers) createCreateDirectoryTool() mcp.Tool {
 
 func (th *ToolHandlers) createListDirectoryTool() mcp.Tool {
 	return mcp.NewTool("list_directory",
+		readOnlyTool("List Directory"),
 		mcp.WithDescription("Get a detailed listing of all files and directories in a specified path. "+
 			"Results clearly distinguish between files and directories with [FILE] and [DIR] "+
 			"prefixes. This tool is essential for understanding directory structure and "+
@@ -135,6 +244,7 @@ func (th *ToolHandlers) createListDirectoryTool() mcp.Tool {
 
 func (th *ToolHandlers) createDirectoryTreeTool() mcp.Tool {
 	return mcp.NewTool("directory_tree",
+		readOnlyTool("Directory Tree"),
 		mcp.WithDescription("Get a recursive tree view of files and directories as a JSON structure. "+
 			"Each entry includes 'name', 'type' (file/directory), and 'children' for directories. "+
 			"Files have no children array, while directories always have a children array (which may be empty). "+
@@ -144,6 +254,7 @@ func (th *ToolHandlers) createDirectoryTreeTool() mcp.Tool {
 
 func (th *ToolHandlers) createMoveFileTool() mcp.Tool {
 	return mcp.NewTool("move_file",
+		writeTool("Move File", true, false),
 		mcp.WithDescription("Move or rename files and directories. Can move files between directories "+
 			"and rename them in a single operation. If the destination exists, the "+
 			"operation will fail. Works across different directories and can be used "+
@@ -154,6 +265,7 @@ func (th *ToolHandlers) createMoveFileTool() mcp.Tool {
 
 func (th *ToolHandlers) createSearchFilesTool() mcp.Tool {
 	return mcp.NewTool("search_files",
+		readOnlyTool("Search Files"),
 		mcp.WithDescription("Recursively search for files and directories matching a pattern. "+
 			"Searches through all subdirectories from the starting path. The search "+
 			"is case-insensitive and matches partial names. Returns full paths to all "+
@@ -167,6 +279,7 @@ func (th *ToolHandlers) createSearchFilesTool() mcp.Tool {
 
 func (th *ToolHandlers) createGetFileInfoTool() mcp.Tool {
 	return mcp.NewTool("get_file_info",
+		readOnlyTool("Get File Info"),
 		mcp.WithDescription("Retrieve detailed metadata about a file or directory. Returns comprehensive "+
 			"information including size, creation time, last modified time, permissions, "+
 			"and type. This tool is perfect for understanding file characteristics "+
@@ -176,6 +289,7 @@ func (th *ToolHandlers) createGetFileInfoTool() mcp.Tool {
 
 func (th *ToolHandlers) createListAllowedDirectoriesTool() mcp.Tool {
 	return mcp.NewTool("list_allowed_directories",
+		readOnlyTool("List Allowed Directories"),
 		mcp.WithDescription("Returns the list of directories that this server is allowed to access. "+
 			"Use this to understand which directories are available before trying to access files."))
 }
@@ -194,19 +308,24 @@ func (th *ToolHandlers) handleReadFile(ctx context.Context, req mcp.CallToolRequ
 	}
 
 	// Validate path security
-	validPath, err := th.pathValidator.ValidatePath(path)
+	validPath, err := th.validatePath(ctx, path, security.OpRead)
 	if err != nil {
-		th.logger.Warn("Path validation failed", "path", path, "error", err)
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		th.logger.WarnContext(ctx, "Path validation failed", "path", path, "error", err)
+		return toolresult.Error(err), nil
+	}
+	if err := th.checkPolicy(ctx, "read_file", validPath, th.fileSize(validPath)); err != nil {
+		return toolresult.Error(err), nil
 	}
 
 	// Read file content
-	content, err := th.fsOps.ReadFile(validPath)
+	content, err := th.fsOps.ReadFile(ctx, validPath)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		return toolresult.Error(err), nil
 	}
+	audit.RecordBytesRead(ctx, int64(len(content)))
 
-	return mcp.NewToolResultText(content), nil
+	// The text block already carries the content, so the payload describes it
+	return toolresult.Text(content, newFileResult(validPath, content, nil)), nil
 }
 
 func (th *ToolHandlers) handleReadMultipleFiles(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -221,28 +340,59 @@ func (th *ToolHandlers) handleReadMultipleFiles(ctx context.Context, req mcp.Cal
 	}
 	// Validate each path and only keep valid ones
 	paths := make([]string, 0, len(pathsSlice))
+	code := toolresult.CodeInvalidArgument
 	for i := 0; i < len(pathsSlice) && i < 100; i++ {
 		path := pathsSlice[i]
-		validPath, err := th.pathValidator.ValidatePath(path)
+		validPath, err := th.validatePath(ctx, path, security.OpRead)
 		if err != nil {
 			// Skip invalid paths but log the failure
-			th.logger.Warn("Path validation failed", "path", path, "error", err)
+			th.logger.WarnContext(ctx, "Path validation failed", "path", path, "error", err)
+			code = toolresult.Code(err)
+			continue
+		}
+		if err := th.checkPolicy(ctx, "read_multiple_files", validPath, th.fileSize(validPath)); err != nil {
+			code = toolresult.Code(err)
 			continue
 		}
 		paths = append(paths, validPath)
 	}
 
 	if len(paths) == 0 {
-		return mcp.NewToolResultError("No valid paths provided"), nil
+		return toolresult.Errorf(code, "No valid paths provided"), nil
 	}
 
 	// Read multiple files
-	content, err := th.fsOps.ReadMultipleFiles(paths)
+	files, err := th.fsOps.ReadFiles(ctx, paths)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		return toolresult.Error(err), nil
 	}
+	results := make([]fileResult, 0, len(files))
+	for _, file := range files {
+		results = append(results, newFileResult(file.Path, file.Content, file.Err))
+		audit.RecordBytesRead(ctx, int64(len(file.Content)))
+	}
+
+	return toolresult.Text(filesystem.FormatFileContents(files), map[string]interface{}{
+		"files": results,
+	}), nil
+}
+
+// fileResult is the payload of read_file and one file of the
+// read_multiple_files payload. It describes the content the text block
+// carries rather than repeating it.
+type fileResult struct {
+	Path     string              `json:"path"`
+	Size     int                 `json:"size,omitempty"`
+	MIMEType string              `json:"mimeType,omitempty"`
+	Error    *toolresult.Failure `json:"error,omitempty"`
+}
 
-	return mcp.NewToolResultText(content), nil
+// newFileResult returns the payload entry of a file read or failed
+func newFileResult(path, content string, err error) fileResult {
+	if err != nil {
+		return fileResult{Path: path, Error: &toolresult.Failure{Code: toolresult.Code(err), Message: err.Error()}}
+	}
+	return fileResult{Path: path, Size: len(content), MIMEType: MIMEType(path, content)}
 }
 
 func (th *ToolHandlers) handleWriteFile(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -262,19 +412,26 @@ func (th *ToolHandlers) handleWriteFile(ctx context.Context, req mcp.CallToolReq
 	}
 
 	// Validate path security
-	validPath, err := th.pathValidator.ValidatePath(path)
+	validPath, err := th.validatePath(ctx, path, security.OpWrite)
 	if err != nil {
-		th.logger.Warn("Path validation failed", "path", path, "error", err)
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		th.logger.WarnContext(ctx, "Path validation failed", "path", path, "error", err)
+		return toolresult.Error(err), nil
+	}
+	if err := th.checkPolicy(ctx, "write_file", validPath, int64(len(content))); err != nil {
+		return toolresult.Error(err), nil
 	}
 
 	// Write file
-	err = th.fsOps.WriteFile(validPath, content)
+	err = th.fsOps.WriteFile(ctx, validPath, content)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		return toolresult.Error(err), nil
 	}
+	audit.RecordBytesWritten(ctx, int64(len(content)))
 
-	return mcp.NewToolResultText(fmt.Sprintf("Successfully wrote to %s", path)), nil
+	return toolresult.Text(fmt.Sprintf("Successfully wrote to %s", path), map[string]interface{}{
+		"path":         validPath,
+		"bytesWritten": len(content),
+	}), nil
 }
 
 func (th *ToolHandlers) handleEditFile(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -296,19 +453,26 @@ func (th *ToolHandlers) handleEditFile(ctx context.Context, req mcp.CallToolRequ
 	dryRun := getOptionalBool(args, "dryRun", false)
 
 	// Validate path security
-	validPath, err := th.pathValidator.ValidatePath(path)
+	validPath, err := th.validatePath(ctx, path, security.OpWrite)
 	if err != nil {
-		th.logger.Warn("Path validation failed", "path", path, "error", err)
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		th.logger.WarnContext(ctx, "Path validation failed", "path", path, "error", err)
+		return toolresult.Error(err), nil
+	}
+	if err := th.checkPolicy(ctx, "edit_file", validPath, th.fileSize(validPath)); err != nil {
+		return toolresult.Error(err), nil
 	}
 
 	// Edit file
-	diff, err := th.fsOps.EditFile(validPath, edits, dryRun)
+	diff, err := th.fsOps.EditFile(ctx, validPath, edits, dryRun)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		return toolresult.Error(err), nil
 	}
 
-	return mcp.NewToolResultText(diff), nil
+	return toolresult.Text(diff, map[string]interface{}{
+		"path":   validPath,
+		"diff":   diff,
+		"dryRun": dryRun,
+	}), nil
 }
 
 func (th *ToolHandlers) handleCreateDirectory(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -323,19 +487,24 @@ func (th *ToolHandlers) handleCreateDirectory(ctx context.Context, req mcp.CallT
 	}
 
 	// Validate path security
-	validPath, err := th.pathValidator.ValidatePath(path)
+	validPath, err := th.validatePath(ctx, path, security.OpWrite)
 	if err != nil {
-		th.logger.Warn("Path validation failed", "path", path, "error", err)
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		th.logger.WarnContext(ctx, "Path validation failed", "path", path, "error", err)
+		return toolresult.Error(err), nil
+	}
+	if err := th.checkPolicy(ctx, "create_directory", validPath, sizeUnknown); err != nil {
+		return toolresult.Error(err), nil
 	}
 
 	// Create directory
-	err = th.fsOps.CreateDirectory(validPath)
+	err = th.fsOps.CreateDirectory(ctx, validPath)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		return toolresult.Error(err), nil
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("Successfully created directory %s", path)), nil
+	return toolresult.Text(fmt.Sprintf("Successfully created directory %s", path), map[string]interface{}{
+		"path": validPath,
+	}), nil
 }
 
 func (th *ToolHandlers) handleListDirectory(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -350,19 +519,25 @@ func (th *ToolHandlers) handleListDirectory(ctx context.Context, req mcp.CallToo
 	}
 
 	// Validate path security
-	validPath, err := th.pathValidator.ValidatePath(path)
+	validPath, err := th.validatePath(ctx, path, security.OpRead)
 	if err != nil {
-		th.logger.Warn("Path validation failed", "path", path, "error", err)
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		th.logger.WarnContext(ctx, "Path validation failed", "path", path, "error", err)
+		return toolresult.Error(err), nil
+	}
+	if err := th.checkPolicy(ctx, "list_directory", validPath, sizeUnknown); err != nil {
+		return toolresult.Error(err), nil
 	}
 
 	// List directory
-	listing, err := th.fsOps.ListDirectory(validPath)
+	entries, err := th.fsOps.ListDirectoryEntries(ctx, validPath)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		return toolresult.Error(err), nil
 	}
 
-	return mcp.NewToolResultText(listing), nil
+	return toolresult.Text(filesystem.FormatListing(entries), map[string]interface{}{
+		"path":    validPath,
+		"entries": entries,
+	}), nil
 }
 
 func (th *ToolHandlers) handleDirectoryTree(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -377,19 +552,29 @@ func (th *ToolHandlers) handleDirectoryTree(ctx context.Context, req mcp.CallToo
 	}
 
 	// Validate path security
-	validPath, err := th.pathValidator.ValidatePath(path)
+	validPath, err := th.validatePath(ctx, path, security.OpRead)
 	if err != nil {
-		th.logger.Warn("Path validation failed", "path", path, "error", err)
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		th.logger.WarnContext(ctx, "Path validation failed", "path", path, "error", err)
+		return toolresult.Error(err), nil
+	}
+	if err := th.checkPolicy(ctx, "directory_tree", validPath, sizeUnknown); err != nil {
+		return toolresult.Error(err), nil
 	}
 
 	// Build directory tree
-	tree, err := th.fsOps.DirectoryTree(validPath)
+	tree, err := th.fsOps.DirectoryTreeEntries(ctx, validPath)
+	if err != nil {
+		return toolresult.Error(err), nil
+	}
+	text, err := filesystem.FormatTree(tree)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		return toolresult.Error(err), nil
 	}
 
-	return mcp.NewToolResultText(tree), nil
+	return toolresult.Text(text, map[string]interface{}{
+		"path": validPath,
+		"tree": tree,
+	}), nil
 }
 
 func (th *ToolHandlers) handleMoveFile(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -409,25 +594,36 @@ func (th *ToolHandlers) handleMoveFile(ctx context.Context, req mcp.CallToolRequ
 	}
 
 	// Validate both paths
-	validSource, err := th.pathValidator.ValidatePath(source)
+	validSource, err := th.validatePath(ctx, source, security.OpDelete)
 	if err != nil {
-		th.logger.Warn("Source path validation failed", "path", source, "error", err)
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		th.logger.WarnContext(ctx, "Source path validation failed", "path", source, "error", err)
+		return toolresult.Error(err), nil
 	}
 
-	validDestination, err := th.pathValidator.ValidatePath(destination)
+	validDestination, err := th.validatePath(ctx, destination, security.OpWrite)
 	if err != nil {
-		th.logger.Warn("Destination path validation failed", "path", destination, "error", err)
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		th.logger.WarnContext(ctx, "Destination path validation failed", "path", destination, "error", err)
+		return toolresult.Error(err), nil
+	}
+
+	// Both ends of the move are subject to policy, with the source's size
+	size := th.fileSize(validSource)
+	for _, validPath := range []string{validSource, validDestination} {
+		if err := th.checkPolicy(ctx, "move_file", validPath, size); err != nil {
+			return toolresult.Error(err), nil
+		}
 	}
 
 	// Move file
-	err = th.fsOps.MoveFile(validSource, validDestination)
+	err = th.fsOps.MoveFile(ctx, validSource, validDestination)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		return toolresult.Error(err), nil
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("Successfully moved %s to %s", source, destination)), nil
+	return toolresult.Text(fmt.Sprintf("Successfully moved %s to %s", source, destination), map[string]interface{}{
+		"source":      validSource,
+		"destination": validDestination,
+	}), nil
 }
 
 func (th *ToolHandlers) handleSearchFiles(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -450,23 +646,31 @@ func (th *ToolHandlers) handleSearchFiles(ctx context.Context, req mcp.CallToolR
 	excludePatterns := getOptionalStringSlice(args, "excludePatterns")
 
 	// Validate path security
-	validPath, err := th.pathValidator.ValidatePath(path)
+	validPath, err := th.validatePath(ctx, path, security.OpRead)
 	if err != nil {
-		th.logger.Warn("Path validation failed", "path", path, "error", err)
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		th.logger.WarnContext(ctx, "Path validation failed", "path", path, "error", err)
+		return toolresult.Error(err), nil
+	}
+	if err := th.checkPolicy(ctx, "search_files", validPath, sizeUnknown); err != nil {
+		return toolresult.Error(err), nil
 	}
 
 	// Search files
-	results, err := th.fsOps.SearchFiles(validPath, pattern, excludePatterns)
+	results, err := th.fsOps.SearchFiles(ctx, validPath, pattern, excludePatterns)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		return toolresult.Error(err), nil
 	}
 
+	payload := map[string]interface{}{
+		"path":    validPath,
+		"pattern": pattern,
+		"matches": append([]string{}, results...),
+	}
 	if len(results) == 0 {
-		return mcp.NewToolResultText("No matches found"), nil
+		return toolresult.Text("No matches found", payload), nil
 	}
 
-	return mcp.NewToolResultText(strings.Join(results, "\n")), nil
+	return toolresult.Text(strings.Join(results, "\n"), payload), nil
 }
 
 func (th *ToolHandlers) handleGetFileInfo(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -482,16 +686,19 @@ func (th *ToolHandlers) handleGetFileInfo(ctx context.Context, req mcp.CallToolR
 	}
 
 	// Validate path security
-	validPath, err := th.pathValidator.ValidatePath(path)
+	validPath, err := th.validatePath(ctx, path, security.OpRead)
 	if err != nil {
-		th.logger.Warn("Path validation failed", "path", path, "error", err)
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		th.logger.WarnContext(ctx, "Path validation failed", "path", path, "error", err)
+		return toolresult.Error(err), nil
+	}
+	if err := th.checkPolicy(ctx, "get_file_info", validPath, th.fileSize(validPath)); err != nil {
+		return toolresult.Error(err), nil
 	}
 
 	// Get file info
-	info, err := th.fsOps.GetFileInfo(validPath)
+	info, err := th.fsOps.GetFileInfo(ctx, validPath)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %s", err.Error())), nil
+		return toolresult.Error(err), nil
 	}
 
 	// Format info as newline separated key/value pairs to match the
@@ -506,11 +713,59 @@ func (th *ToolHandlers) handleGetFileInfo(ctx context.Context, req mcp.CallToolR
 		fmt.Sprintf("permissions: %s", info.Permissions),
 	}
 
-	return mcp.NewToolResultText(strings.Join(formatted, "\n")), nil
+	return toolresult.Text(strings.Join(formatted, "\n"), struct {
+		Path string `json:"path"`
+		*filesystem.FileInfo
+	}{validPath, info}), nil
 }
 
 func (th *ToolHandlers) handleListAllowedDirectories(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	dirs := th.pathValidator.GetAllowedDirectories()
+	roots := th.pathValidator.GetRoots()
+	dirs := make([]string, 0, len(roots))
+	directories := make([]allowedDirectory, 0, len(roots))
+	for _, root := range roots {
+		directories = append(directories, newAllowedDirectory(root))
+
+		// Only annotate restricted and granted directories to keep the
+		// default output unchanged
+		var notes []string
+		if root.Mode != security.ModeReadWrite {
+			notes = append(notes, string(root.Mode))
+		}
+		if root.Granted() {
+			if root.Expires.IsZero() {
+				notes = append(notes, "granted")
+			} else {
+				notes = append(notes, "granted until "+root.Expires.Format(time.RFC3339))
+			}
+		}
+		if len(notes) > 0 {
+			dirs = append(dirs, fmt.Sprintf("%s (%s)", root.Path, strings.Join(notes, ", ")))
+			continue
+		}
+		dirs = append(dirs, root.Path)
+	}
 	result := fmt.Sprintf("Allowed directories:\n%s", strings.Join(dirs, "\n"))
-	return mcp.NewToolResultText(result), nil
+	return toolresult.Text(result, map[string]interface{}{
+		"directories": directories,
+	}), nil
+}
+
+// allowedDirectory is one directory of the list_allowed_directories and
+// grant payloads
+type allowedDirectory struct {
+	Path    string     `json:"path"`
+	Mode    string     `json:"mode"`
+	Granted bool       `json:"granted"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// newAllowedDirectory returns the payload entry of root
+func newAllowedDirectory(root security.Root) allowedDirectory {
+	dir := allowedDirectory{Path: root.Path, Mode: string(root.Mode), Granted: root.Granted()}
+	if !root.Expires.IsZero() {
+		expires := root.Expires
+		dir.Expires = &expires
+	}
+	return dir
 }
@@ -11,7 +11,9 @@ import (
 	"testing"
 
 	"filesystem/pkg/filesystem"
+	"filesystem/pkg/policy"
 	"filesystem/pkg/security"
+	"filesystem/pkg/toolresult"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -234,3 +236,197 @@ func TestHandleReadMultipleFilesInvalid(t *testing.T) {
 		t.Fatalf("expected no valid paths error")
 	}
 }
+
+func TestHandleWriteFilePolicy(t *testing.T) {
+	base := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	rules, err := policy.New(policy.Config{Rules: []policy.Rule{
+		{Name: "docs-markdown", Tools: []string{"write_file"}, Paths: []string{"docs/**/*.md"}, MaxSize: 200 * 1024, Effect: policy.EffectAllow},
+		{Name: "docs-other", Tools: []string{"write_file"}, Paths: []string{"docs/**"}, Effect: policy.EffectDeny},
+	}})
+	if err != nil {
+		t.Fatalf("policy: %v", err)
+	}
+	pv := security.NewPathValidator([]string{base}, logger)
+	th := NewToolHandlersWithOptions(pv, filesystem.NewOperations(pv, logger), Options{Policy: rules}, logger)
+	if err := os.Mkdir(filepath.Join(base, "docs"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	ctx := context.Background()
+	allowed := newRequest(map[string]interface{}{"path": filepath.Join(base, "docs", "a.md"), "content": "# hi"})
+	if res, _ := th.handleWriteFile(ctx, allowed); res.IsError {
+		t.Fatalf("markdown write should be allowed: %+v", res)
+	}
+
+	denied := newRequest(map[string]interface{}{"path": filepath.Join(base, "docs", "a.sh"), "content": "echo"})
+	res, _ := th.handleWriteFile(ctx, denied)
+	if !res.IsError || !strings.Contains(res.Content[0].(mcp.TextContent).Text, `policy rule "docs-other"`) {
+		t.Fatalf("expected denial naming docs-other, got %+v", res)
+	}
+	if _, err := os.Stat(filepath.Join(base, "docs", "a.sh")); !os.IsNotExist(err) {
+		t.Fatalf("denied write created the file")
+	}
+}
+
+func TestReadResourceTypes(t *testing.T) {
+	th, base := newTestHandlers(t)
+	ctx := context.Background()
+	binary := filepath.Join(base, "image.png")
+	if err := os.WriteFile(binary, []byte{0x89, 'P', 'N', 'G', 0, 0xff}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(base, "notes"), []byte("plain words"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := th.ReadResource(ctx, "file://"+binary, binary)
+	if err != nil {
+		t.Fatalf("read binary: %v", err)
+	}
+	blob, ok := contents[0].(mcp.BlobResourceContents)
+	if !ok || blob.MIMEType != "image/png" || blob.Blob != "iVBORwD/" {
+		t.Fatalf("expected base64 png contents, got %#v", contents[0])
+	}
+
+	// Without an extension the type is sniffed
+	notes := filepath.Join(base, "notes")
+	contents, err = th.ReadResource(ctx, "file://"+notes, notes)
+	if err != nil {
+		t.Fatalf("read notes: %v", err)
+	}
+	text, ok := contents[0].(mcp.TextResourceContents)
+	if !ok || text.Text != "plain words" || !strings.HasPrefix(text.MIMEType, "text/plain") {
+		t.Fatalf("expected plain text contents, got %#v", contents[0])
+	}
+
+	contents, err = th.ReadResource(ctx, "file://"+base, base)
+	if err != nil {
+		t.Fatalf("read directory: %v", err)
+	}
+	text, ok = contents[0].(mcp.TextResourceContents)
+	if !ok || !strings.Contains(text.Text, "image.png") {
+		t.Fatalf("expected the directory listing, got %#v", contents[0])
+	}
+
+	if _, err := th.ReadResource(ctx, "file:///etc/passwd", "/etc/passwd"); err == nil {
+		t.Fatal("expected a path outside the allowed directories to be refused")
+	}
+}
+
+// errorCode returns the code of a failed result's payload
+func errorCode(t *testing.T, res *mcp.CallToolResult) string {
+	t.Helper()
+	if res == nil || !res.IsError {
+		t.Fatalf("expected an error result, got %+v", res)
+	}
+	payload, ok := toolresult.Payload(res)
+	if !ok {
+		t.Fatalf("error result has no payload: %+v", res)
+	}
+	var failed struct {
+		Error toolresult.Failure `json:"error"`
+	}
+	if err := json.Unmarshal(payload, &failed); err != nil {
+		t.Fatalf("decode payload %s: %v", payload, err)
+	}
+	return failed.Error.Code
+}
+
+func TestStructuredResults(t *testing.T) {
+	th, base := newTestHandlers(t)
+	ctx := context.Background()
+	p := filepath.Join(base, "a.txt")
+
+	res, _ := th.handleWriteFile(ctx, newRequest(map[string]interface{}{"path": p, "content": "hello"}))
+	payload, ok := toolresult.Payload(res)
+	if res.IsError || !ok || !strings.Contains(string(payload), `"bytesWritten":5`) {
+		t.Fatalf("unexpected write result: %+v", res)
+	}
+
+	// Reads describe the content rather than sending it twice
+	res, _ = th.handleReadFile(ctx, newRequest(map[string]interface{}{"path": p}))
+	payload, _ = toolresult.Payload(res)
+	var read struct {
+		Path     string  `json:"path"`
+		Size     int     `json:"size"`
+		MIMEType string  `json:"mimeType"`
+		Content  *string `json:"content"`
+	}
+	if err := json.Unmarshal(payload, &read); err != nil || read.Path != p || read.Size != 5 ||
+		!strings.HasPrefix(read.MIMEType, "text/plain") || read.Content != nil {
+		t.Fatalf("unexpected read payload %s: %v", payload, err)
+	}
+	res, _ = th.handleReadMultipleFiles(ctx, newRequest(map[string]interface{}{"paths": []interface{}{p}}))
+	if payload, _ = toolresult.Payload(res); strings.Contains(string(payload), "hello") {
+		t.Fatalf("read_multiple_files payload repeats the content: %s", payload)
+	}
+
+	res, _ = th.handleListDirectory(ctx, newRequest(map[string]interface{}{"path": base}))
+	payload, _ = toolresult.Payload(res)
+	var listing struct {
+		Entries []filesystem.DirectoryEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(payload, &listing); err != nil || len(listing.Entries) != 1 ||
+		listing.Entries[0].Name != "a.txt" || listing.Entries[0].Type != "file" {
+		t.Fatalf("unexpected listing payload %s: %v", payload, err)
+	}
+
+	missing := filepath.Join(base, "missing.txt")
+	if code := errorCode(t, mustCall(th.handleReadFile(ctx, newRequest(map[string]interface{}{"path": missing})))); code != toolresult.CodeNotFound {
+		t.Errorf("missing file: got %q, want NOT_FOUND", code)
+	}
+	outside := filepath.Join(os.TempDir(), "outside.txt")
+	if code := errorCode(t, mustCall(th.handleReadFile(ctx, newRequest(map[string]interface{}{"path": outside})))); code != toolresult.CodeAccessDenied {
+		t.Errorf("outside path: got %q, want ACCESS_DENIED", code)
+	}
+	dst := filepath.Join(base, "b.txt")
+	if err := os.WriteFile(dst, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	move := newRequest(map[string]interface{}{"source": p, "destination": dst})
+	if code := errorCode(t, mustCall(th.handleMoveFile(ctx, move))); code != toolresult.CodeExists {
+		t.Errorf("existing destination: got %q, want EXISTS", code)
+	}
+	large := filepath.Join(base, "large.bin")
+	if err := os.WriteFile(large, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(large, 2*1024*1024); err != nil {
+		t.Fatal(err)
+	}
+	if code := errorCode(t, mustCall(th.handleReadFile(ctx, newRequest(map[string]interface{}{"path": large})))); code != toolresult.CodeTooLarge {
+		t.Errorf("large file: got %q, want TOO_LARGE", code)
+	}
+	if code := errorCode(t, mustCall(th.handleReadFile(ctx, newRequest(map[string]interface{}{})))); code != toolresult.CodeInvalidArgument {
+		t.Errorf("missing argument: got %q, want INVALID_ARGUMENT", code)
+	}
+}
+
+// mustCall drops the Go error of a handler, which is always nil
+func mustCall(res *mcp.CallToolResult, _ error) *mcp.CallToolResult {
+	return res
+}
+
+func TestToolAnnotations(t *testing.T) {
+	th, _ := newTestHandlers(t)
+	for _, tool := range th.Tools() {
+		annotations := tool.Tool.Annotations
+		if annotations.Title == "" {
+			t.Errorf("%s has no title", tool.Tool.Name)
+		}
+		readOnly := tool.Tool.Name == "read_file" || tool.Tool.Name == "list_directory" ||
+			tool.Tool.Name == "search_files" || tool.Tool.Name == "list_allowed_directories"
+		if readOnly && (!annotations.ReadOnlyHint || annotations.DestructiveHint) {
+			t.Errorf("%s should be read-only: %+v", tool.Tool.Name, annotations)
+		}
+	}
+	write := th.createWriteFileTool().Annotations
+	if write.ReadOnlyHint || !write.DestructiveHint || !write.IdempotentHint {
+		t.Errorf("write_file should be destructive and idempotent: %+v", write)
+	}
+	create := th.createCreateDirectoryTool().Annotations
+	if create.ReadOnlyHint || create.DestructiveHint || !create.IdempotentHint {
+		t.Errorf("create_directory should be idempotent only: %+v", create)
+	}
+}
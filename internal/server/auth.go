@@ -0,0 +1,92 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"filesystem/internal/transport"
+	"filesystem/pkg/auth"
+	"filesystem/pkg/config"
+	"filesystem/pkg/security"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// authenticate identifies the client of an HTTP request by its bearer
+// token when auth is enabled. The tokens of the active snapshot are used,
+// so a reload adds or revokes tokens for sessions already open.
+func (s *Server) authenticate(r *http.Request) (string, error) {
+	st := s.state.Load()
+	if st == nil {
+		return "", fmt.Errorf("server is not ready")
+	}
+	if st.tokens == nil {
+		return "", nil
+	}
+	token, err := auth.BearerToken(r)
+	if err != nil {
+		return "", err
+	}
+	return st.tokens.Authenticate(token)
+}
+
+// tokenScope returns the directories the token of a session is limited to,
+// or nil when it may use all of the allowed directories
+func (st *snapshot) tokenScope(session server.ClientSession) ([]security.Root, error) {
+	authenticated, ok := session.(transport.Authenticated)
+	if !ok || authenticated.Principal() == "" || !st.config.Auth.Enabled {
+		return nil, nil
+	}
+	name := authenticated.Principal()
+	for _, token := range st.config.Auth.Tokens {
+		if token.Name == name {
+			return token.Scope(), nil
+		}
+	}
+	return nil, fmt.Errorf("token %s is no longer configured", name)
+}
+
+// loadTLSConfig reads the certificate files of the HTTP transports. It
+// runs before sandboxing, which may hide the files afterwards.
+func loadTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("client CA file %s holds no certificates", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// loopbackAddress reports whether a listen address only accepts
+// connections from the local machine
+func loopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
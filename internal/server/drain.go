@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// drainCancelGrace is how long shutdown waits for calls to return once
+// their contexts are cancelled, so their results can still be sent
+const drainCancelGrace = 2 * time.Second
+
+// inflight tracks the tool calls running so shutdown can wait for them
+type inflight struct {
+	// mu guards all fields
+	mu       sync.Mutex
+	draining bool
+	running  map[uint64]context.CancelFunc
+	nextID   uint64
+
+	// idle is closed when draining and no call is running
+	idle chan struct{}
+}
+
+// newInflight creates a tracker accepting calls
+func newInflight() *inflight {
+	return &inflight{
+		running: make(map[uint64]context.CancelFunc),
+		idle:    make(chan struct{}),
+	}
+}
+
+// begin registers a call, returning its context and the function that ends
+// it. It reports false once draining has begun.
+func (f *inflight) begin(ctx context.Context) (context.Context, func(), bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.draining {
+		return ctx, func() {}, false
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	id := f.nextID
+	f.nextID++
+	f.running[id] = cancel
+	return ctx, func() {
+		cancel()
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.running, id)
+		if f.draining && len(f.running) == 0 {
+			close(f.idle)
+		}
+	}, true
+}
+
+// drain refuses new calls and waits for running ones to finish until ctx
+// is done. Calls still running then are cancelled and given
+// drainCancelGrace to return. It returns the number cancelled.
+func (f *inflight) drain(ctx context.Context) int {
+	f.mu.Lock()
+	if !f.draining {
+		f.draining = true
+		if len(f.running) == 0 {
+			close(f.idle)
+		}
+	}
+	f.mu.Unlock()
+
+	select {
+	case <-f.idle:
+		return 0
+	case <-ctx.Done():
+	}
+
+	f.mu.Lock()
+	cancelled := len(f.running)
+	for _, cancel := range f.running {
+		cancel()
+	}
+	f.mu.Unlock()
+
+	timer := time.NewTimer(drainCancelGrace)
+	defer timer.Stop()
+	select {
+	case <-f.idle:
+	case <-timer.C:
+	}
+	return cancelled
+}
+
+// isDraining reports whether new calls are refused
+func (f *inflight) isDraining() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.draining
+}
+
+// count returns the number of calls running
+func (f *inflight) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.running)
+}
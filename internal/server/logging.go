@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// methodSetLevel sets the lowest level of the logs sent to a client
+	methodSetLevel = "logging/setLevel"
+
+	// methodLogMessage carries one log record to a client
+	methodLogMessage = "notifications/message"
+
+	// loggerName names the server as the source of log messages
+	loggerName = "filesystem"
+
+	// defaultClientLevel is the lowest level sent to clients that have not
+	// set one, so denials and skipped entries reach them unasked
+	defaultClientLevel = slog.LevelWarn
+)
+
+// clientLevels maps the MCP log levels to slog levels. slog has no levels
+// between its four, so the rest sit in the gaps above them.
+var clientLevels = map[mcp.LoggingLevel]slog.Level{
+	mcp.LoggingLevelDebug:     slog.LevelDebug,
+	mcp.LoggingLevelInfo:      slog.LevelInfo,
+	mcp.LoggingLevelNotice:    slog.LevelInfo + 2,
+	mcp.LoggingLevelWarning:   slog.LevelWarn,
+	mcp.LoggingLevelError:     slog.LevelError,
+	mcp.LoggingLevelCritical:  slog.LevelError + 4,
+	mcp.LoggingLevelAlert:     slog.LevelError + 8,
+	mcp.LoggingLevelEmergency: slog.LevelError + 12,
+}
+
+// clientLevel returns the MCP level of a slog level
+func clientLevel(level slog.Level) mcp.LoggingLevel {
+	switch {
+	case level >= slog.LevelError:
+		return mcp.LoggingLevelError
+	case level >= slog.LevelWarn:
+		return mcp.LoggingLevelWarning
+	case level >= slog.LevelInfo+2:
+		return mcp.LoggingLevelNotice
+	case level >= slog.LevelInfo:
+		return mcp.LoggingLevelInfo
+	}
+	return mcp.LoggingLevelDebug
+}
+
+// logLevels tracks the log level each session asked for
+type logLevels struct {
+	mu       sync.Mutex
+	sessions map[string]slog.Level
+}
+
+// newLogLevels creates an empty tracker
+func newLogLevels() *logLevels {
+	return &logLevels{sessions: make(map[string]slog.Level)}
+}
+
+// set records the level a session asked for
+func (l *logLevels) set(sessionID string, level slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sessions[sessionID] = level
+}
+
+// forget drops a session's level once it ends
+func (l *logLevels) forget(sessionID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.sessions, sessionID)
+}
+
+// enabled reports whether records at level are sent to the session of ctx
+func (l *logLevels) enabled(ctx context.Context, level slog.Level) bool {
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return false
+	}
+	l.mu.Lock()
+	lowest, ok := l.sessions[session.SessionID()]
+	l.mu.Unlock()
+	if !ok {
+		lowest = defaultClientLevel
+	}
+	return level >= lowest
+}
+
+// setLogLevel handles logging/setLevel for the calling session
+func (s *Server) setLogLevel(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		Level mcp.LoggingLevel `json:"level"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	level, ok := clientLevels[p.Level]
+	if !ok {
+		return nil, fmt.Errorf("unknown log level %q", p.Level)
+	}
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return nil, fmt.Errorf("log levels require a client session")
+	}
+
+	s.logLevels.set(session.SessionID(), level)
+	s.log().Debug("Client log level set", "session", session.SessionID(), "level", p.Level)
+	return mcp.EmptyResult{}, nil
+}
+
+// clientLogger returns a logger that writes to logger and also sends each
+// record logged with the context of a client session to that client as
+// notifications/message. Records logged without a session, or by another
+// session, never reach a client.
+func (s *Server) clientLogger(logger *slog.Logger) *slog.Logger {
+	if _, ok := logger.Handler().(*clientLogHandler); ok {
+		return logger
+	}
+	return slog.New(&clientLogHandler{next: logger.Handler(), server: s})
+}
+
+// clientLogHandler is the slog handler of clientLogger
+type clientLogHandler struct {
+	next   slog.Handler
+	server *Server
+
+	// attrs were added with WithAttrs, their keys qualified by the groups
+	// open at the time; group is the prefix of those open now
+	attrs []slog.Attr
+	group string
+}
+
+// Enabled implements slog.Handler
+func (h *clientLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level) || h.server.logLevels.enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *clientLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	var err error
+	if h.next.Enabled(ctx, r.Level) {
+		err = h.next.Handle(ctx, r)
+	}
+	if h.server.mcpServer == nil || !h.server.logLevels.enabled(ctx, r.Level) {
+		return err
+	}
+
+	data := map[string]any{"message": r.Message}
+	for _, attr := range h.attrs {
+		addAttr(data, "", attr)
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		addAttr(data, h.group, attr)
+		return true
+	})
+	// A client that cannot keep up loses messages; the next handler has
+	// them all
+	_ = h.server.mcpServer.SendNotificationToClient(ctx, methodLogMessage, map[string]any{
+		"level":  clientLevel(r.Level),
+		"logger": loggerName,
+		"data":   data,
+	})
+	return err
+}
+
+// WithAttrs implements slog.Handler
+func (h *clientLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.next = h.next.WithAttrs(attrs)
+	next.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, attr := range attrs {
+		attr.Key = h.group + attr.Key
+		next.attrs = append(next.attrs, attr)
+	}
+	return &next
+}
+
+// WithGroup implements slog.Handler
+func (h *clientLogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.next = h.next.WithGroup(name)
+	next.group = h.group + name + "."
+	return &next
+}
+
+// addAttr adds attr to data under its key qualified by prefix, flattening
+// groups and writing errors as their text
+func addAttr(data map[string]any, prefix string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, member := range value.Group() {
+			addAttr(data, prefix, member)
+		}
+		return
+	}
+	if attr.Key == "" {
+		return
+	}
+	if err, ok := value.Any().(error); ok {
+		data[prefix+attr.Key] = err.Error()
+		return
+	}
+	data[prefix+attr.Key] = value.Any()
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// MCP protocol versions the server speaks, oldest first
+const (
+	// protocol20241105 is the version Claude Desktop and older clients ask for
+	protocol20241105 = "2024-11-05"
+
+	// protocol20250326 adds tool annotations, progress messages and
+	// JSON-RPC batches
+	protocol20250326 = "2025-03-26"
+
+	// latestProtocolVersion is offered to clients asking for a version the
+	// server does not speak
+	latestProtocolVersion = protocol20250326
+)
+
+// supportedProtocolVersions are the versions the server negotiates
+var supportedProtocolVersions = []string{protocol20241105, protocol20250326}
+
+// negotiateProtocolVersion returns the version to answer an initialize
+// request for requested with: the same version when the server speaks it
+// and the latest otherwise, leaving the client to disconnect if it cannot
+// speak that one
+func negotiateProtocolVersion(requested string) string {
+	for _, version := range supportedProtocolVersions {
+		if version == requested {
+			return version
+		}
+	}
+	return latestProtocolVersion
+}
+
+// protocolVersions tracks the version negotiated by each session
+type protocolVersions struct {
+	mu       sync.Mutex
+	sessions map[string]string
+}
+
+// newProtocolVersions creates an empty tracker
+func newProtocolVersions() *protocolVersions {
+	return &protocolVersions{sessions: make(map[string]string)}
+}
+
+// set records the version negotiated by a session
+func (p *protocolVersions) set(sessionID, version string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sessions[sessionID] = version
+}
+
+// forget drops a session's version once it ends
+func (p *protocolVersions) forget(sessionID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.sessions, sessionID)
+}
+
+// since reports whether the session of ctx negotiated version or a later
+// one. Calls outside an initialized session, such as in-process ones, get
+// every feature.
+func (p *protocolVersions) since(ctx context.Context, version string) bool {
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return true
+	}
+	p.mu.Lock()
+	negotiated, ok := p.sessions[session.SessionID()]
+	p.mu.Unlock()
+	// Versions are dates, so they order as strings
+	return !ok || negotiated >= version
+}
+
+// addProtocolHooks negotiates the protocol version of each session
+func (s *Server) addProtocolHooks(hooks *server.Hooks) {
+	hooks.AddAfterInitialize(func(ctx context.Context, id any, req *mcp.InitializeRequest, result *mcp.InitializeResult) {
+		requested := req.Params.ProtocolVersion
+		result.ProtocolVersion = negotiateProtocolVersion(requested)
+		session := server.ClientSessionFromContext(ctx)
+		if session == nil {
+			return
+		}
+		s.protocols.set(session.SessionID(), result.ProtocolVersion)
+		if result.ProtocolVersion != requested {
+			s.log().Warn("Client requested an unsupported protocol version",
+				"session", session.SessionID(), "requested", requested, "offered", result.ProtocolVersion)
+			return
+		}
+		s.log().Debug("Protocol version negotiated", "session", session.SessionID(), "version", requested)
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		s.protocols.forget(session.SessionID())
+	})
+}
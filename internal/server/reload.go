@@ -0,0 +1,266 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"filesystem/internal/handlers"
+	"filesystem/pkg/config"
+	"filesystem/pkg/filesystem"
+	"filesystem/pkg/toolresult"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxAcquireAttempts bounds retries when a reload retires the snapshot a
+// call was about to use per Rule 2
+const maxAcquireAttempts = 8
+
+// methodProgress reports how far a client's request has got
+const methodProgress = "notifications/progress"
+
+// Reload validates the new configuration and atomically swaps in a new path
+// validator, operations layer and logger. Calls already in progress finish
+// with the previous snapshot. Clients are sent tools/list_changed when the
+// advertised tools differ.
+func (s *Server) Reload(cfg *config.Config, logger *slog.Logger) error {
+	// Input validation per Rule 7
+	if cfg == nil {
+		return fmt.Errorf("configuration is required")
+	}
+	if logger == nil {
+		return fmt.Errorf("logger is required")
+	}
+	logger = s.clientLogger(logger)
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	old := s.state.Load()
+	if old == nil {
+		return fmt.Errorf("server is not initialized")
+	}
+
+	// Identity and transport are fixed for the lifetime of the process
+	next := *cfg
+	if !reflect.DeepEqual(next.Server, old.config.Server) {
+		logger.Warn("Server settings changed; restart required for them to take effect",
+			"name", next.Server.Name,
+			"version", next.Server.Version,
+			"transport", next.Server.Transport)
+		next.Server = old.config.Server
+	}
+
+	// The approval queue and its socket are created once at startup
+	if !reflect.DeepEqual(next.Approval, old.config.Approval) {
+		logger.Warn("Approval settings changed; restart required for them to take effect")
+		next.Approval = old.config.Approval
+	}
+
+	// Keep the open audit log unless it moved; two writers on one file
+	// would fork the hash chain
+	auditLog := old.auditLog
+	if next.Audit.Path != old.config.Audit.Path {
+		opened, err := openAuditLog(next.Audit, logger)
+		if err != nil {
+			return err
+		}
+		auditLog = opened
+	} else if next.Audit != old.config.Audit {
+		logger.Warn("Audit rotation settings changed; restart required for them to take effect")
+		next.Audit = old.config.Audit
+	}
+
+	st, err := newSnapshot(&next, logger, auditLog, s.grants)
+	if err != nil {
+		if auditLog != nil && auditLog != old.auditLog {
+			auditLog.Close()
+		}
+		return err
+	}
+
+	// New limits apply to the usage already counted
+	s.quotas.SetConfig(next.Quotas)
+	toolsChanged := !sameTools(old.tools, st.tools)
+	s.state.Store(st)
+
+	// Grants outside the new parents, or all of them when the admin tools
+	// were disabled, end with the old configuration
+	grantPolicy := next.GrantPolicy()
+	for _, root := range s.grants.Retain(grantPolicy.Permits) {
+		logger.Warn("Directory grant revoked by configuration reload", "path", root.Path)
+	}
+
+	// SetTools notifies initialized clients with tools/list_changed
+	if toolsChanged {
+		s.mcpServer.SetTools(s.dispatchers(st.tools)...)
+	}
+	s.syncResources()
+
+	closeAudit := old.auditLog != nil && old.auditLog != auditLog
+	old.retire(func() {
+		if !closeAudit {
+			return
+		}
+		if err := old.auditLog.Close(); err != nil {
+			logger.Error("Failed to close previous audit log", "error", err)
+		}
+	})
+
+	logger.Info("Configuration reloaded",
+		"allowed_directories", st.pathValidator.GetAllowedDirectories(),
+		"log_level", next.LogLevel,
+		"tools_changed", toolsChanged)
+	return nil
+}
+
+// dispatchers wraps each tool so calls are served by the snapshot that is
+// active when the call starts
+func (s *Server) dispatchers(tools []server.ServerTool) []server.ServerTool {
+	wrapped := make([]server.ServerTool, 0, len(tools))
+	for _, tool := range tools {
+		wrapped = append(wrapped, server.ServerTool{Tool: tool.Tool, Handler: s.dispatch(tool.Tool.Name)})
+	}
+	return wrapped
+}
+
+// dispatch returns a handler that runs the named tool on the active
+// snapshot, holding calls that require approval in the queue
+func (s *Server) dispatch(name string) server.ToolHandlerFunc {
+	return s.runTool(name, true)
+}
+
+// runTool returns the handler behind dispatch. An approved call runs it
+// again with gated false, against the snapshot active at approval time, so
+// it is validated under the configuration then in effect.
+func (s *Server) runTool(name string, gated bool) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Approved calls are tracked too, as they run after their request
+		ctx, done, ok := s.calls.begin(ctx)
+		if !ok {
+			return toolresult.Errorf(toolresult.CodeUnavailable, "Error: server is shutting down"), nil
+		}
+		defer done()
+
+		st := s.acquireSnapshot()
+		if st == nil {
+			return toolresult.Errorf(toolresult.CodeUnavailable, "Error: server is not ready"), nil
+		}
+		defer st.release()
+
+		tools, err := s.scopedTools(ctx, st)
+		if err != nil {
+			return toolresult.Error(err), nil
+		}
+
+		handler, ok := tools.handlers[name]
+		if !ok {
+			return toolresult.Errorf(toolresult.CodeAccessDenied, "Error: tool %s is not available", name), nil
+		}
+		handler = withTimeout(name, st.config.Timeouts.For(name), handler)
+		// Only the client's own call may report progress on its token; an
+		// approved call runs after that request was answered
+		if gated {
+			handler = s.withProgress(handler)
+		}
+		// Held calls are checked and rate limited when they are submitted,
+		// charged against quotas when they are approved, and run in the
+		// session that made them
+		if gated && s.approvals.Requires(req) {
+			handler = s.approvals.Defer(s.inSession(ctx, s.runTool(name, false)))
+			if st.config.Quotas.Enabled() {
+				handler = s.quotas.RateLimit(tools.pathValidator.RootOf)(handler)
+			}
+			handler = precheck(tools.toolHandlers, handler)
+		} else if st.config.Quotas.Enabled() {
+			handler = s.quotas.Middleware(tools.pathValidator.RootOf)(handler)
+		}
+		if st.auditLog != nil {
+			handler = st.auditLog.Middleware(st.logger)(handler)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// precheck refuses a call that would fail its path or policy checks
+// before next sees it
+func precheck(th *handlers.ToolHandlers, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if result := th.Check(ctx, req); result != nil {
+			return result, nil
+		}
+		return next(ctx, req)
+	}
+}
+
+// withTimeout bounds next by timeout, reporting a call that ran out of time
+// as such rather than by the error it failed with
+func withTimeout(name string, timeout time.Duration, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		result, err := next(ctx, req)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && (err != nil || result == nil || result.IsError) {
+			return toolresult.Errorf(toolresult.CodeTimeout, "Error: %s timed out after %s", name, timeout), nil
+		}
+		return result, err
+	}
+}
+
+// withProgress sends the client notifications/progress for the walks and
+// copies of a call that carries a progress token
+func (s *Server) withProgress(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if req.Params.Meta == nil || req.Params.Meta.ProgressToken == nil {
+			return next(ctx, req)
+		}
+		token := req.Params.Meta.ProgressToken
+		session := ctx
+		ctx = filesystem.WithProgress(ctx, func(p filesystem.Progress) {
+			params := map[string]any{
+				"progressToken": token,
+				"progress":      p.Done(),
+			}
+			// Progress messages are new in 2025-03-26
+			if s.protocols.since(session, protocol20250326) {
+				params["message"] = p.String()
+			}
+			err := s.mcpServer.SendNotificationToClient(session, methodProgress, params)
+			if err != nil {
+				s.log().Debug("Failed to send progress", "tool", req.Params.Name, "error", err)
+			}
+		})
+		return next(ctx, req)
+	}
+}
+
+// inSession returns a handler that runs next in the client session of ctx,
+// whatever context it is later called with
+func (s *Server) inSession(ctx context.Context, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return next
+	}
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return next(s.mcpServer.WithContext(ctx, session), req)
+	}
+}
+
+// acquireSnapshot pins the active snapshot for the duration of a call
+func (s *Server) acquireSnapshot() *snapshot {
+	for i := 0; i < maxAcquireAttempts; i++ {
+		st := s.state.Load()
+		if st == nil {
+			return nil
+		}
+		if st.acquire() {
+			return st
+		}
+	}
+	return nil
+}
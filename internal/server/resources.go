@@ -0,0 +1,447 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"filesystem/internal/transport"
+	"filesystem/pkg/security"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// fileTemplate is the resource template of the files beneath the
+	// allowed directories. The reserved expansion {+path} matches the
+	// slashes of a path, which {path} would not.
+	fileTemplate = "file:///{+path}"
+
+	// resourcePollInterval is how often subscribed resources are checked
+	// for changes
+	resourcePollInterval = 2 * time.Second
+
+	// maxSubscriptions bounds the subscriptions of all sessions per Rule 2
+	maxSubscriptions = 1024
+)
+
+// Resource methods the MCP server does not implement itself
+const (
+	methodSubscribe       = "resources/subscribe"
+	methodUnsubscribe     = "resources/unsubscribe"
+	methodResourceUpdated = "notifications/resources/updated"
+)
+
+// methods returns the handlers of the client requests the transports
+// serve on behalf of the MCP server
+func (s *Server) methods() transport.Methods {
+	return transport.Methods{
+		string(mcp.MethodToolsList):     s.listTools,
+		string(mcp.MethodResourcesList): s.listResources,
+		methodSubscribe:                 s.subscribe,
+		methodUnsubscribe:               s.unsubscribe,
+		methodSetLevel:                  s.setLogLevel,
+	}
+}
+
+// fileURI returns the file:// URI of an absolute path
+func fileURI(path string) string {
+	p := filepath.ToSlash(path)
+	if p == "" || p[0] != '/' {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+// syncResources registers one resource per allowed directory of the
+// active snapshot, including grants. The MCP server sends
+// resources/list_changed for each one added or removed, and listResources
+// shows each session only its own.
+func (s *Server) syncResources() {
+	st := s.state.Load()
+	if st == nil || s.mcpServer == nil {
+		return
+	}
+
+	s.resourcesMu.Lock()
+	defer s.resourcesMu.Unlock()
+
+	want := make(map[string]string)
+	for _, dir := range st.pathValidator.GetAllowedDirectories() {
+		want[fileURI(dir)] = dir
+	}
+	for uri := range s.resources {
+		if _, ok := want[uri]; !ok {
+			s.mcpServer.RemoveResource(uri)
+			delete(s.resources, uri)
+		}
+	}
+	for uri, dir := range want {
+		if s.resources[uri] {
+			continue
+		}
+		s.mcpServer.AddResource(mcp.NewResource(uri, filepath.Base(dir),
+			mcp.WithResourceDescription("Allowed directory "+dir+"; reads return its listing"),
+			mcp.WithMIMEType("text/plain")), s.readResource)
+		s.resources[uri] = true
+	}
+}
+
+// listResources handles resources/list, leaving out the directories the
+// session's token scope and client roots do not include
+func (s *Server) listResources(ctx context.Context, params json.RawMessage) (any, error) {
+	result, err := s.forward(ctx, mcp.MethodResourcesList, params)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := result.(mcp.ListResourcesResult)
+	if !ok {
+		return result, nil
+	}
+
+	st := s.acquireSnapshot()
+	if st == nil {
+		return nil, fmt.Errorf("server is not ready")
+	}
+	defer st.release()
+
+	tools, err := s.scopedTools(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+	allowed := make(map[string]bool)
+	for _, dir := range tools.pathValidator.GetAllowedDirectories() {
+		allowed[fileURI(dir)] = true
+	}
+	resources := make([]mcp.Resource, 0, len(list.Resources))
+	for _, resource := range list.Resources {
+		if allowed[resource.URI] {
+			resources = append(resources, resource)
+		}
+	}
+	list.Resources = resources
+	return list, nil
+}
+
+// readResource serves resources/read for the allowed directories and the
+// file template with the snapshot and session scope active for the call
+func (s *Server) readResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	ctx, done, ok := s.calls.begin(ctx)
+	if !ok {
+		return nil, fmt.Errorf("server is shutting down")
+	}
+	defer done()
+
+	st := s.acquireSnapshot()
+	if st == nil {
+		return nil, fmt.Errorf("server is not ready")
+	}
+	defer st.release()
+
+	tools, err := s.scopedTools(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+
+	read := func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		path, err := rootPath(req.Params.URI)
+		if err != nil {
+			return nil, err
+		}
+		return tools.toolHandlers.ReadResource(ctx, req.Params.URI, path)
+	}
+	handler := server.ResourceHandlerFunc(read)
+	if st.auditLog != nil {
+		handler = st.auditLog.ResourceMiddleware(st.logger)(handler)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, st.config.Timeouts.For(string(mcp.MethodResourcesRead)))
+	defer cancel()
+	return handler(ctx, req)
+}
+
+// subscribe handles resources/subscribe for a file or directory the
+// session may read
+func (s *Server) subscribe(ctx context.Context, params json.RawMessage) (any, error) {
+	uri, err := resourceURI(params)
+	if err != nil {
+		return nil, err
+	}
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return nil, fmt.Errorf("subscriptions require a client session")
+	}
+
+	st := s.acquireSnapshot()
+	if st == nil {
+		return nil, fmt.Errorf("server is not ready")
+	}
+	defer st.release()
+
+	tools, err := s.scopedTools(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+	path, err := rootPath(uri)
+	if err != nil {
+		return nil, err
+	}
+	validPath, err := tools.pathValidator.ValidatePath(path, security.OpRead)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.subscriptions.add(session, uri, validPath); err != nil {
+		return nil, err
+	}
+	st.logger.Info("Resource subscribed", "session", session.SessionID(), "uri", uri, "path", validPath)
+	return mcp.EmptyResult{}, nil
+}
+
+// unsubscribe handles resources/unsubscribe; ending a subscription the
+// session does not hold succeeds
+func (s *Server) unsubscribe(ctx context.Context, params json.RawMessage) (any, error) {
+	uri, err := resourceURI(params)
+	if err != nil {
+		return nil, err
+	}
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return nil, fmt.Errorf("subscriptions require a client session")
+	}
+
+	if s.subscriptions.remove(session.SessionID(), uri) {
+		s.log().Info("Resource unsubscribed", "session", session.SessionID(), "uri", uri)
+	}
+	return mcp.EmptyResult{}, nil
+}
+
+// resourceURI returns the uri parameter of a subscription request
+func resourceURI(params json.RawMessage) (string, error) {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "", fmt.Errorf("invalid params: %w", err)
+	}
+	if p.URI == "" {
+		return "", fmt.Errorf("uri is required")
+	}
+	return p.URI, nil
+}
+
+// watchResources polls the subscribed resources every
+// resourcePollInterval until ctx is cancelled
+func (s *Server) watchResources(ctx context.Context) {
+	ticker := time.NewTicker(resourcePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.pollResources()
+		}
+	}
+}
+
+// pollResources sends notifications/resources/updated to the subscribers
+// of each resource that changed since the last poll. Subscriptions the
+// subscriber's session may no longer read are dropped.
+func (s *Server) pollResources() {
+	st := s.acquireSnapshot()
+	if st == nil {
+		return
+	}
+	defer st.release()
+
+	// A nil tool set drops the session's subscriptions. Sessions that
+	// subscribed since the list was taken were checked as they did so.
+	views := make(map[string]*toolSet)
+	for id, session := range s.subscriptions.subscribers() {
+		ctx, cancel := context.WithTimeout(s.mcpServer.WithContext(context.Background(), session), resourcePollInterval)
+		tools, err := s.scopedTools(ctx, st)
+		cancel()
+		if err != nil {
+			st.logger.Debug("Dropping resource subscriptions", "session", id, "error", err)
+		}
+		views[id] = tools
+	}
+	allowed := func(sessionID, path string) bool {
+		tools, ok := views[sessionID]
+		if !ok {
+			return true
+		}
+		if tools == nil {
+			return false
+		}
+		_, err := tools.pathValidator.ValidatePath(path, security.OpRead)
+		return err == nil
+	}
+	for _, update := range s.subscriptions.poll(allowed) {
+		err := s.mcpServer.SendNotificationToSpecificClient(update.session, methodResourceUpdated,
+			map[string]any{"uri": update.uri})
+		if err != nil {
+			st.logger.Debug("Failed to send resource update", "session", update.session, "uri", update.uri, "error", err)
+		}
+	}
+}
+
+// resourceState is what a poll compares to tell that a resource changed
+type resourceState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// statResource returns the current state of the resource at path
+func statResource(path string) resourceState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return resourceState{}
+	}
+	return resourceState{exists: true, size: info.Size(), modTime: info.ModTime()}
+}
+
+// equal reports whether two states are the same
+func (r resourceState) equal(other resourceState) bool {
+	return r.exists == other.exists && r.size == other.size && r.modTime.Equal(other.modTime)
+}
+
+// watchedResource is one subscribed resource and its subscribers
+type watchedResource struct {
+	path     string
+	sessions map[string]server.ClientSession
+	state    resourceState
+}
+
+// resourceUpdate names a subscriber to notify of a changed resource
+type resourceUpdate struct {
+	session string
+	uri     string
+}
+
+// subscriptions tracks the resources each session subscribed to
+type subscriptions struct {
+	// mu guards all fields
+	mu      sync.Mutex
+	watched map[string]*watchedResource
+	count   int
+}
+
+// newSubscriptions creates an empty set of subscriptions
+func newSubscriptions() *subscriptions {
+	return &subscriptions{watched: make(map[string]*watchedResource)}
+}
+
+// add subscribes a session to uri, the resource at path
+func (w *subscriptions) add(session server.ClientSession, uri, path string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	sessionID := session.SessionID()
+	resource, ok := w.watched[uri]
+	if ok && resource.sessions[sessionID] != nil {
+		return nil
+	}
+	if w.count >= maxSubscriptions {
+		return fmt.Errorf("too many resource subscriptions (limit %d)", maxSubscriptions)
+	}
+	if !ok {
+		resource = &watchedResource{
+			path:     path,
+			sessions: make(map[string]server.ClientSession),
+			state:    statResource(path),
+		}
+		w.watched[uri] = resource
+	}
+	resource.sessions[sessionID] = session
+	w.count++
+	return nil
+}
+
+// remove ends the subscription of a session to uri and reports whether it
+// had one
+func (w *subscriptions) remove(sessionID, uri string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	resource, ok := w.watched[uri]
+	if !ok || resource.sessions[sessionID] == nil {
+		return false
+	}
+	delete(resource.sessions, sessionID)
+	w.count--
+	if len(resource.sessions) == 0 {
+		delete(w.watched, uri)
+	}
+	return true
+}
+
+// forget ends all subscriptions of a session
+func (w *subscriptions) forget(sessionID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for uri, resource := range w.watched {
+		if resource.sessions[sessionID] == nil {
+			continue
+		}
+		delete(resource.sessions, sessionID)
+		w.count--
+		if len(resource.sessions) == 0 {
+			delete(w.watched, uri)
+		}
+	}
+}
+
+// subscribers returns every session holding a subscription
+func (w *subscriptions) subscribers() map[string]server.ClientSession {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	sessions := make(map[string]server.ClientSession)
+	for _, resource := range w.watched {
+		for id, session := range resource.sessions {
+			sessions[id] = session
+		}
+	}
+	return sessions
+}
+
+// poll stats every watched resource and returns the subscribers of those
+// that changed since the last poll. Subscriptions to a path the session is
+// no longer allowed to read are dropped.
+func (w *subscriptions) poll(allowed func(sessionID, path string) bool) []resourceUpdate {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var updates []resourceUpdate
+	for uri, resource := range w.watched {
+		for sessionID := range resource.sessions {
+			if !allowed(sessionID, resource.path) {
+				delete(resource.sessions, sessionID)
+				w.count--
+			}
+		}
+		if len(resource.sessions) == 0 {
+			delete(w.watched, uri)
+			continue
+		}
+		state := statResource(resource.path)
+		if state.equal(resource.state) {
+			continue
+		}
+		resource.state = state
+		for sessionID := range resource.sessions {
+			updates = append(updates, resourceUpdate{session: sessionID, uri: uri})
+		}
+	}
+	return updates
+}
@@ -0,0 +1,287 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"filesystem/internal/transport"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// methodListRoots asks the client for its roots
+	methodListRoots = "roots/list"
+
+	// methodRootsListChanged is sent by clients whose roots changed
+	methodRootsListChanged = "notifications/roots/list_changed"
+
+	// methodInitialized is sent by clients once initialization completes
+	methodInitialized = "notifications/initialized"
+
+	// maxClientRoots bounds the roots accepted from one client per Rule 2
+	maxClientRoots = 100
+)
+
+// sessionRoots is what is known about one client's roots
+type sessionRoots struct {
+	// supported is whether the client advertised the roots capability
+	supported bool
+
+	// requested is whether roots/list has been sent
+	requested bool
+
+	// ready is closed once the first list or error has arrived
+	ready chan struct{}
+
+	// paths are the client's roots as directories; version counts lists
+	paths   []string
+	version uint64
+	err     error
+}
+
+// clientRoots tracks the roots of every session across reloads
+type clientRoots struct {
+	mu       sync.Mutex
+	sessions map[string]*sessionRoots
+}
+
+// newClientRoots creates an empty tracker
+func newClientRoots() *clientRoots {
+	return &clientRoots{sessions: make(map[string]*sessionRoots)}
+}
+
+// register records whether a newly initialized client supports roots
+func (c *clientRoots) register(sessionID string, supported bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sessions[sessionID] = &sessionRoots{supported: supported, ready: make(chan struct{})}
+}
+
+// forget drops a session that has ended
+func (c *clientRoots) forget(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.sessions, sessionID)
+}
+
+// startRequest marks roots/list as sent for a session and reports whether
+// it should be sent; first limits it to sessions never asked before
+func (c *clientRoots) startRequest(sessionID string, first bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.sessions[sessionID]
+	if !ok || !r.supported || (first && r.requested) {
+		return false
+	}
+	r.requested = true
+	return true
+}
+
+// set stores the outcome of a roots/list request. An error only replaces
+// a list the session has never had.
+func (c *clientRoots) set(sessionID string, paths []string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.sessions[sessionID]
+	if !ok {
+		return
+	}
+	if err == nil {
+		r.paths = paths
+		r.err = nil
+		r.version++
+	} else if r.version == 0 {
+		r.err = err
+	}
+	select {
+	case <-r.ready:
+	default:
+		close(r.ready)
+	}
+}
+
+// get returns the tracked state of a session, or nil
+func (c *clientRoots) get(sessionID string) *sessionRoots {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessions[sessionID]
+}
+
+// wait returns a copy of a session's roots once the first list has
+// arrived, or nil when the client does not support roots
+func (c *clientRoots) wait(ctx context.Context, sessionID string, timeout time.Duration) (*sessionRoots, error) {
+	r := c.get(sessionID)
+	if r == nil {
+		return nil, fmt.Errorf("client roots are not known for session %s", sessionID)
+	}
+	if !r.supported {
+		return nil, nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-r.ready:
+	case <-timer.C:
+		return nil, fmt.Errorf("timed out waiting for the client's roots")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r.err != nil {
+		return nil, fmt.Errorf("client roots unavailable: %w", r.err)
+	}
+	return &sessionRoots{supported: true, paths: r.paths, version: r.version}, nil
+}
+
+// addRootsHooks tracks each client's roots capability and requests its
+// roots once it is initialized and whenever they change
+func (s *Server) addRootsHooks(hooks *server.Hooks) {
+	hooks.AddAfterInitialize(func(ctx context.Context, id any, req *mcp.InitializeRequest, result *mcp.InitializeResult) {
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			s.roots.register(session.SessionID(), req.Params.Capabilities.Roots != nil)
+		}
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		s.roots.forget(session.SessionID())
+		if st := s.state.Load(); st != nil {
+			st.dropView(session.SessionID())
+		}
+	})
+}
+
+// handleRootsNotification requests the roots of the notifying client when
+// client roots are enabled
+func (s *Server) handleRootsNotification(ctx context.Context, notification mcp.JSONRPCNotification) {
+	st := s.state.Load()
+	session := server.ClientSessionFromContext(ctx)
+	if st == nil || session == nil || !st.config.ClientRoots.Enabled {
+		return
+	}
+	first := notification.Method == methodInitialized
+	if !s.roots.startRequest(session.SessionID(), first) {
+		if first {
+			st.logger.Info("Client does not support roots; using the allowed directories",
+				"session", session.SessionID())
+		}
+		return
+	}
+	go s.fetchRoots(ctx, session, st.config.ClientRoots.Timeout)
+}
+
+// fetchRoots asks a client for its roots and stores the directories
+func (s *Server) fetchRoots(ctx context.Context, session server.ClientSession, timeout time.Duration) {
+	logger := s.log()
+	requester, ok := session.(transport.Requester)
+	if !ok {
+		err := fmt.Errorf("transport cannot send requests to the client")
+		logger.Error("Failed to request client roots", "session", session.SessionID(), "error", err)
+		s.roots.set(session.SessionID(), nil, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	raw, err := requester.Request(ctx, methodListRoots, nil)
+	if err != nil {
+		logger.Error("Failed to request client roots", "session", session.SessionID(), "error", err)
+		s.roots.set(session.SessionID(), nil, err)
+		return
+	}
+
+	var result mcp.ListRootsResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		err = fmt.Errorf("invalid roots/list result: %w", err)
+		logger.Error("Failed to request client roots", "session", session.SessionID(), "error", err)
+		s.roots.set(session.SessionID(), nil, err)
+		return
+	}
+
+	paths := make([]string, 0, len(result.Roots))
+	for i := 0; i < len(result.Roots) && i < maxClientRoots; i++ {
+		path, err := rootPath(result.Roots[i].URI)
+		if err != nil {
+			logger.Warn("Ignoring client root", "session", session.SessionID(), "uri", result.Roots[i].URI, "error", err)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	logger.Info("Client roots received", "session", session.SessionID(), "roots", paths)
+	s.roots.set(session.SessionID(), paths, nil)
+}
+
+// scopedTools returns the tool set a call runs with: with client roots or
+// tokens each session sees only its scope
+func (s *Server) scopedTools(ctx context.Context, st *snapshot) (*toolSet, error) {
+	if !st.config.ClientRoots.Enabled && !st.config.Auth.Enabled {
+		return st.toolSet, nil
+	}
+	return s.sessionTools(ctx, st)
+}
+
+// sessionTools returns the tool set for the session of a call: the
+// snapshot's own when neither its token nor its client limits it, otherwise
+// one limited to the token's directories and the client's roots
+func (s *Server) sessionTools(ctx context.Context, st *snapshot) (*toolSet, error) {
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return nil, fmt.Errorf("sessions are scoped but the call has no client session")
+	}
+
+	scope, err := st.tokenScope(session)
+	if err != nil {
+		return nil, err
+	}
+
+	var roots *sessionRoots
+	if st.config.ClientRoots.Enabled {
+		// Client roots may have been enabled by a reload after this session
+		// was initialized
+		timeout := st.config.ClientRoots.Timeout
+		if s.roots.startRequest(session.SessionID(), true) {
+			go s.fetchRoots(context.Background(), session, timeout)
+		}
+		if roots, err = s.roots.wait(ctx, session.SessionID(), timeout); err != nil {
+			return nil, err
+		}
+	}
+
+	if scope == nil && roots == nil {
+		return st.toolSet, nil
+	}
+	return st.view(session.SessionID(), scope, roots), nil
+}
+
+// rootPath converts a file:// root URI to a directory path
+func rootPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("invalid URI: %w", err)
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URI scheme %q", u.Scheme)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("remote file URIs are not supported")
+	}
+
+	path := u.Path
+	if runtime.GOOS == "windows" {
+		path = filepath.FromSlash(strings.TrimPrefix(path, "/"))
+	}
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("root path must be absolute")
+	}
+	return filepath.Clean(path), nil
+}
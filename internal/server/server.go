@@ -2,26 +2,89 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"filesystem/internal/handlers"
+	"filesystem/internal/transport"
+	"filesystem/pkg/approval"
+	"filesystem/pkg/audit"
 	"filesystem/pkg/config"
-	"filesystem/pkg/filesystem"
+	"filesystem/pkg/quota"
 	"filesystem/pkg/security"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
+const (
+	// stdioSessionID identifies the single client served over stdio
+	stdioSessionID = "stdio"
+
+	// httpReadHeaderTimeout bounds how long a client may take to send
+	// request headers
+	httpReadHeaderTimeout = 10 * time.Second
+
+	// httpShutdownTimeout bounds how long closing the HTTP listener waits
+	// for requests in progress
+	httpShutdownTimeout = 5 * time.Second
+)
+
 // Server represents the secure filesystem MCP server
 type Server struct {
-	mcpServer     *server.MCPServer
-	toolHandlers  *handlers.ToolHandlers
-	pathValidator *security.PathValidator
-	fsOps         *filesystem.Operations
-	logger        *slog.Logger
-	config        *config.Config
+	mcpServer *server.MCPServer
+
+	// state is the active configuration snapshot, swapped on reload
+	state atomic.Pointer[snapshot]
+
+	// reloadMu serializes reloads
+	reloadMu sync.Mutex
+
+	// logger is the logger the server was created with
+	logger *slog.Logger
+
+	// quotas counts usage per session and root across reloads
+	quotas *quota.Tracker
+
+	// grants holds directories added at runtime across reloads
+	grants *security.Grants
+
+	// roots tracks the roots advertised by each client session
+	roots *clientRoots
+
+	// approvals holds destructive calls for a human decision; nil when
+	// approval is disabled
+	approvals *approval.Queue
+	control   *approval.ControlServer
+
+	// tlsConfig serves the HTTP transports over TLS; nil serves plain HTTP
+	tlsConfig *tls.Config
+
+	// unix is the socket of the unix transport; nil for other transports
+	unix *transport.UnixListener
+
+	// calls tracks running tool calls so shutdown can drain them
+	calls *inflight
+
+	// resources holds the URIs of the allowed directories advertised as
+	// resources; resourcesMu guards it
+	resourcesMu sync.Mutex
+	resources   map[string]bool
+
+	// subscriptions tracks the resources clients watch for updates
+	subscriptions *subscriptions
+
+	// protocols tracks the protocol version each session negotiated
+	protocols *protocolVersions
+
+	// logLevels tracks the log level each session asked for
+	logLevels *logLevels
 }
 
 // New creates a new server instance with all necessary components
@@ -39,35 +102,118 @@ func New(cfg *config.Config, logger *slog.Logger) (*Server, error) {
 		"version", cfg.Server.Version,
 		"allowed_dirs_count", len(cfg.AllowedDirectories))
 
-	// Create security components
-	pathValidator := security.NewPathValidator(cfg.AllowedDirectories, logger)
-	fsOps := filesystem.NewOperations(pathValidator, logger)
+	// Open the audit log before serving so every call is recorded
+	auditLog, err := openAuditLog(cfg.Audit, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &Server{
+		logger:        logger,
+		quotas:        quota.NewTracker(cfg.Quotas),
+		roots:         newClientRoots(),
+		calls:         newInflight(),
+		resources:     make(map[string]bool),
+		subscriptions: newSubscriptions(),
+		protocols:     newProtocolVersions(),
+		logLevels:     newLogLevels(),
+	}
+	logger = srv.clientLogger(logger)
+	srv.logger = logger
+	srv.grants = security.NewGrants(srv.notifyRootsChanged, logger)
+
+	// Create security components and tool handlers
+	st, err := newSnapshot(cfg, logger, auditLog, srv.grants)
+	if err != nil {
+		if auditLog != nil {
+			auditLog.Close()
+		}
+		return nil, err
+	}
+
+	// Session budgets, subscriptions and log levels end with the session
+	hooks := &server.Hooks{}
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		srv.quotas.Forget(session.SessionID())
+		srv.subscriptions.forget(session.SessionID())
+		srv.logLevels.forget(session.SessionID())
+	})
+	srv.addRootsHooks(hooks)
+	srv.addProtocolHooks(hooks)
 
 	// Create MCP server with capabilities
 	mcpServer := server.NewMCPServer(
 		cfg.Server.Name,
 		cfg.Server.Version,
 		server.WithToolCapabilities(true),
+		server.WithResourceCapabilities(true, true),
+		server.WithLogging(),
+		server.WithHooks(hooks),
 	)
+	srv.mcpServer = mcpServer
+	srv.state.Store(st)
+	mcpServer.AddNotificationHandler(methodInitialized, srv.handleRootsNotification)
+	mcpServer.AddNotificationHandler(methodRootsListChanged, srv.handleRootsNotification)
 
-	// Create tool handlers
-	toolHandlers := handlers.NewToolHandlers(pathValidator, fsOps, logger)
+	// Certificates are read now, before any sandboxing hides them
+	if cfg.Server.Transport != config.TransportStdio && cfg.Server.HTTP.TLS.Enabled() {
+		tlsConfig, err := loadTLSConfig(cfg.Server.HTTP.TLS)
+		if err != nil {
+			if auditLog != nil {
+				auditLog.Close()
+			}
+			return nil, err
+		}
+		srv.tlsConfig = tlsConfig
+	}
 
-	// Register all tools with the MCP server
-	if err := toolHandlers.RegisterTools(mcpServer); err != nil {
-		logger.Error("Failed to register tools", "error", err)
-		return nil, fmt.Errorf("failed to register tools: %w", err)
+	// Create the control socket now so it exists before any sandboxing
+	if cfg.Approval.Enabled {
+		srv.approvals = approval.NewQueue(approval.Options{
+			Tools: cfg.Approval.Tools,
+			TTL:   cfg.Approval.TTL,
+		}, logger)
+		control, err := approval.Listen(cfg.Approval.Socket, srv.approvals, logger)
+		if err != nil {
+			if auditLog != nil {
+				auditLog.Close()
+			}
+			return nil, fmt.Errorf("failed to start approval queue: %w", err)
+		}
+		srv.control = control
 	}
 
-	srv := &Server{
-		mcpServer:     mcpServer,
-		toolHandlers:  toolHandlers,
-		pathValidator: pathValidator,
-		fsOps:         fsOps,
-		logger:        logger,
-		config:        cfg,
+	// The unix transport's socket is likewise created before sandboxing
+	if cfg.Server.Transport == config.TransportUnix {
+		unix, err := transport.ListenUnix(mcpServer, transport.UnixOptions{
+			Path:        cfg.Server.Unix.Path,
+			Mode:        cfg.Server.Unix.FileMode(),
+			AllowedUIDs: cfg.Server.Unix.AllowedUIDs,
+			Methods:     srv.methods(),
+		}, logger)
+		if err != nil {
+			if srv.control != nil {
+				srv.control.Close()
+			}
+			if auditLog != nil {
+				auditLog.Close()
+			}
+			return nil, fmt.Errorf("failed to start unix transport: %w", err)
+		}
+		srv.unix = unix
 	}
 
+	// Register all tools with the MCP server. Handlers are resolved through
+	// the active snapshot on every call so reloads take effect immediately.
+	mcpServer.AddTools(srv.dispatchers(st.tools)...)
+	logger.Info("All filesystem tools registered successfully", "count", len(st.tools))
+
+	// Files are read through the same snapshot and session scope as tools
+	mcpServer.AddResourceTemplate(mcp.NewResourceTemplate(fileTemplate, "Files",
+		mcp.WithTemplateDescription("Contents of a file, or the listing of a directory, within the allowed directories")),
+		srv.readResource)
+	srv.syncResources()
+
 	logger.Info("Server created successfully",
 		"tools_registered", true,
 		"transport", cfg.Server.Transport)
@@ -75,25 +221,118 @@ func New(cfg *config.Config, logger *slog.Logger) (*Server, error) {
 	return srv, nil
 }
 
-// Start begins serving MCP requests via stdio
+// Start begins serving MCP requests with the configured transport until
+// ctx is cancelled
 func (s *Server) Start(ctx context.Context) error {
 	// Input validation per Rule 7
 	if ctx == nil {
 		return fmt.Errorf("context is required")
 	}
 
-	s.logger.Info("Starting MCP server",
-		"allowed_directories", s.pathValidator.GetAllowedDirectories())
+	s.log().Info("Starting MCP server",
+		"allowed_directories", s.GetAllowedDirectories())
+
+	if s.control != nil {
+		go func() {
+			if err := s.control.Serve(ctx); err != nil {
+				s.log().Error("Approval control socket failed", "error", err)
+			}
+		}()
+	}
 
-	// Use ServeStdio to serve the MCP server over stdio
-	if err := server.ServeStdio(s.mcpServer); err != nil {
-		s.logger.Error("Failed to serve stdio", "error", err)
+	go s.watchResources(ctx)
+
+	// Transport settings are fixed at startup, so any snapshot has them
+	serverCfg := s.state.Load().config.Server
+	switch serverCfg.Transport {
+	case config.TransportSSE, config.TransportHTTP:
+		return s.serveHTTP(ctx, serverCfg)
+	case config.TransportUnix:
+		if err := s.unix.Serve(ctx); err != nil {
+			s.log().Error("Failed to serve unix socket", "error", err)
+			return fmt.Errorf("failed to serve unix socket: %w", err)
+		}
+		return nil
+	}
+
+	// Serve the single stdio client; the stream transport also carries
+	// server requests such as roots/list
+	stream := transport.NewStreamWithOptions(stdioSessionID, s.mcpServer, os.Stdout,
+		transport.StreamOptions{Methods: s.methods()}, s.log())
+	if err := stream.Serve(ctx, os.Stdin); err != nil && ctx.Err() == nil {
+		s.log().Error("Failed to serve stdio", "error", err)
 		return fmt.Errorf("failed to serve stdio: %w", err)
 	}
 
 	return nil
 }
 
+// serveHTTP serves clients over the sse or http transport until ctx is
+// cancelled, then ends their sessions and closes the listener
+func (s *Server) serveHTTP(ctx context.Context, cfg config.ServerConfig) error {
+	handler := s.httpHandler(cfg)
+
+	listener, err := net.Listen("tcp", cfg.HTTP.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", cfg.HTTP.Address, err)
+	}
+	if s.tlsConfig != nil {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+
+	// Anyone who can reach the port gets the allowed directories
+	if !loopbackAddress(cfg.HTTP.Address) && !s.state.Load().config.Auth.Enabled && cfg.HTTP.TLS.ClientCAFile == "" {
+		s.log().Warn("HTTP transport is reachable beyond this machine without authentication",
+			"address", cfg.HTTP.Address)
+	}
+	httpServer := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: httpReadHeaderTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- httpServer.Serve(listener) }()
+	s.log().Info("Serving MCP over HTTP",
+		"transport", cfg.Transport,
+		"tls", s.tlsConfig != nil,
+		"address", listener.Addr().String(),
+		"endpoints", handler.Endpoints())
+
+	select {
+	case err := <-serveErr:
+		handler.Close()
+		s.log().Error("Failed to serve HTTP", "error", err)
+		return fmt.Errorf("failed to serve http: %w", err)
+	case <-ctx.Done():
+	}
+
+	// Ending the sessions first returns their event streams, which
+	// Shutdown would otherwise wait on
+	handler.Close()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down http server: %w", err)
+	}
+	return nil
+}
+
+// httpHandler creates the handler of the configured HTTP transport
+func (s *Server) httpHandler(cfg config.ServerConfig) *transport.HTTPHandler {
+	options := transport.HTTPOptions{
+		BasePath:       cfg.HTTP.BasePath,
+		KeepAlive:      cfg.HTTP.KeepAlive,
+		AllowedOrigins: cfg.HTTP.AllowedOrigins,
+		Authenticate:   s.authenticate,
+		Methods:        s.methods(),
+	}
+	if cfg.Transport == config.TransportSSE {
+		return transport.NewSSEHandler(s.mcpServer, options, s.logger)
+	}
+	return transport.NewStreamableHandler(s.mcpServer, options, s.logger)
+}
+
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown(ctx context.Context) error {
 	// Input validation per Rule 7
@@ -101,13 +340,46 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		return fmt.Errorf("context is required")
 	}
 
-	s.logger.Info("Shutting down MCP server")
+	logger := s.log()
+	logger.Info("Shutting down MCP server")
+
+	// New calls are refused from here on, while the transports keep
+	// running so the results of calls in flight still reach their clients
+	if s.calls != nil {
+		timeout := config.DefaultShutdownTimeout
+		if st := s.state.Load(); st != nil {
+			timeout = st.config.Server.ShutdownTimeout
+		}
+		if running := s.calls.count(); running > 0 {
+			logger.Info("Waiting for tool calls to finish", "running", running, "timeout", timeout.String())
+		}
+		drainCtx, cancel := context.WithTimeout(ctx, timeout)
+		cancelled := s.calls.drain(drainCtx)
+		cancel()
+		if cancelled > 0 {
+			logger.Warn("Cancelled tool calls still running at the shutdown deadline", "cancelled", cancelled)
+		}
+	}
+
+	if s.control != nil {
+		if err := s.control.Close(); err != nil {
+			logger.Error("Failed to close approval socket", "error", err)
+		}
+	}
+	if s.unix != nil {
+		if err := s.unix.Close(); err != nil {
+			logger.Error("Failed to close unix socket", "error", err)
+		}
+	}
 
-	// Note: The MCP-Go library doesn't appear to have explicit shutdown methods
-	// so we just log the shutdown. The transport connection will be closed
-	// when the context is cancelled.
+	if st := s.state.Load(); st != nil && st.auditLog != nil {
+		if err := st.auditLog.Close(); err != nil {
+			logger.Error("Failed to close audit log", "error", err)
+			return fmt.Errorf("failed to close audit log: %w", err)
+		}
+	}
 
-	s.logger.Info("MCP server shutdown complete")
+	logger.Info("MCP server shutdown complete")
 	return nil
 }
 
@@ -119,10 +391,62 @@ func (s *Server) GetCapabilities() mcp.ServerCapabilities {
 		}{
 			ListChanged: true,
 		},
+		Resources: &struct {
+			Subscribe   bool `json:"subscribe,omitempty"`
+			ListChanged bool `json:"listChanged,omitempty"`
+		}{
+			Subscribe:   true,
+			ListChanged: true,
+		},
+		Logging: &struct{}{},
 	}
 }
 
 // GetAllowedDirectories returns the allowed directories for this server
 func (s *Server) GetAllowedDirectories() []string {
-	return s.pathValidator.GetAllowedDirectories()
+	st := s.state.Load()
+	if st == nil {
+		return nil
+	}
+	return st.pathValidator.GetAllowedDirectories()
+}
+
+// notifyRootsChanged tells clients to list tools again after a directory
+// grant changes, so they can call list_allowed_directories, and updates
+// the directory resources
+func (s *Server) notifyRootsChanged() {
+	if s.mcpServer == nil {
+		return
+	}
+	s.mcpServer.SendNotificationToAllClients(mcp.MethodNotificationToolsListChanged, nil)
+	s.syncResources()
+}
+
+// log returns the logger of the active snapshot
+func (s *Server) log() *slog.Logger {
+	if st := s.state.Load(); st != nil {
+		return st.logger
+	}
+	return s.logger
+}
+
+// openAuditLog opens the configured audit log, returning nil when auditing
+// is disabled
+func openAuditLog(cfg config.AuditConfig, logger *slog.Logger) (*audit.Logger, error) {
+	if cfg.Path == "" {
+		return nil, nil
+	}
+
+	auditLog, err := audit.Open(audit.Options{
+		Path:       cfg.Path,
+		MaxSize:    int64(cfg.MaxSizeMB) * 1024 * 1024,
+		MaxBackups: cfg.MaxBackups,
+	})
+	if err != nil {
+		logger.Error("Failed to open audit log", "path", cfg.Path, "error", err)
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	logger.Info("Audit logging enabled", "path", cfg.Path)
+	return auditLog, nil
 }
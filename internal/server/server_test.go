@@ -1,13 +1,27 @@
 package server
 
 import (
+    "bufio"
     "bytes"
     "context"
+    "encoding/json"
     "io"
     "log/slog"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
     "testing"
+    "time"
 
+    "filesystem/internal/transport"
+    "filesystem/pkg/audit"
+    "filesystem/pkg/auth"
     "filesystem/pkg/config"
+    "filesystem/pkg/security"
+
+    "github.com/mark3labs/mcp-go/mcp"
 )
 
 func TestNewNilParameters(t *testing.T) {
@@ -44,3 +58,1056 @@ func TestShutdownLogsAndReturnsNil(t *testing.T) {
     }
 }
 
+
+func callListAllowed(t *testing.T, srv *Server) string {
+    t.Helper()
+    var req mcp.CallToolRequest
+    req.Params.Name = "list_allowed_directories"
+    res, err := srv.dispatch("list_allowed_directories")(context.Background(), req)
+    if err != nil || res.IsError {
+        t.Fatalf("list_allowed_directories failed: %v %+v", err, res)
+    }
+    return res.Content[0].(mcp.TextContent).Text
+}
+
+func TestReloadSwapsSnapshotAfterInFlightCalls(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    dirA, dirB := t.TempDir(), t.TempDir()
+    auditA := filepath.Join(t.TempDir(), "a.jsonl")
+    auditB := filepath.Join(t.TempDir(), "b.jsonl")
+
+    cfg := config.Default()
+    cfg.AllowedDirectories = config.NewAllowedDirectories([]string{dirA})
+    cfg.Audit.Path = auditA
+    srv, err := New(cfg, logger)
+    if err != nil {
+        t.Fatalf("new: %v", err)
+    }
+    defer srv.Shutdown(context.Background())
+
+    if out := callListAllowed(t, srv); !strings.Contains(out, dirA) {
+        t.Fatalf("expected %s in %q", dirA, out)
+    }
+
+    // Pin the current snapshot as an in-flight call would
+    old := srv.acquireSnapshot()
+
+    next := config.Default()
+    next.AllowedDirectories = config.NewAllowedDirectories([]string{dirB})
+    next.Audit.Path = auditB
+    if err := srv.Reload(next, logger); err != nil {
+        t.Fatalf("reload: %v", err)
+    }
+
+    if out := callListAllowed(t, srv); !strings.Contains(out, dirB) || strings.Contains(out, dirA) {
+        t.Fatalf("expected only %s in %q", dirB, out)
+    }
+    if dirs := old.pathValidator.GetAllowedDirectories(); len(dirs) != 1 || dirs[0] != dirA {
+        t.Fatalf("in-flight snapshot changed: %v", dirs)
+    }
+
+    // The previous audit log stays open until the pinned call finishes
+    if err := old.auditLog.Write(audit.Record{Tool: "read_file"}); err != nil {
+        t.Fatalf("old audit log closed early: %v", err)
+    }
+    old.release()
+    if err := old.auditLog.Write(audit.Record{Tool: "read_file"}); err == nil {
+        t.Fatalf("expected old audit log to be closed after release")
+    }
+}
+
+func TestReloadRejectsNilConfig(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    srv, err := New(config.Default(), logger)
+    if err != nil {
+        t.Fatalf("new: %v", err)
+    }
+    if err := srv.Reload(nil, logger); err == nil {
+        t.Fatalf("expected error for nil config")
+    }
+}
+
+func TestApprovalHoldsWriteUntilApproved(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    dir := t.TempDir()
+    cfg := config.Default()
+    cfg.AllowedDirectories = config.NewAllowedDirectories([]string{dir})
+    cfg.Approval.Enabled = true
+    cfg.Approval.Socket = filepath.Join(t.TempDir(), "approval.sock")
+    srv, err := New(cfg, logger)
+    if err != nil {
+        t.Fatalf("new: %v", err)
+    }
+    defer srv.Shutdown(context.Background())
+
+    target := filepath.Join(dir, "held.txt")
+    var req mcp.CallToolRequest
+    req.Params.Name = "write_file"
+    req.Params.Arguments = map[string]interface{}{"path": target, "content": "hello"}
+    res, err := srv.dispatch("write_file")(context.Background(), req)
+    if err != nil || res.IsError {
+        t.Fatalf("write_file failed: %v %+v", err, res)
+    }
+    if _, err := os.Stat(target); !os.IsNotExist(err) {
+        t.Fatalf("file written before approval: %v", err)
+    }
+
+    ops := srv.approvals.List()
+    if len(ops) != 1 {
+        t.Fatalf("expected one pending operation, got %+v", ops)
+    }
+    if res, err := srv.approvals.Approve(context.Background(), ops[0].ID); err != nil || res.IsError {
+        t.Fatalf("approve failed: %v %+v", err, res)
+    }
+    if data, err := os.ReadFile(target); err != nil || string(data) != "hello" {
+        t.Fatalf("approved write missing: %v %q", err, data)
+    }
+}
+
+func TestApprovalQueuesOnlyCallsThatCouldRun(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    dir := t.TempDir()
+    cfg := config.Default()
+    cfg.AllowedDirectories = config.NewAllowedDirectories([]string{dir})
+    cfg.DenyPatterns = []string{"**/.env"}
+    cfg.Approval.Enabled = true
+    cfg.Approval.Socket = filepath.Join(t.TempDir(), "approval.sock")
+    cfg.Quotas.Session.OperationsPerMinute = 1
+    srv, err := New(cfg, logger)
+    if err != nil {
+        t.Fatalf("new: %v", err)
+    }
+    defer srv.Shutdown(context.Background())
+
+    write := func(path string) *mcp.CallToolResult {
+        var req mcp.CallToolRequest
+        req.Params.Name = "write_file"
+        req.Params.Arguments = map[string]interface{}{"path": path, "content": "hello"}
+        res, err := srv.dispatch("write_file")(context.Background(), req)
+        if err != nil {
+            t.Fatalf("write_file: %v", err)
+        }
+        return res
+    }
+
+    for _, path := range []string{filepath.Join(t.TempDir(), "outside.txt"), filepath.Join(dir, ".env")} {
+        if res := write(path); !res.IsError {
+            t.Fatalf("expected %s to be refused, got %+v", path, res)
+        }
+    }
+    if ops := srv.approvals.List(); len(ops) != 0 {
+        t.Fatalf("refused calls were queued: %+v", ops)
+    }
+
+    // Submissions count against the rate limit
+    if res := write(filepath.Join(dir, "a.txt")); res.IsError {
+        t.Fatalf("write_file failed: %+v", res)
+    }
+    if res := write(filepath.Join(dir, "b.txt")); !res.IsError {
+        t.Fatalf("expected the rate limit to refuse the submission, got %+v", res)
+    }
+    if ops := srv.approvals.List(); len(ops) != 1 {
+        t.Fatalf("expected one pending operation, got %+v", ops)
+    }
+}
+
+func TestAdminGrantsFollowReloads(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    dir, parent := t.TempDir(), t.TempDir()
+    granted := filepath.Join(parent, "project")
+    if err := os.Mkdir(granted, 0755); err != nil {
+        t.Fatalf("mkdir: %v", err)
+    }
+
+    cfg := config.Default()
+    cfg.AllowedDirectories = config.NewAllowedDirectories([]string{dir})
+    cfg.Admin = config.AdminConfig{Enabled: true, Parents: config.NewAllowedDirectories([]string{parent})}
+    srv, err := New(cfg, logger)
+    if err != nil {
+        t.Fatalf("new: %v", err)
+    }
+    defer srv.Shutdown(context.Background())
+
+    var req mcp.CallToolRequest
+    req.Params.Name = "add_allowed_directory"
+    req.Params.Arguments = map[string]interface{}{"path": granted, "expiresIn": "10m"}
+    res, err := srv.dispatch("add_allowed_directory")(context.Background(), req)
+    if err != nil || res.IsError {
+        t.Fatalf("add_allowed_directory failed: %v %+v", err, res)
+    }
+    if out := callListAllowed(t, srv); !strings.Contains(out, granted+" (granted until ") {
+        t.Fatalf("expected %s to be listed as granted in %q", granted, out)
+    }
+
+    // The grant outlives a reload that keeps its parent
+    if err := srv.Reload(cfg, logger); err != nil {
+        t.Fatalf("reload: %v", err)
+    }
+    if out := callListAllowed(t, srv); !strings.Contains(out, granted) {
+        t.Fatalf("grant lost on reload: %q", out)
+    }
+
+    next := config.Default()
+    next.AllowedDirectories = config.NewAllowedDirectories([]string{dir})
+    if err := srv.Reload(next, logger); err != nil {
+        t.Fatalf("reload: %v", err)
+    }
+    if out := callListAllowed(t, srv); strings.Contains(out, granted) {
+        t.Fatalf("grant should be revoked when admin tools are disabled: %q", out)
+    }
+    if _, ok := srv.state.Load().handlers["add_allowed_directory"]; ok {
+        t.Fatalf("admin tools should be removed when disabled")
+    }
+}
+
+func TestClientRootsLimitSession(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    base, other := t.TempDir(), t.TempDir()
+    project := filepath.Join(base, "project")
+    if err := os.Mkdir(project, 0755); err != nil {
+        t.Fatalf("mkdir: %v", err)
+    }
+
+    cfg := config.Default()
+    cfg.AllowedDirectories = config.NewAllowedDirectories([]string{base})
+    cfg.ClientRoots.Enabled = true
+    srv, err := New(cfg, logger)
+    if err != nil {
+        t.Fatalf("new: %v", err)
+    }
+    defer srv.Shutdown(context.Background())
+
+    inR, inW := io.Pipe()
+    outR, outW := io.Pipe()
+    defer inW.Close()
+    stream := transport.NewStream("client", srv.mcpServer, outW, logger)
+    go stream.Serve(context.Background(), inR)
+
+    out := bufio.NewReader(outR)
+    send := func(msg string) {
+        if _, err := io.WriteString(inW, msg+"\n"); err != nil {
+            t.Fatalf("send: %v", err)
+        }
+    }
+    next := func() map[string]interface{} {
+        line, err := out.ReadBytes('\n')
+        if err != nil {
+            t.Fatalf("read: %v", err)
+        }
+        var msg map[string]interface{}
+        if err := json.Unmarshal(line, &msg); err != nil {
+            t.Fatalf("decode %q: %v", line, err)
+        }
+        return msg
+    }
+
+    send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{"roots":{"listChanged":true}},"clientInfo":{"name":"test","version":"1.0.0"}}}`)
+    if res := next(); res["id"] != float64(1) {
+        t.Fatalf("expected initialize response, got %v", res)
+    }
+    send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+    req := next()
+    if req["method"] != "roots/list" {
+        t.Fatalf("expected roots/list request, got %v", req)
+    }
+    id, _ := json.Marshal(req["id"])
+    send(`{"jsonrpc":"2.0","id":` + string(id) + `,"result":{"roots":[{"uri":"file://` + filepath.ToSlash(project) + `"},{"uri":"file://` + filepath.ToSlash(other) + `"}]}}`)
+
+    send(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"list_allowed_directories","arguments":{}}}`)
+    res := next()
+    data, _ := json.Marshal(res["result"])
+    if !strings.Contains(string(data), `Allowed directories:\n`+project+`"`) {
+        t.Fatalf("expected only %s to be allowed, got %s", project, data)
+    }
+
+    send(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"write_file","arguments":{"path":"` + filepath.ToSlash(filepath.Join(base, "outside.txt")) + `","content":"x"}}}`)
+    res = next()
+    data, _ = json.Marshal(res["result"])
+    if !strings.Contains(string(data), "outside allowed directories") {
+        t.Fatalf("expected a write outside the client roots to be refused, got %s", data)
+    }
+}
+
+func TestClientRootsLimitGrants(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    base, parent := t.TempDir(), t.TempDir()
+    granted := filepath.Join(parent, "project")
+    shared := filepath.Join(granted, "shared")
+    if err := os.MkdirAll(shared, 0755); err != nil {
+        t.Fatalf("mkdir: %v", err)
+    }
+
+    cfg := config.Default()
+    cfg.AllowedDirectories = config.NewAllowedDirectories([]string{base})
+    cfg.ClientRoots.Enabled = true
+    cfg.Admin = config.AdminConfig{Enabled: true, Parents: config.NewAllowedDirectories([]string{parent})}
+    srv, err := New(cfg, logger)
+    if err != nil {
+        t.Fatalf("new: %v", err)
+    }
+    defer srv.Shutdown(context.Background())
+
+    // Calls need a session once client roots are enabled, so grant directly
+    if _, err := srv.state.Load().pathValidator.AddRoot(security.Root{Path: granted, Mode: security.ModeReadWrite}); err != nil {
+        t.Fatalf("add root: %v", err)
+    }
+
+    inR, inW := io.Pipe()
+    outR, outW := io.Pipe()
+    defer inW.Close()
+    stream := transport.NewStream("client", srv.mcpServer, outW, logger)
+    go stream.Serve(context.Background(), inR)
+
+    out := bufio.NewReader(outR)
+    send := func(msg string) {
+        if _, err := io.WriteString(inW, msg+"\n"); err != nil {
+            t.Fatalf("send: %v", err)
+        }
+    }
+    next := func() map[string]interface{} {
+        for {
+            line, err := out.ReadBytes('\n')
+            if err != nil {
+                t.Fatalf("read: %v", err)
+            }
+            var msg map[string]interface{}
+            if err := json.Unmarshal(line, &msg); err != nil {
+                t.Fatalf("decode %q: %v", line, err)
+            }
+            // Denials are also logged to the client
+            if msg["method"] != methodLogMessage {
+                return msg
+            }
+        }
+    }
+
+    send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{"roots":{"listChanged":true}},"clientInfo":{"name":"test","version":"1.0.0"}}}`)
+    if res := next(); res["id"] != float64(1) {
+        t.Fatalf("expected initialize response, got %v", res)
+    }
+    send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+    req := next()
+    if req["method"] != "roots/list" {
+        t.Fatalf("expected roots/list request, got %v", req)
+    }
+    id, _ := json.Marshal(req["id"])
+    send(`{"jsonrpc":"2.0","id":` + string(id) + `,"result":{"roots":[{"uri":"file://` + filepath.ToSlash(base) + `"},{"uri":"file://` + filepath.ToSlash(shared) + `"}]}}`)
+
+    // The grant outside the client roots is refused, the part inside allowed
+    send(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"write_file","arguments":{"path":"` + filepath.ToSlash(filepath.Join(granted, "outside.txt")) + `","content":"x"}}}`)
+    res := next()
+    data, _ := json.Marshal(res["result"])
+    if !strings.Contains(string(data), "outside allowed directories") {
+        t.Fatalf("expected a write to a grant outside the client roots to be refused, got %s", data)
+    }
+    if _, err := os.Stat(filepath.Join(granted, "outside.txt")); !os.IsNotExist(err) {
+        t.Fatalf("refused write reached the disk: %v", err)
+    }
+
+    send(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"write_file","arguments":{"path":"` + filepath.ToSlash(filepath.Join(shared, "inside.txt")) + `","content":"x"}}}`)
+    res = next()
+    if result, _ := res["result"].(map[string]interface{}); result == nil || result["isError"] == true {
+        t.Fatalf("expected a write to the granted client root to succeed, got %v", res)
+    }
+}
+
+func TestTokensScopeHTTPSessions(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    base := t.TempDir()
+    docs := filepath.Join(base, "docs")
+    if err := os.Mkdir(docs, 0755); err != nil {
+        t.Fatalf("mkdir: %v", err)
+    }
+
+    cfg := config.Default()
+    cfg.AllowedDirectories = config.NewAllowedDirectories([]string{base})
+    cfg.Server.Transport = config.TransportHTTP
+    cfg.Auth = config.AuthConfig{
+        Enabled: true,
+        Tokens: []config.TokenConfig{
+            {Name: "reader", Hash: auth.Hash("reader-token-0123456789"), AllowedDirectories: []config.AllowedDirectory{
+                {Path: docs, Mode: security.ModeReadOnly},
+            }},
+            {Name: "owner", Hash: auth.Hash("owner-token-0123456789")},
+        },
+    }
+    srv, err := New(cfg, logger)
+    if err != nil {
+        t.Fatalf("new: %v", err)
+    }
+    defer srv.Shutdown(context.Background())
+    handler := srv.httpHandler(cfg.Server)
+    defer handler.Close()
+    ts := httptest.NewServer(handler)
+    defer ts.Close()
+
+    post := func(token, session, body string) (*http.Response, string) {
+        req, _ := http.NewRequest(http.MethodPost, ts.URL+"/mcp", strings.NewReader(body))
+        if token != "" {
+            req.Header.Set("Authorization", "Bearer "+token)
+        }
+        if session != "" {
+            req.Header.Set(transport.HeaderSessionID, session)
+        }
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatalf("post: %v", err)
+        }
+        defer resp.Body.Close()
+        data, _ := io.ReadAll(resp.Body)
+        return resp, string(data)
+    }
+    initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`
+    call := func(name, args string) string {
+        return `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"` + name + `","arguments":` + args + `}}`
+    }
+
+    if resp, _ := post("", "", initialize); resp.StatusCode != http.StatusUnauthorized {
+        t.Fatalf("expected a request without a token to be refused, got %d", resp.StatusCode)
+    }
+    if resp, _ := post("wrong-token-0123456789", "", initialize); resp.StatusCode != http.StatusUnauthorized {
+        t.Fatalf("expected an unknown token to be refused, got %d", resp.StatusCode)
+    }
+
+    resp, _ := post("reader-token-0123456789", "", initialize)
+    session := resp.Header.Get(transport.HeaderSessionID)
+    if resp.StatusCode != http.StatusOK || session == "" {
+        t.Fatalf("expected the reader to start a session, got %d", resp.StatusCode)
+    }
+    if _, body := post("reader-token-0123456789", session, call("list_allowed_directories", `{}`)); !strings.Contains(body, `Allowed directories:\n`+docs+` (read-only)`) {
+        t.Fatalf("expected the reader to see only %s read-only, got %s", docs, body)
+    }
+    if _, body := post("reader-token-0123456789", session, call("write_file", `{"path":"`+filepath.ToSlash(filepath.Join(docs, "a.txt"))+`","content":"x"}`)); !strings.Contains(body, `"isError":true`) {
+        t.Fatalf("expected the reader's write to be refused, got %s", body)
+    }
+
+    // A session only answers to the token that opened it
+    if resp, _ := post("owner-token-0123456789", session, call("list_allowed_directories", `{}`)); resp.StatusCode != http.StatusNotFound {
+        t.Fatalf("expected another token's session to be unknown, got %d", resp.StatusCode)
+    }
+
+    resp, _ = post("owner-token-0123456789", "", initialize)
+    owner := resp.Header.Get(transport.HeaderSessionID)
+    if _, body := post("owner-token-0123456789", owner, call("write_file", `{"path":"`+filepath.ToSlash(filepath.Join(docs, "a.txt"))+`","content":"x"}`)); strings.Contains(body, `"isError":true`) {
+        t.Fatalf("expected the owner's write to succeed, got %s", body)
+    }
+}
+
+func TestShutdownDrainsToolCalls(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    cfg := config.Default()
+    cfg.AllowedDirectories = config.NewAllowedDirectories([]string{t.TempDir()})
+    cfg.Server.ShutdownTimeout = 50 * time.Millisecond
+    srv, err := New(cfg, logger)
+    if err != nil {
+        t.Fatalf("new: %v", err)
+    }
+
+    // One call finishes during the drain, the other runs past the deadline
+    _, finish, ok := srv.calls.begin(context.Background())
+    stuck, endStuck, ok2 := srv.calls.begin(context.Background())
+    if !ok || !ok2 {
+        t.Fatalf("calls should be accepted before shutdown")
+    }
+    go func() {
+        <-stuck.Done()
+        endStuck()
+    }()
+
+    done := make(chan error, 1)
+    go func() { done <- srv.Shutdown(context.Background()) }()
+    for !srv.calls.isDraining() {
+        time.Sleep(time.Millisecond)
+    }
+
+    var req mcp.CallToolRequest
+    req.Params.Name = "list_allowed_directories"
+    res, err := srv.dispatch("list_allowed_directories")(context.Background(), req)
+    if err != nil || !res.IsError || !strings.Contains(res.Content[0].(mcp.TextContent).Text, "shutting down") {
+        t.Fatalf("new calls should be refused while draining: %v %+v", err, res)
+    }
+    finish()
+
+    select {
+    case err := <-done:
+        if err != nil {
+            t.Fatalf("shutdown: %v", err)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatalf("shutdown did not return")
+    }
+    if stuck.Err() == nil {
+        t.Fatalf("a call running past the deadline should be cancelled")
+    }
+}
+
+func TestToolTimeouts(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    dir := t.TempDir()
+    cfg := config.Default()
+    cfg.AllowedDirectories = config.NewAllowedDirectories([]string{dir})
+    cfg.Timeouts.Tools = map[string]time.Duration{"search_files": time.Nanosecond}
+    srv, err := New(cfg, logger)
+    if err != nil {
+        t.Fatalf("new: %v", err)
+    }
+    defer srv.Shutdown(context.Background())
+
+    var req mcp.CallToolRequest
+    req.Params.Name = "search_files"
+    req.Params.Arguments = map[string]interface{}{"path": dir, "pattern": "x"}
+    res, err := srv.dispatch("search_files")(context.Background(), req)
+    if err != nil || !res.IsError || !strings.Contains(res.Content[0].(mcp.TextContent).Text, "search_files timed out after 1ns") {
+        t.Fatalf("search_files should time out: %v %+v", err, res)
+    }
+
+    // Other tools keep the default timeout
+    if out := callListAllowed(t, srv); !strings.Contains(out, dir) {
+        t.Fatalf("expected %s in %q", dir, out)
+    }
+}
+
+func TestSearchFilesSendsProgress(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    dir := t.TempDir()
+    cfg := config.Default()
+    cfg.AllowedDirectories = config.NewAllowedDirectories([]string{dir})
+    srv, err := New(cfg, logger)
+    if err != nil {
+        t.Fatalf("new: %v", err)
+    }
+    defer srv.Shutdown(context.Background())
+
+    inR, inW := io.Pipe()
+    outR, outW := io.Pipe()
+    defer inW.Close()
+    stream := transport.NewStream("progress", srv.mcpServer, outW, logger)
+    go stream.Serve(context.Background(), inR)
+    out := bufio.NewReader(outR)
+    send := func(msg string) {
+        if _, err := io.WriteString(inW, msg+"\n"); err != nil {
+            t.Fatalf("send: %v", err)
+        }
+    }
+
+    send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`)
+    if _, err := out.ReadBytes('\n'); err != nil {
+        t.Fatalf("read initialize response: %v", err)
+    }
+    send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+    send(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"search_files","arguments":{"path":"` + dir + `","pattern":"x"},"_meta":{"progressToken":"walk"}}}`)
+
+    // The first report is sent at once; it is queued, so it may arrive
+    // after the result
+    var progress map[string]any
+    for i := 0; i < 2 && progress == nil; i++ {
+        line, err := out.ReadBytes('\n')
+        if err != nil {
+            t.Fatalf("read: %v", err)
+        }
+        var msg struct {
+            Method string         `json:"method"`
+            Params map[string]any `json:"params"`
+        }
+        if err := json.Unmarshal(line, &msg); err != nil {
+            t.Fatalf("decode %s: %v", line, err)
+        }
+        if msg.Method == "notifications/progress" {
+            progress = msg.Params
+        }
+    }
+    if progress == nil || progress["progressToken"] != "walk" {
+        t.Fatalf("expected a progress notification, got %v", progress)
+    }
+    if !strings.Contains(progress["message"].(string), "scanned 1 entries") {
+        t.Fatalf("unexpected progress message: %v", progress["message"])
+    }
+}
+
+func TestResourcesReadAndSubscribe(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    dir := t.TempDir()
+    file := filepath.Join(dir, "sub dir", "notes.md")
+    if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(file, []byte("# notes\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    outside := filepath.Join(t.TempDir(), "secret.txt")
+    if err := os.WriteFile(outside, []byte("secret"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    cfg := config.Default()
+    cfg.AllowedDirectories = config.NewAllowedDirectories([]string{dir})
+    srv, err := New(cfg, logger)
+    if err != nil {
+        t.Fatalf("new: %v", err)
+    }
+    defer srv.Shutdown(context.Background())
+
+    inR, inW := io.Pipe()
+    outR, outW := io.Pipe()
+    defer inW.Close()
+    stream := transport.NewStreamWithOptions("resources", srv.mcpServer, outW,
+        transport.StreamOptions{Methods: srv.methods()}, logger)
+    go stream.Serve(context.Background(), inR)
+    out := bufio.NewReader(outR)
+    type response struct {
+        ID     int             `json:"id"`
+        Method string          `json:"method"`
+        Params map[string]any  `json:"params"`
+        Result json.RawMessage `json:"result"`
+        Error  *struct {
+            Message string `json:"message"`
+        } `json:"error"`
+    }
+    call := func(msg string) response {
+        if _, err := io.WriteString(inW, msg+"\n"); err != nil {
+            t.Fatalf("send: %v", err)
+        }
+        line, err := out.ReadBytes('\n')
+        if err != nil {
+            t.Fatalf("read: %v", err)
+        }
+        var r response
+        if err := json.Unmarshal(line, &r); err != nil {
+            t.Fatalf("decode %s: %v", line, err)
+        }
+        return r
+    }
+
+    call(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`)
+    if _, err := io.WriteString(inW, `{"jsonrpc":"2.0","method":"notifications/initialized"}`+"\n"); err != nil {
+        t.Fatalf("send: %v", err)
+    }
+
+    // Denials below are logged as warnings; keep them off the stream
+    if level := call(`{"jsonrpc":"2.0","id":10,"method":"logging/setLevel","params":{"level":"error"}}`); level.Error != nil {
+        t.Fatalf("set level: %s", level.Error.Message)
+    }
+
+    realDir, _ := filepath.EvalSymlinks(dir)
+    list := call(`{"jsonrpc":"2.0","id":2,"method":"resources/list"}`)
+    if !strings.Contains(string(list.Result), `"uri":"`+fileURI(realDir)+`"`) {
+        t.Fatalf("expected the allowed directory as a resource, got %s", list.Result)
+    }
+
+    uri := fileURI(file)
+    read := call(`{"jsonrpc":"2.0","id":3,"method":"resources/read","params":{"uri":"` + uri + `"}}`)
+    if read.Error != nil {
+        t.Fatalf("read %s: %s", uri, read.Error.Message)
+    }
+    var contents struct {
+        Contents []struct {
+            MIMEType string `json:"mimeType"`
+            Text     string `json:"text"`
+        } `json:"contents"`
+    }
+    if err := json.Unmarshal(read.Result, &contents); err != nil || len(contents.Contents) != 1 {
+        t.Fatalf("unexpected read result %s: %v", read.Result, err)
+    }
+    if contents.Contents[0].Text != "# notes\n" || !strings.HasPrefix(contents.Contents[0].MIMEType, "text/markdown") {
+        t.Fatalf("unexpected contents: %+v", contents.Contents[0])
+    }
+
+    denied := call(`{"jsonrpc":"2.0","id":4,"method":"resources/read","params":{"uri":"` + fileURI(outside) + `"}}`)
+    if denied.Error == nil {
+        t.Fatalf("expected reading outside the allowed directories to fail, got %s", denied.Result)
+    }
+    denied = call(`{"jsonrpc":"2.0","id":5,"method":"resources/subscribe","params":{"uri":"` + fileURI(outside) + `"}}`)
+    if denied.Error == nil {
+        t.Fatalf("expected subscribing outside the allowed directories to fail")
+    }
+
+    sub := call(`{"jsonrpc":"2.0","id":6,"method":"resources/subscribe","params":{"uri":"` + uri + `"}}`)
+    if sub.Error != nil {
+        t.Fatalf("subscribe: %s", sub.Error.Message)
+    }
+    if err := os.WriteFile(file, []byte("# notes\nmore\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    srv.pollResources()
+    line, err := out.ReadBytes('\n')
+    if err != nil {
+        t.Fatalf("read: %v", err)
+    }
+    var update response
+    if err := json.Unmarshal(line, &update); err != nil {
+        t.Fatalf("decode %s: %v", line, err)
+    }
+    if update.Method != "notifications/resources/updated" || update.Params["uri"] != uri {
+        t.Fatalf("expected an update for %s, got %s", uri, line)
+    }
+
+    unsub := call(`{"jsonrpc":"2.0","id":7,"method":"resources/unsubscribe","params":{"uri":"` + uri + `"}}`)
+    if unsub.Error != nil {
+        t.Fatalf("unsubscribe: %s", unsub.Error.Message)
+    }
+    if err := os.Remove(file); err != nil {
+        t.Fatal(err)
+    }
+    if updates := srv.subscriptions.poll(func(string, string) bool { return true }); len(updates) != 0 {
+        t.Fatalf("expected no subscribers after unsubscribe, got %v", updates)
+    }
+}
+
+func TestResourcesFollowSessionScope(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    dirA, dirB := t.TempDir(), t.TempDir()
+    fileA := filepath.Join(dirA, "a.txt")
+    if err := os.WriteFile(fileA, []byte("a"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    cfg := config.Default()
+    cfg.AllowedDirectories = config.NewAllowedDirectories([]string{dirA, dirB})
+    cfg.ClientRoots.Enabled = true
+    srv, err := New(cfg, logger)
+    if err != nil {
+        t.Fatalf("new: %v", err)
+    }
+    defer srv.Shutdown(context.Background())
+
+    inR, inW := io.Pipe()
+    outR, outW := io.Pipe()
+    defer inW.Close()
+    stream := transport.NewStreamWithOptions("scoped", srv.mcpServer, outW,
+        transport.StreamOptions{Methods: srv.methods()}, logger)
+    go stream.Serve(context.Background(), inR)
+    out := bufio.NewReader(outR)
+    send := func(msg string) {
+        if _, err := io.WriteString(inW, msg+"\n"); err != nil {
+            t.Fatalf("send: %v", err)
+        }
+    }
+    next := func() map[string]interface{} {
+        line, err := out.ReadBytes('\n')
+        if err != nil {
+            t.Fatalf("read: %v", err)
+        }
+        var msg map[string]interface{}
+        if err := json.Unmarshal(line, &msg); err != nil {
+            t.Fatalf("decode %q: %v", line, err)
+        }
+        return msg
+    }
+    // answerRoots replies to the server's roots/list request with dir
+    answerRoots := func(dir string) {
+        for {
+            req := next()
+            if req["method"] != "roots/list" {
+                continue
+            }
+            id, _ := json.Marshal(req["id"])
+            send(`{"jsonrpc":"2.0","id":` + string(id) + `,"result":{"roots":[{"uri":"` + fileURI(dir) + `"}]}}`)
+            return
+        }
+    }
+    // call returns the response to a request, skipping notifications
+    call := func(id int, msg string) map[string]interface{} {
+        send(msg)
+        for {
+            res := next()
+            if res["id"] == float64(id) && res["method"] == nil {
+                return res
+            }
+        }
+    }
+
+    send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{"roots":{"listChanged":true}},"clientInfo":{"name":"test","version":"1"}}}`)
+    if res := next(); res["id"] != float64(1) {
+        t.Fatalf("expected initialize response, got %v", res)
+    }
+    send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+    answerRoots(dirA)
+
+    realA, _ := filepath.EvalSymlinks(dirA)
+    realB, _ := filepath.EvalSymlinks(dirB)
+    list, _ := json.Marshal(call(2, `{"jsonrpc":"2.0","id":2,"method":"resources/list"}`)["result"])
+    if !strings.Contains(string(list), fileURI(realA)) || strings.Contains(string(list), fileURI(realB)) {
+        t.Fatalf("expected only %s to be listed, got %s", realA, list)
+    }
+
+    if res := call(3, `{"jsonrpc":"2.0","id":3,"method":"resources/subscribe","params":{"uri":"`+fileURI(fileA)+`"}}`); res["error"] != nil {
+        t.Fatalf("subscribe: %v", res["error"])
+    }
+
+    // Once the client's roots move away, its subscription is dropped
+    send(`{"jsonrpc":"2.0","method":"notifications/roots/list_changed"}`)
+    answerRoots(dirB)
+    dropped := false
+    for i := 0; i < 100 && !dropped; i++ {
+        srv.pollResources()
+        dropped = len(srv.subscriptions.subscribers()) == 0
+        time.Sleep(10 * time.Millisecond)
+    }
+    if !dropped {
+        t.Fatalf("subscription outside the session's roots was kept")
+    }
+    list, _ = json.Marshal(call(4, `{"jsonrpc":"2.0","id":4,"method":"resources/list"}`)["result"])
+    if strings.Contains(string(list), fileURI(realA)) || !strings.Contains(string(list), fileURI(realB)) {
+        t.Fatalf("expected only %s to be listed, got %s", realB, list)
+    }
+}
+
+func TestToolListWritesFalseHints(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    cfg := config.Default()
+    cfg.AllowedDirectories = config.NewAllowedDirectories([]string{t.TempDir()})
+    srv, err := New(cfg, logger)
+    if err != nil {
+        t.Fatalf("new: %v", err)
+    }
+    defer srv.Shutdown(context.Background())
+
+    inR, inW := io.Pipe()
+    outR, outW := io.Pipe()
+    defer inW.Close()
+    stream := transport.NewStreamWithOptions("hints", srv.mcpServer, outW, transport.StreamOptions{Methods: srv.methods()}, logger)
+    go stream.Serve(context.Background(), inR)
+    out := bufio.NewReader(outR)
+    call := func(msg string) json.RawMessage {
+        if _, err := io.WriteString(inW, msg+"\n"); err != nil {
+            t.Fatalf("send: %v", err)
+        }
+        line, err := out.ReadBytes('\n')
+        if err != nil {
+            t.Fatalf("read: %v", err)
+        }
+        var res struct {
+            Result json.RawMessage `json:"result"`
+        }
+        if err := json.Unmarshal(line, &res); err != nil {
+            t.Fatalf("decode %s: %v", line, err)
+        }
+        return res.Result
+    }
+
+    call(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`)
+    var list struct {
+        Tools []struct {
+            Name        string                     `json:"name"`
+            Annotations map[string]json.RawMessage `json:"annotations"`
+        } `json:"tools"`
+    }
+    if err := json.Unmarshal(call(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`), &list); err != nil {
+        t.Fatalf("decode tools: %v", err)
+    }
+    want := map[string]string{
+        "create_directory": `{"destructiveHint":false,"idempotentHint":true,"openWorldHint":false,"readOnlyHint":false}`,
+        "read_file":        `{"destructiveHint":false,"idempotentHint":true,"openWorldHint":false,"readOnlyHint":true}`,
+        "write_file":       `{"destructiveHint":true,"idempotentHint":true,"openWorldHint":false,"readOnlyHint":false}`,
+    }
+    for _, tool := range list.Tools {
+        expected, ok := want[tool.Name]
+        if !ok {
+            continue
+        }
+        delete(tool.Annotations, "title")
+        hints, _ := json.Marshal(tool.Annotations)
+        if string(hints) != expected {
+            t.Errorf("%s: expected hints %s, got %s", tool.Name, expected, hints)
+        }
+        delete(want, tool.Name)
+    }
+    if len(want) != 0 {
+        t.Fatalf("tools missing from the list: %v", want)
+    }
+}
+
+func TestProtocolVersionNegotiation(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    dir := t.TempDir()
+    cfg := config.Default()
+    cfg.AllowedDirectories = config.NewAllowedDirectories([]string{dir})
+    srv, err := New(cfg, logger)
+    if err != nil {
+        t.Fatalf("new: %v", err)
+    }
+    defer srv.Shutdown(context.Background())
+
+    tests := []struct {
+        requested, negotiated string
+        features              bool
+    }{
+        {"2024-11-05", "2024-11-05", false},
+        {"2025-03-26", "2025-03-26", true},
+        {"2099-01-01", "2025-03-26", true},
+    }
+    for _, tt := range tests {
+        inR, inW := io.Pipe()
+        outR, outW := io.Pipe()
+        stream := transport.NewStreamWithOptions("protocol-"+tt.requested, srv.mcpServer, outW,
+            transport.StreamOptions{Methods: srv.methods()}, logger)
+        go stream.Serve(context.Background(), inR)
+        out := bufio.NewReader(outR)
+        type message struct {
+            ID     int             `json:"id"`
+            Method string          `json:"method"`
+            Params map[string]any  `json:"params"`
+            Result json.RawMessage `json:"result"`
+        }
+        send := func(msg string) {
+            if _, err := io.WriteString(inW, msg+"\n"); err != nil {
+                t.Fatalf("send: %v", err)
+            }
+        }
+        next := func() message {
+            line, err := out.ReadBytes('\n')
+            if err != nil {
+                t.Fatalf("read: %v", err)
+            }
+            var msg message
+            if err := json.Unmarshal(line, &msg); err != nil {
+                t.Fatalf("decode %s: %v", line, err)
+            }
+            return msg
+        }
+
+        send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"` + tt.requested + `","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`)
+        var initialized struct {
+            ProtocolVersion string `json:"protocolVersion"`
+        }
+        if err := json.Unmarshal(next().Result, &initialized); err != nil || initialized.ProtocolVersion != tt.negotiated {
+            t.Fatalf("%s: expected protocol version %s, got %q (%v)", tt.requested, tt.negotiated, initialized.ProtocolVersion, err)
+        }
+        send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+        // Tool annotations are only listed from 2025-03-26; older sessions
+        // do not get the key at all
+        send(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+        var list struct {
+            Tools []map[string]json.RawMessage `json:"tools"`
+        }
+        if err := json.Unmarshal(next().Result, &list); err != nil || len(list.Tools) == 0 {
+            t.Fatalf("%s: unexpected tools/list result: %v", tt.requested, err)
+        }
+        for _, tool := range list.Tools {
+            if _, annotated := tool["annotations"]; annotated != tt.features {
+                t.Errorf("%s: expected annotations listed %v for %s, got %v", tt.requested, tt.features, tool["name"], annotated)
+            }
+        }
+
+        // So are progress messages
+        send(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"search_files","arguments":{"path":"` + dir + `","pattern":"x"},"_meta":{"progressToken":"walk"}}}`)
+        var progress map[string]any
+        for i := 0; i < 2 && progress == nil; i++ {
+            if msg := next(); msg.Method == "notifications/progress" {
+                progress = msg.Params
+            }
+        }
+        if progress == nil {
+            t.Fatalf("%s: expected a progress notification", tt.requested)
+        }
+        if _, ok := progress["message"]; ok != tt.features {
+            t.Errorf("%s: expected progress message %v, got %v", tt.requested, tt.features, progress)
+        }
+        inW.Close()
+    }
+}
+
+func TestLogsReachClient(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    dir := t.TempDir()
+    deep := dir
+    for i := 0; i < 22; i++ {
+        deep = filepath.Join(deep, "d")
+    }
+    if err := os.MkdirAll(deep, 0755); err != nil {
+        t.Fatal(err)
+    }
+    cfg := config.Default()
+    cfg.AllowedDirectories = config.NewAllowedDirectories([]string{dir})
+    srv, err := New(cfg, logger)
+    if err != nil {
+        t.Fatalf("new: %v", err)
+    }
+    defer srv.Shutdown(context.Background())
+
+    inR, inW := io.Pipe()
+    outR, outW := io.Pipe()
+    defer inW.Close()
+    stream := transport.NewStreamWithOptions("logs", srv.mcpServer, outW,
+        transport.StreamOptions{Methods: srv.methods()}, logger)
+    go stream.Serve(context.Background(), inR)
+    out := bufio.NewReader(outR)
+    type message struct {
+        ID     int            `json:"id"`
+        Method string         `json:"method"`
+        Params map[string]any `json:"params"`
+        Error  *struct {
+            Message string `json:"message"`
+        } `json:"error"`
+    }
+    send := func(msg string) {
+        if _, err := io.WriteString(inW, msg+"\n"); err != nil {
+            t.Fatalf("send: %v", err)
+        }
+    }
+    next := func() message {
+        line, err := out.ReadBytes('\n')
+        if err != nil {
+            t.Fatalf("read: %v", err)
+        }
+        var msg message
+        if err := json.Unmarshal(line, &msg); err != nil {
+            t.Fatalf("decode %s: %v", line, err)
+        }
+        return msg
+    }
+    // logsUntil returns the response with id and the log messages read
+    // before it; logs may also arrive after the response
+    logsUntil := func(id int) (message, []map[string]any) {
+        var logs []map[string]any
+        for i := 0; i < 20; i++ {
+            msg := next()
+            if msg.Method == "notifications/message" {
+                logs = append(logs, msg.Params)
+            } else if msg.ID == id {
+                return msg, logs
+            }
+        }
+        t.Fatalf("no response to request %d", id)
+        return message{}, nil
+    }
+
+    send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`)
+    logsUntil(1)
+    send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+    // Warnings are sent without asking, such as why a tree was cut short
+    send(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"directory_tree","arguments":{"path":"` + dir + `"}}}`)
+    _, logs := logsUntil(2)
+    if len(logs) == 0 {
+        logs = append(logs, next().Params)
+    }
+    data, _ := logs[0]["data"].(map[string]any)
+    errText, _ := data["error"].(string)
+    if logs[0]["level"] != "warning" || logs[0]["logger"] != "filesystem" ||
+        data["message"] != "Failed to build subtree" || !strings.Contains(errText, "depth") {
+        t.Fatalf("expected the skipped subtree as a warning, got %v", logs[0])
+    }
+
+    send(`{"jsonrpc":"2.0","id":3,"method":"logging/setLevel","params":{"level":"loud"}}`)
+    if res, _ := logsUntil(3); res.Error == nil {
+        t.Fatal("expected an unknown level to be refused")
+    }
+
+    // Raising the level keeps warnings from the client
+    send(`{"jsonrpc":"2.0","id":4,"method":"logging/setLevel","params":{"level":"error"}}`)
+    if res, _ := logsUntil(4); res.Error != nil {
+        t.Fatalf("set level: %s", res.Error.Message)
+    }
+    outside := filepath.Join(t.TempDir(), "outside.txt")
+    send(`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"read_file","arguments":{"path":"` + outside + `"}}}`)
+    _, logs = logsUntil(5)
+    send(`{"jsonrpc":"2.0","id":6,"method":"ping"}`)
+    _, late := logsUntil(6)
+    for _, log := range append(logs, late...) {
+        if data, _ := log["data"].(map[string]any); data["message"] == "Path validation failed" {
+            t.Fatalf("expected warnings to be held back at level error, got %v", log)
+        }
+    }
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"filesystem/internal/handlers"
+	"filesystem/pkg/audit"
+	"filesystem/pkg/auth"
+	"filesystem/pkg/config"
+	"filesystem/pkg/filesystem"
+	"filesystem/pkg/policy"
+	"filesystem/pkg/secrets"
+	"filesystem/pkg/security"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// snapshot holds the components built from one configuration. Apart from
+// the tool sets cached for scoped sessions it is never modified after
+// creation; a reload builds a new snapshot and swaps it in, so tool calls
+// already running keep the one they started with.
+type snapshot struct {
+	*toolSet
+
+	config   *config.Config
+	logger   *slog.Logger
+	auditLog *audit.Logger
+	grants   *security.Grants
+	rules    *policy.Policy
+	scanner  *secrets.Scanner
+
+	// tokens authenticates HTTP clients; nil when auth is disabled
+	tokens *auth.Tokens
+
+	// mu guards the in-flight call count, retirement state and the tool
+	// sets built for scoped sessions
+	mu      sync.Mutex
+	active  int
+	retired bool
+	onIdle  func()
+	views   map[string]sessionView
+}
+
+// toolSet is a path validator with the operations and tool handlers bound
+// to it
+type toolSet struct {
+	pathValidator *security.PathValidator
+	fsOps         *filesystem.Operations
+	toolHandlers  *handlers.ToolHandlers
+	tools         []server.ServerTool
+	handlers      map[string]server.ToolHandlerFunc
+}
+
+// sessionView is the tool set for one session's token scope and client
+// roots; version is that of the roots it was built for
+type sessionView struct {
+	version uint64
+	tools   *toolSet
+}
+
+// newSnapshot builds the validator, operations and tool handlers for cfg.
+// grants are the directories added at runtime, shared across snapshots.
+func newSnapshot(cfg *config.Config, logger *slog.Logger, auditLog *audit.Logger, grants *security.Grants) (*snapshot, error) {
+	rules, err := policy.New(cfg.Policy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+
+	scanner, err := secrets.New(cfg.SecretScanning)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret scanning configuration: %w", err)
+	}
+
+	var tokens *auth.Tokens
+	if cfg.Auth.Enabled {
+		if tokens, err = auth.New(cfg.AuthTokens()); err != nil {
+			return nil, fmt.Errorf("invalid auth configuration: %w", err)
+		}
+	}
+
+	st := &snapshot{
+		config:   cfg,
+		logger:   logger,
+		auditLog: auditLog,
+		grants:   grants,
+		rules:    rules,
+		scanner:  scanner,
+		tokens:   tokens,
+		views:    make(map[string]sessionView),
+	}
+	st.toolSet = st.newToolSet(cfg.Roots(), true, nil)
+	return st, nil
+}
+
+// newToolSet builds a validator for roots with the rest of the snapshot's
+// settings, and the operations and tool handlers that use it. Runtime
+// grants and the admin tools are included only when admin is set, the
+// grants limited to clientRoots unless it is nil.
+func (st *snapshot) newToolSet(roots []security.Root, admin bool, clientRoots []string) *toolSet {
+	cfg := st.config
+	securityOpts := cfg.SecurityOptions()
+	securityOpts.Roots = roots
+	grantPolicy := cfg.GrantPolicy()
+	if admin {
+		securityOpts.Grants = st.grants
+		securityOpts.GrantScope = clientRoots
+	} else {
+		grantPolicy = nil
+	}
+	pathValidator := security.NewPathValidatorWithOptions(securityOpts, st.logger)
+	fsOps := filesystem.NewOperationsWithOptions(pathValidator, filesystem.Options{
+		Secrets: st.scanner,
+		Naming:  cfg.FilenamePolicy,
+	}, st.logger)
+	toolHandlers := handlers.NewToolHandlersWithOptions(pathValidator, fsOps, handlers.Options{
+		Policy: st.rules,
+		Grants: grantPolicy,
+	}, st.logger)
+
+	tools := toolHandlers.Tools()
+	byName := make(map[string]server.ToolHandlerFunc, len(tools))
+	for _, tool := range tools {
+		byName[tool.Tool.Name] = tool.Handler
+	}
+
+	return &toolSet{
+		pathValidator: pathValidator,
+		fsOps:         fsOps,
+		toolHandlers:  toolHandlers,
+		tools:         tools,
+		handlers:      byName,
+	}
+}
+
+// view returns the tool set limited to a session's token scope and client
+// roots, either of which may be nil, building it when the session's roots
+// have changed since it was last used. Scoped tokens see no runtime grants,
+// which could otherwise widen them, and client roots see only the parts of
+// the grants they contain.
+func (st *snapshot) view(sessionID string, scope []security.Root, roots *sessionRoots) *toolSet {
+	var version uint64
+	if roots != nil {
+		version = roots.version
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if v, ok := st.views[sessionID]; ok && v.version == version {
+		return v.tools
+	}
+	effective := st.config.Roots()
+	if scope != nil {
+		effective = security.RestrictRoots(effective, scope)
+	}
+	var clientRoots []string
+	if roots != nil {
+		effective = security.IntersectRoots(effective, roots.paths)
+		// An empty list still limits the grants, to nothing
+		clientRoots = append([]string{}, roots.paths...)
+	}
+	if len(effective) == 0 {
+		st.logger.Warn("Session shares no directory with the allowed directories",
+			"session", sessionID,
+			"token_scope", scope,
+			"client_roots", clientRoots)
+	}
+	tools := st.newToolSet(effective, scope == nil, clientRoots)
+	st.views[sessionID] = sessionView{version: version, tools: tools}
+	return tools
+}
+
+// dropView forgets the tool set built for a session
+func (st *snapshot) dropView(sessionID string) {
+	st.mu.Lock()
+	delete(st.views, sessionID)
+	st.mu.Unlock()
+}
+
+// acquire registers an in-flight call, failing once the snapshot is retired
+func (st *snapshot) acquire() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.retired {
+		return false
+	}
+	st.active++
+	return true
+}
+
+// release ends an in-flight call and runs the retirement hook when the
+// last call of a retired snapshot finishes
+func (st *snapshot) release() {
+	st.mu.Lock()
+	st.active--
+	idle := st.retired && st.active == 0
+	onIdle := st.onIdle
+	st.mu.Unlock()
+
+	if idle && onIdle != nil {
+		onIdle()
+	}
+}
+
+// retire stops new calls from using the snapshot and runs onIdle once all
+// in-flight calls have finished
+func (st *snapshot) retire(onIdle func()) {
+	st.mu.Lock()
+	st.retired = true
+	st.onIdle = onIdle
+	idle := st.active == 0
+	st.mu.Unlock()
+
+	if idle && onIdle != nil {
+		onIdle()
+	}
+}
+
+// sameTools reports whether two tool sets advertise identical definitions
+func sameTools(a, b []server.ServerTool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	encode := func(tools []server.ServerTool) []byte {
+		defs := make([]mcp.Tool, 0, len(tools))
+		for _, tool := range tools {
+			defs = append(defs, tool.Tool)
+		}
+		data, err := json.Marshal(defs)
+		if err != nil {
+			return nil
+		}
+		return data
+	}
+	encodedA := encode(a)
+	return encodedA != nil && bytes.Equal(encodedA, encode(b))
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// toolList is a tools/list result as it is sent to clients
+type toolList struct {
+	Meta       map[string]interface{} `json:"_meta,omitempty"`
+	Tools      []toolListing          `json:"tools"`
+	NextCursor mcp.Cursor             `json:"nextCursor,omitempty"`
+}
+
+// toolListing is one tool of a tools/list result
+type toolListing struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	InputSchema any              `json:"inputSchema"`
+	Annotations *toolAnnotations `json:"annotations,omitempty"`
+}
+
+// toolAnnotations writes out every hint. mcp-go omits hints that are
+// false, which clients read as the protocol defaults, and those make
+// every tool destructive and open world.
+type toolAnnotations struct {
+	Title           string `json:"title,omitempty"`
+	ReadOnlyHint    bool   `json:"readOnlyHint"`
+	DestructiveHint bool   `json:"destructiveHint"`
+	IdempotentHint  bool   `json:"idempotentHint"`
+	OpenWorldHint   bool   `json:"openWorldHint"`
+}
+
+// listTools handles tools/list. The MCP server builds the list, running
+// its hooks, and the tools are then written out as toolListing. Tool
+// annotations are new in 2025-03-26, so older sessions get none.
+func (s *Server) listTools(ctx context.Context, params json.RawMessage) (any, error) {
+	result, err := s.forward(ctx, mcp.MethodToolsList, params)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := result.(mcp.ListToolsResult)
+	if !ok {
+		return result, nil
+	}
+	return newToolList(list, s.protocols.since(ctx, protocol20250326)), nil
+}
+
+// forward has the MCP server handle a request the transports intercepted
+// and returns its result
+func (s *Server) forward(ctx context.Context, method mcp.MCPMethod, params json.RawMessage) (any, error) {
+	req := map[string]any{"jsonrpc": mcp.JSONRPC_VERSION, "id": 0, "method": string(method)}
+	if len(params) > 0 {
+		req["params"] = params
+	}
+	raw, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+
+	switch resp := s.mcpServer.HandleMessage(ctx, raw).(type) {
+	case mcp.JSONRPCResponse:
+		return resp.Result, nil
+	case mcp.JSONRPCError:
+		return nil, errors.New(resp.Error.Message)
+	}
+	return nil, fmt.Errorf("unexpected response to %s", method)
+}
+
+// newToolList converts the tools/list result of the MCP server, leaving
+// out the annotations unless annotated is set
+func newToolList(result mcp.ListToolsResult, annotated bool) toolList {
+	list := toolList{
+		Meta:       result.Meta,
+		Tools:      make([]toolListing, 0, len(result.Tools)),
+		NextCursor: result.NextCursor,
+	}
+	for _, tool := range result.Tools {
+		var schema any = tool.InputSchema
+		if tool.RawInputSchema != nil {
+			schema = tool.RawInputSchema
+		}
+		listing := toolListing{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: schema,
+		}
+		if annotated {
+			annotations := toolAnnotations(tool.Annotations)
+			listing.Annotations = &annotations
+		}
+		list.Tools = append(list.Tools, listing)
+	}
+	return list
+}
@@ -0,0 +1,805 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// HeaderSessionID carries the session of a streamable HTTP client
+	HeaderSessionID = "Mcp-Session-Id"
+
+	// maxHTTPSessions bounds the open HTTP sessions per Rule 2
+	maxHTTPSessions = 256
+
+	// maxBatch bounds the messages in one request body per Rule 2
+	maxBatch = 64
+
+	// maxBodySize bounds a request body; file content is sent inline
+	maxBodySize = 64 << 20
+
+	// outboundBuffer is the number of server messages queued per session
+	outboundBuffer = 64
+
+	// sessionIdleTimeout ends streamable HTTP sessions unused for this long
+	// without an open event stream
+	sessionIdleTimeout = 30 * time.Minute
+
+	// batchProtocolVersion is the first MCP protocol version with JSON-RPC
+	// batches; sessions that negotiated an earlier one must not send them
+	batchProtocolVersion = "2025-03-26"
+
+	// batchVersionMessage refuses a batch from a session whose protocol
+	// version predates them
+	batchVersionMessage = "batches require protocol version " + batchProtocolVersion
+)
+
+// errTooManySessions is returned when maxHTTPSessions are open
+var errTooManySessions = errors.New("too many open sessions")
+
+// HTTPOptions configures the HTTP transports
+type HTTPOptions struct {
+	// BasePath prefixes the endpoint paths; empty serves them at the root
+	BasePath string
+
+	// KeepAlive is the interval of comments written to event streams so
+	// idle connections stay open; zero disables them
+	KeepAlive time.Duration
+
+	// AllowedOrigins are browser origins accepted besides loopback ones
+	AllowedOrigins []string
+
+	// Authenticate identifies the client of each request, returning an
+	// error to refuse it; nil accepts every client anonymously. A session
+	// only accepts requests from the client that opened it.
+	Authenticate func(r *http.Request) (string, error)
+
+	// Methods handle client requests in place of the MCP server
+	Methods Methods
+}
+
+// Authenticated is implemented by sessions that know their client
+type Authenticated interface {
+	// Principal names the authenticated client, or is empty
+	Principal() string
+}
+
+// HTTPHandler serves MCP clients over HTTP, either with streamable HTTP at
+// {base}/mcp or with the HTTP+SSE transport of earlier protocol versions at
+// {base}/sse and {base}/message. Server requests such as roots/list are
+// sent on a session's event stream and answered with a POST.
+type HTTPHandler struct {
+	server     *server.MCPServer
+	options    HTTPOptions
+	logger     *slog.Logger
+	streamable bool
+
+	// mu guards sessions and closed
+	mu       sync.Mutex
+	sessions map[string]*httpSession
+	closed   bool
+}
+
+// NewStreamableHandler creates a handler for the streamable HTTP transport
+func NewStreamableHandler(srv *server.MCPServer, options HTTPOptions, logger *slog.Logger) *HTTPHandler {
+	return newHTTPHandler(srv, options, logger, true)
+}
+
+// NewSSEHandler creates a handler for the HTTP+SSE transport
+func NewSSEHandler(srv *server.MCPServer, options HTTPOptions, logger *slog.Logger) *HTTPHandler {
+	return newHTTPHandler(srv, options, logger, false)
+}
+
+// newHTTPHandler creates a handler for either transport
+func newHTTPHandler(srv *server.MCPServer, options HTTPOptions, logger *slog.Logger, streamable bool) *HTTPHandler {
+	options.BasePath = strings.TrimSuffix(options.BasePath, "/")
+	return &HTTPHandler{
+		server:     srv,
+		options:    options,
+		logger:     logger,
+		streamable: streamable,
+		sessions:   make(map[string]*httpSession),
+	}
+}
+
+// Endpoints returns the paths the handler serves
+func (h *HTTPHandler) Endpoints() []string {
+	if h.streamable {
+		return []string{h.options.BasePath + "/mcp"}
+	}
+	return []string{h.options.BasePath + "/sse", h.options.BasePath + "/message"}
+}
+
+// ServeHTTP implements http.Handler
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.allowedOrigin(r) {
+		h.logger.Warn("Refusing request from disallowed origin",
+			"origin", r.Header.Get("Origin"),
+			"remote", r.RemoteAddr)
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	principal, err := h.authenticate(r)
+	if err != nil {
+		h.logger.Warn("Refusing unauthenticated request", "remote", r.RemoteAddr, "error", err)
+		w.Header().Set("WWW-Authenticate", `Bearer realm="mcp"`)
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	base := h.options.BasePath
+	switch {
+	case h.streamable && r.URL.Path == base+"/mcp":
+		switch r.Method {
+		case http.MethodPost:
+			h.postStreamable(w, r, principal)
+		case http.MethodGet:
+			h.getStreamable(w, r, principal)
+		case http.MethodDelete:
+			h.deleteStreamable(w, r, principal)
+		default:
+			methodNotAllowed(w, "GET, POST, DELETE")
+		}
+	case !h.streamable && r.URL.Path == base+"/sse":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, "GET")
+			return
+		}
+		h.getSSE(w, r, principal)
+	case !h.streamable && r.URL.Path == base+"/message":
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, "POST")
+			return
+		}
+		h.postSSE(w, r, principal)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Close ends every session and refuses new ones. Open event streams return.
+func (h *HTTPHandler) Close() {
+	h.mu.Lock()
+	h.closed = true
+	sessions := make([]*httpSession, 0, len(h.sessions))
+	for id, session := range h.sessions {
+		sessions = append(sessions, session)
+		delete(h.sessions, id)
+	}
+	h.mu.Unlock()
+
+	for _, session := range sessions {
+		session.close()
+		h.server.UnregisterSession(context.Background(), session.id)
+	}
+}
+
+// postStreamable handles messages posted to the streamable HTTP endpoint.
+// Responses to requests are returned in the body; an initialize request
+// starts a new session.
+func (h *HTTPHandler) postStreamable(w http.ResponseWriter, r *http.Request, principal string) {
+	p, ok := readMessages(w, r)
+	if !ok {
+		return
+	}
+
+	var session *httpSession
+	if p.initializes() {
+		if p.batch {
+			writeRPCError(w, http.StatusBadRequest, mcp.INVALID_REQUEST, "initialize must not be batched")
+			return
+		}
+		opened, err := h.open(principal)
+		if err != nil {
+			h.logger.Warn("Refusing new HTTP session", "remote", r.RemoteAddr, "error", err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		session = opened
+	} else if session = h.sessionFor(w, r.Header.Get(HeaderSessionID), principal); session == nil {
+		return
+	} else if p.batch && !session.batches.Load() {
+		writeRPCError(w, http.StatusBadRequest, mcp.INVALID_REQUEST, batchVersionMessage)
+		return
+	}
+	w.Header().Set(HeaderSessionID, session.id)
+
+	responses := make([]mcp.JSONRPCMessage, 0, len(p.raws))
+	for i, raw := range p.raws {
+		msg := p.msgs[i]
+		switch {
+		case msg.isResponse():
+			h.resolve(session, msg)
+		case msg.isNotification():
+			h.notify(session, msg, raw)
+		default:
+			response, err := h.handleRequest(r.Context(), session, msg, raw)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			if response != nil {
+				responses = append(responses, response)
+			}
+		}
+	}
+
+	// A failed initialize leaves nothing to continue
+	if p.initializes() && len(responses) == 1 {
+		if _, failed := responses[0].(mcp.JSONRPCError); failed {
+			h.end(session)
+		}
+	}
+
+	switch {
+	case len(responses) == 0:
+		w.WriteHeader(http.StatusAccepted)
+	case p.batch:
+		writeJSON(w, http.StatusOK, responses)
+	default:
+		writeJSON(w, http.StatusOK, responses[0])
+	}
+}
+
+// getStreamable opens the event stream of a streamable HTTP session, which
+// carries its notifications and server requests
+func (h *HTTPHandler) getStreamable(w http.ResponseWriter, r *http.Request, principal string) {
+	if !acceptsEventStream(r) {
+		http.Error(w, "client must accept text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	session := h.sessionFor(w, r.Header.Get(HeaderSessionID), principal)
+	if session == nil {
+		return
+	}
+	if !session.streaming.CompareAndSwap(false, true) {
+		http.Error(w, "session already has an event stream", http.StatusConflict)
+		return
+	}
+	defer func() {
+		session.touch()
+		session.streaming.Store(false)
+	}()
+
+	w.Header().Set(HeaderSessionID, session.id)
+	h.stream(w, r, session, nil)
+}
+
+// deleteStreamable ends a streamable HTTP session at the client's request
+func (h *HTTPHandler) deleteStreamable(w http.ResponseWriter, r *http.Request, principal string) {
+	session := h.sessionFor(w, r.Header.Get(HeaderSessionID), principal)
+	if session == nil {
+		return
+	}
+	h.end(session)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getSSE opens an HTTP+SSE session, which lasts as long as its event
+// stream. The first event tells the client where to post messages.
+func (h *HTTPHandler) getSSE(w http.ResponseWriter, r *http.Request, principal string) {
+	session, err := h.open(principal)
+	if err != nil {
+		h.logger.Warn("Refusing new HTTP session", "remote", r.RemoteAddr, "error", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer h.end(session)
+	session.streaming.Store(true)
+
+	endpoint := h.options.BasePath + "/message?sessionId=" + url.QueryEscape(session.id)
+	h.stream(w, r, session, func() error {
+		return writeEvent(w, "endpoint", []byte(endpoint))
+	})
+}
+
+// postSSE handles messages posted to an HTTP+SSE session. Responses are
+// sent on the event stream, so requests run beyond the POST.
+func (h *HTTPHandler) postSSE(w http.ResponseWriter, r *http.Request, principal string) {
+	session := h.sessionFor(w, r.URL.Query().Get("sessionId"), principal)
+	if session == nil {
+		return
+	}
+	p, ok := readMessages(w, r)
+	if !ok {
+		return
+	}
+	if p.batch && !session.batches.Load() {
+		writeRPCError(w, http.StatusBadRequest, mcp.INVALID_REQUEST, batchVersionMessage)
+		return
+	}
+
+	for i, raw := range p.raws {
+		msg := p.msgs[i]
+		switch {
+		case msg.isResponse():
+			h.resolve(session, msg)
+		case msg.isNotification():
+			h.notify(session, msg, raw)
+		default:
+			select {
+			case session.slots <- struct{}{}:
+			case <-r.Context().Done():
+				return
+			case <-session.ctx.Done():
+				http.Error(w, ErrClosed.Error(), http.StatusNotFound)
+				return
+			}
+			ctx, done := session.running.start(session.ctx, msg.ID)
+			go func() {
+				defer func() { <-session.slots }()
+				defer done()
+				response := h.options.Methods.handle(ctx, h.server, msg, raw)
+				if response == nil {
+					return
+				}
+				session.observe(msg, response)
+				if err := session.deliver(response); err != nil {
+					h.logger.Debug("Dropping response for closed session", "session", session.id)
+				}
+			}()
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleRequest runs one request of a streamable HTTP session. It ends
+// when the client disconnects or the session ends.
+func (h *HTTPHandler) handleRequest(ctx context.Context, session *httpSession, msg message, raw json.RawMessage) (mcp.JSONRPCMessage, error) {
+	select {
+	case session.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-session.ctx.Done():
+		return nil, ErrClosed
+	}
+	defer func() { <-session.slots }()
+
+	ctx, cancel := context.WithCancel(h.server.WithContext(ctx, session))
+	defer cancel()
+	stop := context.AfterFunc(session.ctx, cancel)
+	defer stop()
+	ctx, done := session.running.start(ctx, msg.ID)
+	defer done()
+	response := h.options.Methods.handle(ctx, h.server, msg, raw)
+	session.observe(msg, response)
+	return response, nil
+}
+
+// notify passes a notification to the server, first cancelling the request
+// it names if it is notifications/cancelled
+func (h *HTTPHandler) notify(session *httpSession, msg message, raw json.RawMessage) {
+	if msg.Method == methodCancelled && session.running.cancel(msg.Params) {
+		h.logger.Debug("Client cancelled request", "session", session.id, "params", string(msg.Params))
+	}
+	h.server.HandleMessage(session.ctx, raw)
+}
+
+// stream writes a session's notifications and server messages as server
+// sent events until the client disconnects or the session ends. first, if
+// set, writes the opening event.
+func (h *HTTPHandler) stream(w http.ResponseWriter, r *http.Request, session *httpSession, first func() error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if first != nil {
+		if err := first(); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	var tick <-chan time.Time
+	if h.options.KeepAlive > 0 {
+		ticker := time.NewTicker(h.options.KeepAlive)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		var msg any
+		select {
+		case <-r.Context().Done():
+			return
+		case <-session.ctx.Done():
+			return
+		case notification := <-session.notifications:
+			msg = notification
+		case msg = <-session.outbound:
+		case <-tick:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+			continue
+		}
+
+		data, err := json.Marshal(msg)
+		if err != nil {
+			h.logger.Error("Failed to encode message", "session", session.id, "error", err)
+			continue
+		}
+		if err := writeEvent(w, "message", data); err != nil {
+			h.logger.Debug("Event stream closed", "session", session.id, "error", err)
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// open creates and registers a session for principal, first ending idle
+// ones
+func (h *HTTPHandler) open(principal string) (*httpSession, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil, ErrClosed
+	}
+	now := time.Now()
+	idle := make([]*httpSession, 0)
+	for sid, session := range h.sessions {
+		if session.idle(now) {
+			idle = append(idle, session)
+			delete(h.sessions, sid)
+		}
+	}
+	if len(h.sessions) >= maxHTTPSessions {
+		h.mu.Unlock()
+		h.unregister(idle)
+		return nil, errTooManySessions
+	}
+	session := newHTTPSession(id, principal, h.server)
+	h.sessions[id] = session
+	h.mu.Unlock()
+	h.unregister(idle)
+
+	if err := h.server.RegisterSession(session.ctx, session); err != nil {
+		h.end(session)
+		return nil, fmt.Errorf("register session: %w", err)
+	}
+	h.logger.Debug("HTTP session opened", "session", id, "principal", principal)
+	return session, nil
+}
+
+// end removes a session and fails its pending requests
+func (h *HTTPHandler) end(session *httpSession) {
+	h.mu.Lock()
+	current, ok := h.sessions[session.id]
+	if ok && current == session {
+		delete(h.sessions, session.id)
+	}
+	h.mu.Unlock()
+	if ok && current == session {
+		h.unregister([]*httpSession{session})
+		h.logger.Debug("HTTP session ended", "session", session.id)
+	}
+}
+
+// unregister closes sessions already removed from the handler
+func (h *HTTPHandler) unregister(sessions []*httpSession) {
+	for _, session := range sessions {
+		session.close()
+		h.server.UnregisterSession(context.Background(), session.id)
+	}
+}
+
+// sessionFor returns the open session with the given ID, writing an error
+// response and returning nil when there is none or it belongs to another
+// client
+func (h *HTTPHandler) sessionFor(w http.ResponseWriter, id, principal string) *httpSession {
+	if id == "" {
+		http.Error(w, "session ID is required", http.StatusBadRequest)
+		return nil
+	}
+	h.mu.Lock()
+	session := h.sessions[id]
+	h.mu.Unlock()
+	if session == nil || session.principal != principal {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return nil
+	}
+	session.touch()
+	return session
+}
+
+// resolve delivers a client response to the server request waiting for it
+func (h *HTTPHandler) resolve(session *httpSession, msg message) {
+	if !session.requests.resolve(msg) {
+		h.logger.Debug("Ignoring response to unknown request", "session", session.id, "id", string(msg.ID))
+	}
+}
+
+// authenticate identifies the client of a request
+func (h *HTTPHandler) authenticate(r *http.Request) (string, error) {
+	if h.options.Authenticate == nil {
+		return "", nil
+	}
+	return h.options.Authenticate(r)
+}
+
+// allowedOrigin reports whether a request may be served. Browsers send
+// Origin with cross-site requests; refusing other origins stops web pages
+// from reaching a server on localhost, including through DNS rebinding.
+func (h *HTTPHandler) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range h.options.AllowedOrigins {
+		if origin == allowed {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// httpSession is one client of an HTTP transport
+type httpSession struct {
+	id        string
+	principal string
+
+	// ctx carries the session and is cancelled when it ends
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	notifications chan mcp.JSONRPCNotification
+	outbound      chan any
+	initialized   atomic.Bool
+	streaming     atomic.Bool
+	batches       atomic.Bool
+	lastUsed      atomic.Int64
+	requests      *pendingRequests
+	running       *runningRequests
+
+	// slots bounds the requests handled at once per Rule 2
+	slots chan struct{}
+}
+
+// newHTTPSession creates a session whose context carries it for srv
+func newHTTPSession(id, principal string, srv *server.MCPServer) *httpSession {
+	session := &httpSession{
+		id:            id,
+		principal:     principal,
+		notifications: make(chan mcp.JSONRPCNotification, notificationBuffer),
+		outbound:      make(chan any, outboundBuffer),
+		requests:      newPendingRequests(),
+		running:       newRunningRequests(),
+		slots:         make(chan struct{}, maxInFlight),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	session.ctx = srv.WithContext(ctx, session)
+	session.cancel = cancel
+	session.touch()
+	return session
+}
+
+// SessionID implements server.ClientSession
+func (s *httpSession) SessionID() string {
+	return s.id
+}
+
+// NotificationChannel implements server.ClientSession
+func (s *httpSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+// Initialize implements server.ClientSession
+func (s *httpSession) Initialize() {
+	s.initialized.Store(true)
+}
+
+// Initialized implements server.ClientSession
+func (s *httpSession) Initialized() bool {
+	return s.initialized.Load()
+}
+
+// Principal implements Authenticated
+func (s *httpSession) Principal() string {
+	return s.principal
+}
+
+// Request sends method to the client on its event stream and waits for
+// the response it posts
+func (s *httpSession) Request(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	return s.requests.do(ctx, method, params, func(msg any) error {
+		select {
+		case s.outbound <- msg:
+			return nil
+		case <-s.ctx.Done():
+			return ErrClosed
+		default:
+			return fmt.Errorf("message queue of session %s is full", s.id)
+		}
+	})
+}
+
+// deliver queues a response for the event stream, waiting for room
+func (s *httpSession) deliver(msg any) error {
+	select {
+	case s.outbound <- msg:
+		return nil
+	case <-s.ctx.Done():
+		return ErrClosed
+	}
+}
+
+// touch records that the session was used
+func (s *httpSession) touch() {
+	s.lastUsed.Store(time.Now().UnixNano())
+}
+
+// idle reports whether the session has gone unused without an event stream
+// for longer than sessionIdleTimeout
+func (s *httpSession) idle(now time.Time) bool {
+	return !s.streaming.Load() && now.Sub(time.Unix(0, s.lastUsed.Load())) > sessionIdleTimeout
+}
+
+// observe notes whether the protocol version negotiated by an initialize
+// response allows batches
+func (s *httpSession) observe(msg message, response mcp.JSONRPCMessage) {
+	if msg.Method != string(mcp.MethodInitialize) {
+		return
+	}
+	res, ok := response.(mcp.JSONRPCResponse)
+	if !ok {
+		return
+	}
+	if result, ok := res.Result.(mcp.InitializeResult); ok {
+		// Versions are dates, so they order as strings
+		s.batches.Store(result.ProtocolVersion >= batchProtocolVersion)
+	}
+}
+
+// close ends the session's context and fails its pending requests
+func (s *httpSession) close() {
+	s.cancel()
+	s.requests.close()
+}
+
+// postedMessages is the body of a POST: one message or a batch
+type postedMessages struct {
+	raws  []json.RawMessage
+	msgs  []message
+	batch bool
+}
+
+// initializes reports whether the body holds an initialize request
+func (p postedMessages) initializes() bool {
+	for _, msg := range p.msgs {
+		if msg.Method == string(mcp.MethodInitialize) {
+			return true
+		}
+	}
+	return false
+}
+
+// readMessages decodes the body of a POST, writing an error response and
+// returning false when it is not valid JSON-RPC
+func readMessages(w http.ResponseWriter, r *http.Request) (postedMessages, bool) {
+	var p postedMessages
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return p, false
+		}
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return p, false
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		p.batch = true
+		if err := json.Unmarshal(body, &p.raws); err != nil {
+			writeRPCError(w, http.StatusBadRequest, mcp.PARSE_ERROR, "Parse error")
+			return p, false
+		}
+		if len(p.raws) == 0 || len(p.raws) > maxBatch {
+			writeRPCError(w, http.StatusBadRequest, mcp.INVALID_REQUEST,
+				fmt.Sprintf("batch must hold 1 to %d messages", maxBatch))
+			return p, false
+		}
+	} else {
+		p.raws = []json.RawMessage{body}
+	}
+
+	p.msgs = make([]message, len(p.raws))
+	for i, raw := range p.raws {
+		if err := json.Unmarshal(raw, &p.msgs[i]); err != nil {
+			writeRPCError(w, http.StatusBadRequest, mcp.PARSE_ERROR, "Parse error")
+			return p, false
+		}
+	}
+	return p, true
+}
+
+// writeJSON writes v as a JSON response body
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeRPCError writes a JSON-RPC error that answers no particular request
+func writeRPCError(w http.ResponseWriter, status, code int, msg string) {
+	response := mcp.JSONRPCError{JSONRPC: mcp.JSONRPC_VERSION}
+	response.Error.Code = code
+	response.Error.Message = msg
+	writeJSON(w, status, response)
+}
+
+// writeEvent writes one server sent event; data must not contain newlines
+func writeEvent(w io.Writer, event string, data []byte) error {
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+// methodNotAllowed rejects a request method, listing the allowed ones
+func methodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+}
+
+// acceptsEventStream reports whether the client accepts server sent events
+func acceptsEventStream(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		if strings.Contains(accept, "text/event-stream") {
+			return true
+		}
+	}
+	return false
+}
+
+// newSessionID returns a random session ID
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+var (
+	_ server.ClientSession = (*httpSession)(nil)
+	_ Requester            = (*httpSession)(nil)
+	_ Authenticated        = (*httpSession)(nil)
+	_ http.Handler         = (*HTTPHandler)(nil)
+)
@@ -0,0 +1,37 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// MethodFunc handles a client request the MCP server does not implement,
+// or does not answer as the server should, returning its result or an
+// error sent back as invalid params
+type MethodFunc func(ctx context.Context, params json.RawMessage) (any, error)
+
+// Methods are handlers for client requests by method name. The transports
+// try them before passing a request to the MCP server.
+type Methods map[string]MethodFunc
+
+// handle answers the request msg, read as raw, with its method when there
+// is one and with srv otherwise. Notifications always go to srv.
+func (m Methods) handle(ctx context.Context, srv *server.MCPServer, msg message, raw []byte) mcp.JSONRPCMessage {
+	fn, ok := m[msg.Method]
+	if !ok || msg.isNotification() {
+		return srv.HandleMessage(ctx, raw)
+	}
+
+	var id mcp.RequestId
+	if err := json.Unmarshal(msg.ID, &id); err != nil {
+		return mcp.NewJSONRPCError(nil, mcp.INVALID_REQUEST, "invalid request id", nil)
+	}
+	result, err := fn(ctx, msg.Params)
+	if err != nil {
+		return mcp.NewJSONRPCError(id, mcp.INVALID_PARAMS, err.Error(), nil)
+	}
+	return mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPC_VERSION, ID: id, Result: result}
+}
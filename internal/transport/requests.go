@@ -0,0 +1,206 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ErrClosed is returned by Request once the session has ended
+var ErrClosed = errors.New("client connection closed")
+
+// methodCancelled is the notification a client sends to cancel one of its
+// requests
+const methodCancelled = "notifications/cancelled"
+
+// Requester sends requests from the server to the client of a session
+type Requester interface {
+	// Request sends method with params and waits for the client's result
+	Request(ctx context.Context, method string, params any) (json.RawMessage, error)
+}
+
+// RequestError is an error response from the client to a server request
+type RequestError struct {
+	Code    int
+	Message string
+}
+
+// Error implements the error interface
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("client returned error %d: %s", e.Code, e.Message)
+}
+
+// message is the union of the JSON-RPC messages a client sends
+type message struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// isResponse reports whether the message answers a server request
+func (m message) isResponse() bool {
+	return m.Method == "" && len(m.ID) > 0 && (m.Result != nil || m.Error != nil)
+}
+
+// isNotification reports whether the message expects no response
+func (m message) isNotification() bool {
+	return len(m.ID) == 0
+}
+
+// reply is the outcome of a server request
+type reply struct {
+	result json.RawMessage
+	err    error
+}
+
+// pendingRequests matches client responses to the server requests of one
+// session
+type pendingRequests struct {
+	// mu guards waiting and closed
+	mu      sync.Mutex
+	waiting map[string]chan reply
+	closed  bool
+	nextID  atomic.Int64
+}
+
+// newPendingRequests creates an empty set of requests
+func newPendingRequests() *pendingRequests {
+	return &pendingRequests{waiting: make(map[string]chan reply)}
+}
+
+// do sends method to the client with send and waits for its response
+func (p *pendingRequests) do(ctx context.Context, method string, params any, send func(msg any) error) (json.RawMessage, error) {
+	id := "server-" + strconv.FormatInt(p.nextID.Add(1), 10)
+	key := strconv.Quote(id)
+	ch := make(chan reply, 1)
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrClosed
+	}
+	p.waiting[key] = ch
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.waiting, key)
+		p.mu.Unlock()
+	}()
+
+	request := struct {
+		JSONRPC string `json:"jsonrpc"`
+		ID      string `json:"id"`
+		Method  string `json:"method"`
+		Params  any    `json:"params,omitempty"`
+	}{JSONRPC: mcp.JSONRPC_VERSION, ID: id, Method: method, Params: params}
+	if err := send(request); err != nil {
+		return nil, err
+	}
+
+	select {
+	case r := <-ch:
+		return r.result, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// resolve delivers a client response to the request waiting for it and
+// reports whether there was one
+func (p *pendingRequests) resolve(msg message) bool {
+	p.mu.Lock()
+	ch, ok := p.waiting[string(msg.ID)]
+	p.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	r := reply{result: msg.Result}
+	if msg.Error != nil {
+		r = reply{err: &RequestError{Code: msg.Error.Code, Message: msg.Error.Message}}
+	}
+	// A duplicate response is dropped rather than blocking the reader
+	select {
+	case ch <- r:
+	default:
+	}
+	return true
+}
+
+// close fails every request still waiting for the client and any made later
+func (p *pendingRequests) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for id, ch := range p.waiting {
+		select {
+		case ch <- reply{err: ErrClosed}:
+		default:
+		}
+		delete(p.waiting, id)
+	}
+}
+
+// runningRequests holds the contexts of the client requests a session is
+// handling, so that notifications/cancelled can end them
+type runningRequests struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+// newRunningRequests creates an empty set of requests
+func newRunningRequests() *runningRequests {
+	return &runningRequests{cancels: make(map[string]context.CancelFunc)}
+}
+
+// start returns the context to handle the request with id in and the
+// function to call once it is handled
+func (r *runningRequests) start(ctx context.Context, id json.RawMessage) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	key := string(bytes.TrimSpace(id))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// A reused ID cannot be told apart, so only the first is cancellable
+	if _, dup := r.cancels[key]; dup {
+		return ctx, cancel
+	}
+	r.cancels[key] = cancel
+	return ctx, func() {
+		cancel()
+		r.mu.Lock()
+		delete(r.cancels, key)
+		r.mu.Unlock()
+	}
+}
+
+// cancel ends the request named by the params of a notifications/cancelled
+// message and reports whether it was running
+func (r *runningRequests) cancel(params json.RawMessage) bool {
+	var p struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil || len(p.RequestID) == 0 {
+		return false
+	}
+
+	r.mu.Lock()
+	cancel, ok := r.cancels[string(bytes.TrimSpace(p.RequestID))]
+	r.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
@@ -0,0 +1,244 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// maxInFlight bounds the client requests handled at once per Rule 2
+	maxInFlight = 32
+
+	// notificationBuffer is the number of notifications queued per session
+	notificationBuffer = 100
+)
+
+// Stream serves one MCP client over newline-delimited JSON-RPC, such as
+// stdin and stdout. Unlike the mcp-go stdio server it reads the client's
+// responses, so the server can send requests such as roots/list, and it
+// handles requests concurrently so a handler may wait on the client.
+type Stream struct {
+	id     string
+	server *server.MCPServer
+	logger *slog.Logger
+
+	// writeMu serializes messages written to out
+	writeMu sync.Mutex
+	out     io.Writer
+
+	notifications chan mcp.JSONRPCNotification
+	initialized   atomic.Bool
+	requests      *pendingRequests
+	running       *runningRequests
+	methods       Methods
+}
+
+// StreamOptions configures a stream session
+type StreamOptions struct {
+	// Methods handle client requests in place of the MCP server
+	Methods Methods
+}
+
+// NewStream creates a session with the given ID writing to out
+func NewStream(id string, srv *server.MCPServer, out io.Writer, logger *slog.Logger) *Stream {
+	return NewStreamWithOptions(id, srv, out, StreamOptions{}, logger)
+}
+
+// NewStreamWithOptions creates a session with the given ID writing to out,
+// configured by options
+func NewStreamWithOptions(id string, srv *server.MCPServer, out io.Writer, options StreamOptions, logger *slog.Logger) *Stream {
+	return &Stream{
+		id:            id,
+		server:        srv,
+		logger:        logger,
+		out:           out,
+		notifications: make(chan mcp.JSONRPCNotification, notificationBuffer),
+		requests:      newPendingRequests(),
+		running:       newRunningRequests(),
+		methods:       options.Methods,
+	}
+}
+
+// SessionID implements server.ClientSession
+func (s *Stream) SessionID() string {
+	return s.id
+}
+
+// NotificationChannel implements server.ClientSession
+func (s *Stream) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+// Initialize implements server.ClientSession
+func (s *Stream) Initialize() {
+	s.initialized.Store(true)
+}
+
+// Initialized implements server.ClientSession
+func (s *Stream) Initialized() bool {
+	return s.initialized.Load()
+}
+
+// Serve registers the session and handles messages read from in until it
+// ends or ctx is cancelled. Requests still running are waited for.
+func (s *Stream) Serve(ctx context.Context, in io.Reader) error {
+	// Input validation per Rule 7
+	if ctx == nil {
+		return fmt.Errorf("context is required")
+	}
+	if in == nil {
+		return fmt.Errorf("input is required")
+	}
+
+	if err := s.server.RegisterSession(ctx, s); err != nil {
+		return fmt.Errorf("register session: %w", err)
+	}
+	defer s.server.UnregisterSession(ctx, s.id)
+
+	ctx, cancel := context.WithCancel(s.server.WithContext(ctx, s))
+	defer cancel()
+
+	go s.writeNotifications(ctx)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer s.requests.close()
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go readLines(ctx, in, lines, readErr)
+
+	slots := make(chan struct{}, maxInFlight)
+	for {
+		var line []byte
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read message: %w", err)
+		case line = <-lines:
+		}
+
+		var msg message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			// Let the server produce the parse error response
+			s.handle(ctx, msg, line)
+			continue
+		}
+
+		// Responses complete requests sent by the server
+		if msg.isResponse() {
+			if !s.requests.resolve(msg) {
+				s.logger.Debug("Ignoring response to unknown request", "session", s.id, "id", string(msg.ID))
+			}
+			continue
+		}
+
+		// Notifications are handled in order, requests concurrently
+		if msg.isNotification() {
+			if msg.Method == methodCancelled && s.running.cancel(msg.Params) {
+				s.logger.Debug("Client cancelled request", "session", s.id, "params", string(msg.Params))
+			}
+			s.handle(ctx, msg, line)
+			continue
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		requestCtx, done := s.running.start(ctx, msg.ID)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer done()
+			s.handle(requestCtx, msg, line)
+		}()
+	}
+}
+
+// Request sends method to the client and waits for its response
+func (s *Stream) Request(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	return s.requests.do(ctx, method, params, s.write)
+}
+
+// handle passes one message to its method or the MCP server and writes
+// its response
+func (s *Stream) handle(ctx context.Context, msg message, line []byte) {
+	response := s.methods.handle(ctx, s.server, msg, line)
+	if response == nil {
+		return
+	}
+	if err := s.write(response); err != nil {
+		s.logger.Error("Failed to write response", "session", s.id, "error", err)
+	}
+}
+
+// writeNotifications forwards queued notifications until ctx is done
+func (s *Stream) writeNotifications(ctx context.Context) {
+	for {
+		select {
+		case notification := <-s.notifications:
+			if err := s.write(notification); err != nil {
+				s.logger.Error("Failed to write notification", "session", s.id, "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// write sends one JSON-RPC message followed by a newline
+func (s *Stream) write(msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	data = append(data, '\n')
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, err := s.out.Write(data); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// readLines sends each non-empty line of in to lines until an error,
+// which is sent to errs
+func readLines(ctx context.Context, in io.Reader, lines chan<- []byte, errs chan<- error) {
+	reader := bufio.NewReader(in)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && (len(line) > 1 || line[0] != '\n') {
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err != nil {
+			errs <- err
+			return
+		}
+	}
+}
+
+var (
+	_ server.ClientSession = (*Stream)(nil)
+	_ Requester            = (*Stream)(nil)
+)
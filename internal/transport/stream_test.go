@@ -0,0 +1,417 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"filesystem/pkg/peercred"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// pipeClient drives a Stream from the client side
+type pipeClient struct {
+	t   *testing.T
+	in  *io.PipeWriter
+	out *bufio.Reader
+}
+
+func (c *pipeClient) send(msg string) {
+	c.t.Helper()
+	if _, err := io.WriteString(c.in, msg+"\n"); err != nil {
+		c.t.Fatalf("send: %v", err)
+	}
+}
+
+func (c *pipeClient) next() map[string]interface{} {
+	c.t.Helper()
+	line, err := c.out.ReadBytes('\n')
+	if err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal(line, &msg); err != nil {
+		c.t.Fatalf("decode %q: %v", line, err)
+	}
+	return msg
+}
+
+func newPipeStream(t *testing.T, srv *server.MCPServer) (*Stream, *pipeClient, chan error) {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	stream := NewStream("test", srv, outW, logger)
+
+	done := make(chan error, 1)
+	go func() { done <- stream.Serve(context.Background(), inR) }()
+	t.Cleanup(func() { inW.Close() })
+	return stream, &pipeClient{t: t, in: inW, out: bufio.NewReader(outR)}, done
+}
+
+func TestStreamServerRequestWhileHandlingTool(t *testing.T) {
+	srv := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(true))
+
+	// The tool waits on the client, which only works when responses are
+	// read while the call is running
+	srv.AddTool(mcp.NewTool("ask"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		requester := server.ClientSessionFromContext(ctx).(Requester)
+		raw, err := requester.Request(ctx, "roots/list", nil)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText(string(raw)), nil
+	})
+	_, client, _ := newPipeStream(t, srv)
+
+	client.send(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"ask"}}`)
+	req := client.next()
+	if req["method"] != "roots/list" {
+		t.Fatalf("expected roots/list request, got %v", req)
+	}
+	id, _ := json.Marshal(req["id"])
+	client.send(`{"jsonrpc":"2.0","id":` + string(id) + `,"result":{"roots":[]}}`)
+
+	res := client.next()
+	if res["id"] != float64(1) {
+		t.Fatalf("expected tool response, got %v", res)
+	}
+	data, _ := json.Marshal(res["result"])
+	if !strings.Contains(string(data), `roots`) {
+		t.Fatalf("tool should return the client's result, got %s", data)
+	}
+}
+
+func TestStreamRequestFailsWhenClosed(t *testing.T) {
+	srv := server.NewMCPServer("test", "1.0.0")
+	stream, client, done := newPipeStream(t, srv)
+
+	client.in.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("serve should end cleanly at EOF: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("serve did not return at EOF")
+	}
+
+	if _, err := stream.Request(context.Background(), "roots/list", nil); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestStreamCancelsRequestOnNotification(t *testing.T) {
+	_, client, _ := newPipeStream(t, newAskServer())
+
+	// The tool waits on a roots/list the client never answers
+	client.send(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"ask"}}`)
+	if req := client.next(); req["method"] != "roots/list" {
+		t.Fatalf("expected roots/list request, got %v", req)
+	}
+	client.send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7,"reason":"user"}}`)
+
+	res := client.next()
+	data, _ := json.Marshal(res["result"])
+	if res["id"] != float64(7) || !strings.Contains(string(data), "context canceled") {
+		t.Fatalf("the cancelled call should end with its context, got %v", res)
+	}
+}
+
+// readEvent reads one server sent event, skipping keep-alive comments
+func readEvent(t *testing.T, r *bufio.Reader) (string, string) {
+	t.Helper()
+	var event, data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event: %v", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "" && data != "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// post sends a JSON-RPC body to url with an optional session
+func post(t *testing.T, url, session, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if session != "" {
+		req.Header.Set(HeaderSessionID, session)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	return resp
+}
+
+// newAskServer creates a server whose ask tool returns the client's roots
+func newAskServer() *server.MCPServer {
+	srv := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(true))
+	srv.AddTool(mcp.NewTool("ask"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		requester := server.ClientSessionFromContext(ctx).(Requester)
+		raw, err := requester.Request(ctx, "roots/list", nil)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText(string(raw)), nil
+	})
+	return srv
+}
+
+const initializeRequest = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{"roots":{}},"clientInfo":{"name":"test","version":"1"}}}`
+
+func TestStreamableHTTPSession(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewStreamableHandler(newAskServer(), HTTPOptions{BasePath: "/fs"}, logger)
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+	defer handler.Close()
+	endpoint := ts.URL + "/fs/mcp"
+
+	resp := post(t, endpoint, "", `{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("a request without a session should be refused, got %d", resp.StatusCode)
+	}
+
+	resp = post(t, endpoint, "", initializeRequest)
+	resp.Body.Close()
+	session := resp.Header.Get(HeaderSessionID)
+	if resp.StatusCode != http.StatusOK || session == "" {
+		t.Fatalf("initialize should start a session, got %d %q", resp.StatusCode, session)
+	}
+
+	// Server requests travel on the event stream opened with GET
+	req, _ := http.NewRequest(http.MethodGet, endpoint, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(HeaderSessionID, session)
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil || stream.StatusCode != http.StatusOK {
+		t.Fatalf("open event stream: %v %v", err, stream)
+	}
+	defer stream.Body.Close()
+
+	result := make(chan string, 1)
+	go func() {
+		resp, err := http.DefaultClient.Do(func() *http.Request {
+			req, _ := http.NewRequest(http.MethodPost, endpoint,
+				strings.NewReader(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"ask"}}`))
+			req.Header.Set(HeaderSessionID, session)
+			return req
+		}())
+		if err != nil {
+			result <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		result <- string(body)
+	}()
+
+	_, data := readEvent(t, bufio.NewReader(stream.Body))
+	var request map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &request); err != nil || request["method"] != "roots/list" {
+		t.Fatalf("expected roots/list on the event stream, got %s", data)
+	}
+	id, _ := json.Marshal(request["id"])
+	resp = post(t, endpoint, session, `{"jsonrpc":"2.0","id":`+string(id)+`,"result":{"roots":[]}}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("a posted response should be accepted, got %d", resp.StatusCode)
+	}
+
+	select {
+	case body := <-result:
+		if !strings.Contains(body, `"id":2`) || !strings.Contains(body, "roots") {
+			t.Fatalf("tool call should return the client's roots, got %s", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("tool call did not complete")
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, endpoint, nil)
+	req.Header.Set(HeaderSessionID, session)
+	if resp, err = http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete session: %v %v", err, resp)
+	}
+	resp.Body.Close()
+	resp = post(t, endpoint, session, `{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("a deleted session should be unknown, got %d", resp.StatusCode)
+	}
+}
+
+func TestStreamableHTTPBatchesNeedProtocolVersion(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	batch := `[{"jsonrpc":"2.0","id":2,"method":"ping"},{"jsonrpc":"2.0","id":3,"method":"ping"}]`
+	for _, version := range []string{"2024-11-05", "2025-03-26"} {
+		hooks := &server.Hooks{}
+		hooks.AddAfterInitialize(func(ctx context.Context, id any, req *mcp.InitializeRequest, result *mcp.InitializeResult) {
+			result.ProtocolVersion = version
+		})
+		srv := server.NewMCPServer("test", "1.0.0", server.WithHooks(hooks))
+		handler := NewStreamableHandler(srv, HTTPOptions{}, logger)
+		ts := httptest.NewServer(handler)
+
+		resp := post(t, ts.URL+"/mcp", "", initializeRequest)
+		resp.Body.Close()
+		session := resp.Header.Get(HeaderSessionID)
+
+		resp = post(t, ts.URL+"/mcp", session, batch)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if version == "2024-11-05" && (resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "batches require")) {
+			t.Errorf("a %s session should not batch, got %d %s", version, resp.StatusCode, body)
+		}
+		if version == "2025-03-26" && (resp.StatusCode != http.StatusOK || !strings.HasPrefix(string(body), "[")) {
+			t.Errorf("a %s session should batch, got %d %s", version, resp.StatusCode, body)
+		}
+
+		handler.Close()
+		ts.Close()
+	}
+}
+
+func TestSSEHandlerRespondsOnStream(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewSSEHandler(newAskServer(), HTTPOptions{KeepAlive: 10 * time.Millisecond}, logger)
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+	defer handler.Close()
+
+	stream, err := http.Get(ts.URL + "/sse")
+	if err != nil {
+		t.Fatalf("open event stream: %v", err)
+	}
+	defer stream.Body.Close()
+	events := bufio.NewReader(stream.Body)
+
+	event, endpoint := readEvent(t, events)
+	if event != "endpoint" || !strings.HasPrefix(endpoint, "/message?sessionId=") {
+		t.Fatalf("expected the message endpoint first, got %s %q", event, endpoint)
+	}
+
+	resp := post(t, ts.URL+endpoint, "", initializeRequest)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("posted messages should be accepted, got %d", resp.StatusCode)
+	}
+
+	// Keep-alive comments may arrive before the response
+	event, data := readEvent(t, events)
+	if event != "message" || !strings.Contains(data, `"id":1`) || !strings.Contains(data, "serverInfo") {
+		t.Fatalf("expected the initialize result on the stream, got %s %s", event, data)
+	}
+}
+
+func TestHTTPRefusesForeignOrigins(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewStreamableHandler(newAskServer(), HTTPOptions{
+		AllowedOrigins: []string{"https://editor.example"},
+	}, logger)
+	defer handler.Close()
+
+	tests := []struct {
+		origin string
+		status int
+	}{
+		{"", http.StatusOK},
+		{"http://localhost:3000", http.StatusOK},
+		{"http://127.0.0.1", http.StatusOK},
+		{"https://editor.example", http.StatusOK},
+		{"http://attacker.example", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(initializeRequest))
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("origin %q: expected %d, got %d", tt.origin, tt.status, rec.Code)
+		}
+	}
+}
+
+func TestUnixListenerChecksPeerUID(t *testing.T) {
+	if !peercred.Supported {
+		t.Skip("peer credentials are not available on this platform")
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+
+	serve := func(name string, allowed []int) string {
+		path := filepath.Join(dir, name)
+		l, err := ListenUnix(newAskServer(), UnixOptions{Path: path, Mode: 0600, AllowedUIDs: allowed}, logger)
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- l.Serve(ctx) }()
+		t.Cleanup(func() {
+			cancel()
+			<-done
+			l.Close()
+		})
+		return path
+	}
+
+	path := serve("own.sock", nil)
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("socket should have mode 0600: %v %v", info, err)
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, initializeRequest+"\n")
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil || !strings.Contains(string(line), `"protocolVersion"`) {
+		t.Fatalf("the server's own user should be served: %q %v", line, err)
+	}
+
+	// A socket that only admits another user closes the connection unserved
+	path = serve("other.sock", []int{os.Getuid() + 1})
+	conn, err = net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, initializeRequest+"\n")
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err = bufio.NewReader(conn).ReadBytes('\n')
+	if err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("a user not allowed should be refused: %q %v", line, err)
+	}
+}
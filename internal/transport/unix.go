@@ -0,0 +1,214 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"filesystem/pkg/peercred"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxUnixConnections bounds the clients served at once per Rule 2
+const maxUnixConnections = 64
+
+// UnixOptions configures the Unix socket transport
+type UnixOptions struct {
+	// Path of the socket file
+	Path string
+
+	// Mode is the permission of the socket file
+	Mode os.FileMode
+
+	// AllowedUIDs are the users that may connect; empty allows only the
+	// user running the server
+	AllowedUIDs []int
+
+	// Methods handle client requests in place of the MCP server
+	Methods Methods
+}
+
+// UnixListener serves MCP clients that connect to a Unix socket, one
+// session per connection speaking newline-delimited JSON-RPC. Each peer's
+// user is checked against an allow-list before it is served.
+type UnixListener struct {
+	server   *server.MCPServer
+	listener *net.UnixListener
+	path     string
+	allowed  map[int]bool
+	methods  Methods
+	logger   *slog.Logger
+
+	nextID atomic.Int64
+	wg     sync.WaitGroup
+}
+
+// ListenUnix creates the socket and restricts its permissions. A socket
+// left behind by a previous process is replaced.
+func ListenUnix(srv *server.MCPServer, options UnixOptions, logger *slog.Logger) (*UnixListener, error) {
+	// Input validation per Rule 7
+	if srv == nil {
+		return nil, fmt.Errorf("server is required")
+	}
+	if options.Path == "" {
+		return nil, fmt.Errorf("socket path is required")
+	}
+	if !peercred.Supported {
+		return nil, fmt.Errorf("the unix transport checks peer credentials, which are only available on Linux")
+	}
+
+	if err := removeStaleSocket(options.Path); err != nil {
+		return nil, err
+	}
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: options.Path, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", options.Path, err)
+	}
+	// Close removes the socket file, which Landlock may forbid by then
+	listener.SetUnlinkOnClose(false)
+	if err := os.Chmod(options.Path, options.Mode); err != nil {
+		listener.Close()
+		os.Remove(options.Path)
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+
+	allowed := map[int]bool{os.Getuid(): true}
+	if len(options.AllowedUIDs) > 0 {
+		allowed = make(map[int]bool, len(options.AllowedUIDs))
+		for _, uid := range options.AllowedUIDs {
+			allowed[uid] = true
+		}
+	}
+
+	logger.Info("Unix socket transport listening",
+		"path", options.Path,
+		"mode", fmt.Sprintf("%04o", options.Mode),
+		"allowed_uids", options.AllowedUIDs)
+	return &UnixListener{
+		server:   srv,
+		listener: listener,
+		path:     options.Path,
+		allowed:  allowed,
+		methods:  options.Methods,
+		logger:   logger,
+	}, nil
+}
+
+// Path returns the socket path
+func (l *UnixListener) Path() string {
+	return l.path
+}
+
+// Serve accepts clients until ctx is cancelled or the listener is closed,
+// then waits for their sessions to end
+func (l *UnixListener) Serve(ctx context.Context) error {
+	// Input validation per Rule 7
+	if ctx == nil {
+		return fmt.Errorf("context is required")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		l.listener.Close()
+	}()
+	defer l.wg.Wait()
+
+	slots := make(chan struct{}, maxUnixConnections)
+	for {
+		conn, err := l.listener.AcceptUnix()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			l.logger.Warn("Refusing Unix socket client; too many connections", "limit", maxUnixConnections)
+			conn.Close()
+			continue
+		}
+		l.wg.Add(1)
+		go func() {
+			defer l.wg.Done()
+			defer func() { <-slots }()
+			l.handle(ctx, conn)
+		}()
+	}
+}
+
+// Close stops accepting clients and removes the socket
+func (l *UnixListener) Close() error {
+	err := l.listener.Close()
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		return fmt.Errorf("failed to close unix socket: %w", err)
+	}
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		l.logger.Debug("Failed to remove unix socket", "path", l.path, "error", err)
+	}
+	return nil
+}
+
+// handle checks the peer of conn and serves it as one session
+func (l *UnixListener) handle(ctx context.Context, conn *net.UnixConn) {
+	defer conn.Close()
+
+	peer, err := peercred.Of(conn)
+	if err != nil {
+		l.logger.Warn("Refusing Unix socket client", "error", err)
+		return
+	}
+	if !l.allowed[peer.UID] {
+		l.logger.Warn("Refusing Unix socket client; user not allowed",
+			"uid", peer.UID,
+			"gid", peer.GID,
+			"pid", peer.PID)
+		return
+	}
+
+	// The user is part of the session ID so audit records name it
+	id := fmt.Sprintf("unix-uid%d-%d", peer.UID, l.nextID.Add(1))
+	logger := l.logger.With("session", id, "uid", peer.UID, "gid", peer.GID, "pid", peer.PID)
+	logger.Info("Unix socket client connected")
+	start := time.Now()
+
+	stream := NewStreamWithOptions(id, l.server, conn, StreamOptions{Methods: l.methods}, logger)
+	if err := stream.Serve(ctx, conn); err != nil && ctx.Err() == nil {
+		logger.Warn("Unix socket session failed", "error", err)
+	}
+	logger.Info("Unix socket client disconnected", "duration_ms", time.Since(start).Milliseconds())
+}
+
+// removeStaleSocket deletes a socket at path that no process is serving,
+// refusing to touch anything else
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to inspect socket path: %w", err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("socket path %s exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("socket %s is in use by another process", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	return nil
+}
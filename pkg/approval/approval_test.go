@@ -0,0 +1,183 @@
+package approval
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"filesystem/pkg/peercred"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func newQueue(opts Options) *Queue {
+	return NewQueue(opts, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func toolRequest(name string, args map[string]interface{}) mcp.CallToolRequest {
+	var req mcp.CallToolRequest
+	req.Params.Name = name
+	req.Params.Arguments = args
+	return req
+}
+
+// countingHandler returns a handler that records how often it ran
+func countingHandler(calls *int) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		*calls++
+		return mcp.NewToolResultText("done " + req.Params.Name), nil
+	}
+}
+
+func TestRequires(t *testing.T) {
+	q := newQueue(Options{})
+	if !q.Requires(toolRequest("write_file", nil)) {
+		t.Fatalf("write_file should require approval")
+	}
+	if !q.Requires(toolRequest("edit_file", map[string]interface{}{"dryRun": false})) {
+		t.Fatalf("edit_file without dry run should require approval")
+	}
+	if q.Requires(toolRequest("edit_file", map[string]interface{}{"dryRun": true})) {
+		t.Fatalf("dry run should not require approval")
+	}
+	if q.Requires(toolRequest("read_file", nil)) {
+		t.Fatalf("read_file should not require approval")
+	}
+	var disabled *Queue
+	if disabled.Requires(toolRequest("write_file", nil)) {
+		t.Fatalf("nil queue should not require approval")
+	}
+}
+
+func TestDeferApproveAndDeny(t *testing.T) {
+	q := newQueue(Options{})
+	calls := 0
+	handler := q.Defer(countingHandler(&calls))
+
+	res, err := handler(context.Background(), toolRequest("write_file", map[string]interface{}{"path": "/tmp/a"}))
+	if err != nil || res.IsError {
+		t.Fatalf("defer failed: %v %+v", err, res)
+	}
+	if calls != 0 {
+		t.Fatalf("handler ran before approval")
+	}
+	if text := res.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "Pending approval") {
+		t.Fatalf("unexpected result %q", text)
+	}
+
+	ops := q.List()
+	if len(ops) != 1 || ops[0].Tool != "write_file" {
+		t.Fatalf("unexpected pending operations: %+v", ops)
+	}
+	if _, err := q.Approve(context.Background(), ops[0].ID); err != nil || calls != 1 {
+		t.Fatalf("approve: %v calls=%d", err, calls)
+	}
+	if _, err := q.Approve(context.Background(), ops[0].ID); err == nil {
+		t.Fatalf("expected second approval to fail")
+	}
+
+	handler(context.Background(), toolRequest("move_file", nil))
+	id := q.List()[0].ID
+	if err := q.Deny(id); err != nil {
+		t.Fatalf("deny: %v", err)
+	}
+	if len(q.List()) != 0 || calls != 1 {
+		t.Fatalf("denied operation should be discarded without running")
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	q := newQueue(Options{TTL: time.Minute})
+	now := time.Now()
+	q.now = func() time.Time { return now }
+
+	calls := 0
+	op, err := q.Submit(toolRequest("write_file", nil), countingHandler(&calls))
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if len(q.List()) != 0 {
+		t.Fatalf("expected operation to expire")
+	}
+	if _, err := q.Approve(context.Background(), op.ID); err == nil || calls != 0 {
+		t.Fatalf("expired operation must not run: %v calls=%d", err, calls)
+	}
+}
+
+func TestQueueLimit(t *testing.T) {
+	q := newQueue(Options{MaxPending: 1})
+	calls := 0
+	if _, err := q.Submit(toolRequest("write_file", nil), countingHandler(&calls)); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if _, err := q.Submit(toolRequest("write_file", nil), countingHandler(&calls)); err == nil {
+		t.Fatalf("expected full queue error")
+	}
+}
+
+func TestControlSocket(t *testing.T) {
+	q := newQueue(Options{})
+	socket := filepath.Join(t.TempDir(), "approval.sock")
+	control, err := Listen(socket, q, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go control.Serve(ctx)
+	defer control.Close()
+
+	if _, err := Listen(socket, q, slog.New(slog.NewTextHandler(io.Discard, nil))); err == nil {
+		t.Fatalf("expected error for socket in use")
+	}
+	if info, err := os.Lstat(socket); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("socket should be private: %v %v", info, err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(socket)); len(entries) != 1 {
+		t.Fatalf("expected only the socket beside it, got %v", entries)
+	}
+
+	calls := 0
+	op, err := q.Submit(toolRequest("write_file", map[string]interface{}{"path": "/tmp/a"}), countingHandler(&calls))
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+
+	resp, err := Send(socket, Request{Action: ActionList}, 5*time.Second)
+	if err != nil || !resp.OK || len(resp.Operations) != 1 || resp.Operations[0].ID != op.ID {
+		t.Fatalf("list: %v %+v", err, resp)
+	}
+
+	resp, err = Send(socket, Request{Action: ActionApprove, ID: op.ID}, 5*time.Second)
+	if err != nil || !resp.OK || resp.Result != "done write_file" || calls != 1 {
+		t.Fatalf("approve: %v %+v calls=%d", err, resp, calls)
+	}
+
+	resp, err = Send(socket, Request{Action: ActionDeny, ID: op.ID}, 5*time.Second)
+	if err != nil || resp.OK {
+		t.Fatalf("expected deny of unknown id to fail: %v %+v", err, resp)
+	}
+
+	// Clients running as another user are hung up on
+	if !peercred.Supported {
+		return
+	}
+	other := filepath.Join(t.TempDir(), "other.sock")
+	strict, err := Listen(other, q, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	strict.uid = os.Getuid() + 1
+	go strict.Serve(ctx)
+	defer strict.Close()
+	if resp, err := Send(other, Request{Action: ActionList}, 5*time.Second); err == nil {
+		t.Fatalf("expected a client of another user to be refused: %+v", resp)
+	}
+}
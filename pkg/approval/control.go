@@ -0,0 +1,275 @@
+package approval
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"filesystem/pkg/peercred"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Control actions
+const (
+	ActionList    = "list"
+	ActionApprove = "approve"
+	ActionDeny    = "deny"
+)
+
+const (
+	// requestTimeout bounds how long a control client may take to send
+	// its request
+	requestTimeout = 10 * time.Second
+
+	// maxRequestSize bounds a control request line per Rule 2
+	maxRequestSize = 4096
+)
+
+// Request is a single control command, sent as one JSON line
+type Request struct {
+	Action string `json:"action"`
+	ID     string `json:"id,omitempty"`
+}
+
+// Response answers a control Request
+type Response struct {
+	OK         bool        `json:"ok"`
+	Error      string      `json:"error,omitempty"`
+	Operations []Operation `json:"operations,omitempty"`
+	Result     string      `json:"result,omitempty"`
+	IsError    bool        `json:"is_error,omitempty"`
+}
+
+// ControlServer accepts approval commands on a Unix socket that only the
+// owning user can connect to
+type ControlServer struct {
+	queue    *Queue
+	listener *net.UnixListener
+	path     string
+	uid      int
+	logger   *slog.Logger
+}
+
+// Listen creates the control socket at socketPath. A stale socket left by
+// a previous process is replaced; one that is still in use is an error.
+// The socket is created with mode 0600 inside a private directory and only
+// then moved to socketPath, so no other user can connect in between.
+func Listen(socketPath string, queue *Queue, logger *slog.Logger) (*ControlServer, error) {
+	// Input validation per Rule 7
+	if socketPath == "" {
+		return nil, fmt.Errorf("socket path is required")
+	}
+	if queue == nil {
+		return nil, fmt.Errorf("queue is required")
+	}
+
+	if err := removeStaleSocket(socketPath); err != nil {
+		return nil, err
+	}
+
+	listener, err := listenPrivate(socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Approval control socket listening", "path", socketPath)
+	return &ControlServer{queue: queue, listener: listener, path: socketPath, uid: os.Getuid(), logger: logger}, nil
+}
+
+// listenPrivate listens on a socket created in a new 0700 directory beside
+// socketPath, restricts it to 0600 and renames it to socketPath
+func listenPrivate(socketPath string) (*net.UnixListener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(socketPath), ".approval-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create approval socket directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	private := filepath.Join(dir, "sock")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: private, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on approval socket: %w", err)
+	}
+	// The socket is renamed below, so Close removes it by its final name
+	listener.SetUnlinkOnClose(false)
+	if err := os.Chmod(private, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict approval socket: %w", err)
+	}
+	if err := os.Rename(private, socketPath); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to move approval socket into place: %w", err)
+	}
+	return listener, nil
+}
+
+// Serve handles control connections until the listener is closed or ctx
+// is cancelled. Approved operations run with ctx.
+func (c *ControlServer) Serve(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		c.listener.Close()
+	}()
+
+	for {
+		conn, err := c.listener.AcceptUnix()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("failed to accept control connection: %w", err)
+		}
+		go c.handle(ctx, conn)
+	}
+}
+
+// Close stops accepting commands and removes the socket
+func (c *ControlServer) Close() error {
+	err := c.listener.Close()
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		return fmt.Errorf("failed to close approval socket: %w", err)
+	}
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		c.logger.Debug("Failed to remove approval socket", "path", c.path, "error", err)
+	}
+	return nil
+}
+
+// handle answers one request on conn
+func (c *ControlServer) handle(ctx context.Context, conn *net.UnixConn) {
+	defer conn.Close()
+
+	if !c.permits(conn) {
+		return
+	}
+
+	conn.SetReadDeadline(time.Now().Add(requestTimeout))
+	line, err := bufio.NewReaderSize(conn, maxRequestSize).ReadSlice('\n')
+	if err != nil {
+		c.logger.Debug("Failed to read control request", "error", err)
+		return
+	}
+
+	var req Request
+	resp := Response{}
+	if err := json.Unmarshal(line, &req); err != nil {
+		resp.Error = fmt.Sprintf("invalid request: %v", err)
+	} else {
+		resp = c.execute(ctx, req)
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(requestTimeout))
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		c.logger.Debug("Failed to write control response", "error", err)
+	}
+}
+
+// permits reports whether the peer of conn runs as the server's user.
+// Where peer credentials are unavailable the socket's mode is the only
+// check.
+func (c *ControlServer) permits(conn *net.UnixConn) bool {
+	if !peercred.Supported {
+		return true
+	}
+	peer, err := peercred.Of(conn)
+	if err != nil {
+		c.logger.Warn("Refusing approval control client", "error", err)
+		return false
+	}
+	if peer.UID != c.uid {
+		c.logger.Warn("Refusing approval control client; user not allowed",
+			"uid", peer.UID,
+			"gid", peer.GID,
+			"pid", peer.PID)
+		return false
+	}
+	return true
+}
+
+// execute performs a control request against the queue
+func (c *ControlServer) execute(ctx context.Context, req Request) Response {
+	switch req.Action {
+	case ActionList:
+		return Response{OK: true, Operations: c.queue.List()}
+	case ActionApprove:
+		result, err := c.queue.Approve(ctx, req.ID)
+		if err != nil {
+			return Response{Error: err.Error()}
+		}
+		text, isError := resultText(result)
+		return Response{OK: true, Result: text, IsError: isError}
+	case ActionDeny:
+		if err := c.queue.Deny(req.ID); err != nil {
+			return Response{Error: err.Error()}
+		}
+		return Response{OK: true}
+	default:
+		return Response{Error: fmt.Sprintf("unknown action: %s", req.Action)}
+	}
+}
+
+// Send connects to the control socket, sends req and returns the response
+func Send(socketPath string, req Request, timeout time.Duration) (*Response, error) {
+	conn, err := net.DialTimeout("unix", socketPath, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to approval socket: %w", err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return &resp, nil
+}
+
+// removeStaleSocket deletes a socket at path that no process is serving
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to inspect approval socket: %w", err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("approval socket path %s exists and is not a socket", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("approval socket %s is in use by another process", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale approval socket: %w", err)
+	}
+	return nil
+}
+
+// resultText joins the text content of a tool result
+func resultText(result *mcp.CallToolResult) (string, bool) {
+	if result == nil {
+		return "", false
+	}
+	parts := make([]string, 0, len(result.Content))
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n"), result.IsError
+}
@@ -0,0 +1,243 @@
+package approval
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"filesystem/pkg/toolresult"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// DefaultTTL is how long an operation waits for a decision
+	DefaultTTL = 10 * time.Minute
+
+	// DefaultMaxPending bounds the queue per Rule 2
+	DefaultMaxPending = 100
+)
+
+// DefaultTools are the tools held for approval when none are configured
+var DefaultTools = []string{"write_file", "edit_file", "move_file"}
+
+// Options configures a Queue
+type Options struct {
+	// Tools are the names of the tools that require approval; nil uses
+	// DefaultTools
+	Tools []string
+
+	// TTL is how long a pending operation stays approvable; zero uses
+	// DefaultTTL
+	TTL time.Duration
+
+	// MaxPending is the largest number of operations held at once; zero
+	// uses DefaultMaxPending
+	MaxPending int
+}
+
+// Operation describes a tool call waiting for a decision
+type Operation struct {
+	ID        string                 `json:"id"`
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Created   time.Time              `json:"created"`
+	Expires   time.Time              `json:"expires"`
+}
+
+// pending is a held call together with the handler that performs it
+type pending struct {
+	op  Operation
+	req mcp.CallToolRequest
+	run server.ToolHandlerFunc
+}
+
+// Queue holds destructive tool calls until a human approves or denies them
+// through the control socket. Expired operations are discarded.
+type Queue struct {
+	mu         sync.Mutex
+	ops        map[string]*pending
+	tools      map[string]bool
+	ttl        time.Duration
+	maxPending int
+	logger     *slog.Logger
+
+	// now is replaceable in tests
+	now func() time.Time
+}
+
+// NewQueue creates an empty approval queue
+func NewQueue(opts Options, logger *slog.Logger) *Queue {
+	tools := opts.Tools
+	if tools == nil {
+		tools = DefaultTools
+	}
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	maxPending := opts.MaxPending
+	if maxPending <= 0 {
+		maxPending = DefaultMaxPending
+	}
+
+	byName := make(map[string]bool, len(tools))
+	for _, name := range tools {
+		byName[name] = true
+	}
+
+	return &Queue{
+		ops:        make(map[string]*pending),
+		tools:      byName,
+		ttl:        ttl,
+		maxPending: maxPending,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Requires reports whether a call must wait for approval. Calls that ask
+// for a dry run only preview changes and are never held. A nil queue
+// requires nothing.
+func (q *Queue) Requires(req mcp.CallToolRequest) bool {
+	if q == nil || !q.tools[req.Params.Name] {
+		return false
+	}
+	if dryRun, ok := req.Params.Arguments["dryRun"].(bool); ok && dryRun {
+		return false
+	}
+	return true
+}
+
+// Defer returns a handler that queues the call instead of performing it.
+// run performs the call once it is approved.
+func (q *Queue) Defer(run server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		op, err := q.Submit(req, run)
+		if err != nil {
+			return toolresult.Errorf(toolresult.CodeUnavailable, "Error: %v", err), nil
+		}
+		return toolresult.Text(fmt.Sprintf(
+			"Pending approval: operation %s (%s) was queued and will run once a human approves it. It expires at %s.",
+			op.ID, op.Tool, op.Expires.Format(time.RFC3339)), map[string]interface{}{
+			"pending": map[string]interface{}{
+				"id":      op.ID,
+				"tool":    op.Tool,
+				"expires": op.Expires,
+			},
+		}), nil
+	}
+}
+
+// Submit adds a call to the queue and returns the pending operation
+func (q *Queue) Submit(req mcp.CallToolRequest, run server.ToolHandlerFunc) (Operation, error) {
+	// Input validation per Rule 7
+	if run == nil {
+		return Operation{}, fmt.Errorf("handler is required")
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Operation{}, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expireLocked()
+
+	if len(q.ops) >= q.maxPending {
+		return Operation{}, fmt.Errorf("approval queue is full (%d pending operations)", q.maxPending)
+	}
+
+	now := q.now()
+	op := Operation{
+		ID:        id,
+		Tool:      req.Params.Name,
+		Arguments: req.Params.Arguments,
+		Created:   now,
+		Expires:   now.Add(q.ttl),
+	}
+	q.ops[id] = &pending{op: op, req: req, run: run}
+
+	q.logger.Info("Operation queued for approval", "id", id, "tool", op.Tool, "expires", op.Expires)
+	return op, nil
+}
+
+// List returns the pending operations, oldest first
+func (q *Queue) List() []Operation {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expireLocked()
+
+	ops := make([]Operation, 0, len(q.ops))
+	for _, p := range q.ops {
+		ops = append(ops, p.op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].Created.Before(ops[j].Created)
+	})
+	return ops
+}
+
+// Approve removes an operation from the queue and performs it, returning
+// the tool result
+func (q *Queue) Approve(ctx context.Context, id string) (*mcp.CallToolResult, error) {
+	p, err := q.take(id)
+	if err != nil {
+		return nil, err
+	}
+
+	q.logger.Info("Operation approved", "id", id, "tool", p.op.Tool)
+	return p.run(ctx, p.req)
+}
+
+// Deny removes an operation from the queue without performing it
+func (q *Queue) Deny(id string) error {
+	p, err := q.take(id)
+	if err != nil {
+		return err
+	}
+
+	q.logger.Info("Operation denied", "id", id, "tool", p.op.Tool)
+	return nil
+}
+
+// take removes and returns an unexpired operation
+func (q *Queue) take(id string) (*pending, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expireLocked()
+
+	p, ok := q.ops[id]
+	if !ok {
+		return nil, fmt.Errorf("no pending operation %s", id)
+	}
+	delete(q.ops, id)
+	return p, nil
+}
+
+// expireLocked discards operations past their expiry. q.mu must be held.
+func (q *Queue) expireLocked() {
+	now := q.now()
+	for id, p := range q.ops {
+		if now.After(p.op.Expires) {
+			delete(q.ops, id)
+			q.logger.Info("Pending operation expired", "id", id, "tool", p.op.Tool)
+		}
+	}
+}
+
+// newID returns a random operation identifier
+func newID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate operation id: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
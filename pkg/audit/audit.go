@@ -0,0 +1,289 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxSize is the file size in bytes that triggers rotation
+	DefaultMaxSize = 10 * 1024 * 1024
+
+	// DefaultMaxBackups is the number of rotated files kept
+	DefaultMaxBackups = 5
+
+	// maxRecordSize bounds a single line when reading existing logs per Rule 2
+	maxRecordSize = 1024 * 1024
+
+	// maxBackupsLimit bounds the rotation loop per Rule 2
+	maxBackupsLimit = 1000
+)
+
+// Outcome values recorded for each call
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// Record is a single audit log line describing one tool call
+type Record struct {
+	Timestamp    time.Time       `json:"timestamp"`
+	Session      string          `json:"session,omitempty"`
+	Principal    string          `json:"principal,omitempty"`
+	Tool         string          `json:"tool"`
+	Arguments    json.RawMessage `json:"arguments,omitempty"`
+	Paths        []string        `json:"paths,omitempty"`
+	PolicyRules  []string        `json:"policy_rules,omitempty"`
+	Outcome      string          `json:"outcome"`
+	Error        string          `json:"error,omitempty"`
+	ErrorCode    string          `json:"error_code,omitempty"`
+	BytesRead    int64           `json:"bytes_read"`
+	BytesWritten int64           `json:"bytes_written"`
+	DurationMs   float64         `json:"duration_ms"`
+	PrevHash     string          `json:"prev_hash"`
+	Hash         string          `json:"hash,omitempty"`
+}
+
+// Options configures an audit Logger
+type Options struct {
+	// Path of the active log file
+	Path string
+
+	// MaxSize in bytes before the file is rotated; zero uses DefaultMaxSize
+	MaxSize int64
+
+	// MaxBackups is the number of rotated files kept; zero uses DefaultMaxBackups
+	MaxBackups int
+}
+
+// Logger appends hash-chained records to a JSONL file
+type Logger struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+	prevHash   string
+}
+
+// Open opens or creates the audit log, continuing the hash chain of any
+// existing records
+func Open(opts Options) (*Logger, error) {
+	// Input validation per Rule 7
+	if opts.Path == "" {
+		return nil, fmt.Errorf("audit log path is required")
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+	if opts.MaxBackups <= 0 {
+		opts.MaxBackups = DefaultMaxBackups
+	}
+	if opts.MaxBackups > maxBackupsLimit {
+		return nil, fmt.Errorf("audit log max backups exceeds %d", maxBackupsLimit)
+	}
+
+	l := &Logger{
+		path:       filepath.Clean(opts.Path),
+		maxSize:    opts.MaxSize,
+		maxBackups: opts.MaxBackups,
+	}
+
+	// Continue the chain from the active file, or from the newest backup
+	// when the active file is empty
+	prevHash, err := lastHash(l.path)
+	if err != nil {
+		return nil, err
+	}
+	if prevHash == "" {
+		prevHash, err = lastHash(l.backupPath(1))
+		if err != nil {
+			return nil, err
+		}
+	}
+	l.prevHash = prevHash
+
+	if err := l.openFile(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Write completes the record's hash chain fields and appends it to the log
+func (l *Logger) Write(rec Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return fmt.Errorf("audit log is closed")
+	}
+
+	rec.PrevHash = l.prevHash
+	line, hash, err := seal(rec)
+	if err != nil {
+		return err
+	}
+
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	l.prevHash = hash
+	return nil
+}
+
+// Close flushes and closes the active log file
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// openFile opens the active log for appending and records its size
+func (l *Logger) openFile() error {
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// rotate shifts backups up by one, moves the active file to the first
+// backup and starts a new file. The chain carries over unchanged.
+func (l *Logger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+
+	for i := l.maxBackups - 1; i >= 1; i-- {
+		err := os.Rename(l.backupPath(i), l.backupPath(i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(l.path, l.backupPath(1)); err != nil {
+		return err
+	}
+	return l.openFile()
+}
+
+// backupPath returns the name of the n-th rotated file
+func (l *Logger) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", l.path, n)
+}
+
+// seal computes the record hash over its JSON encoding without the hash
+// field and returns the complete line to append
+func seal(rec Record) ([]byte, string, error) {
+	rec.Hash = ""
+	body, err := json.Marshal(rec)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	rec.Hash = hex.EncodeToString(sum[:])
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	return append(line, '\n'), rec.Hash, nil
+}
+
+// lastHash returns the hash of the final record in a log file, or an empty
+// string if the file does not exist or holds no records
+func lastHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	last := ""
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return "", fmt.Errorf("corrupt audit log %s: %w", path, err)
+		}
+		last = rec.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return last, nil
+}
+
+// Verify checks the hash chain of the records read from r. prevHash is the
+// hash the first record is expected to link to, or empty for the first file.
+// It returns the hash of the last record so rotated files can be checked in
+// order.
+func Verify(r io.Reader, prevHash string) (string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return "", fmt.Errorf("line %d: invalid record: %w", line, err)
+		}
+		if rec.PrevHash != prevHash {
+			return "", fmt.Errorf("line %d: chain broken: expected prev_hash %q", line, prevHash)
+		}
+		_, hash, err := seal(rec)
+		if err != nil {
+			return "", fmt.Errorf("line %d: %w", line, err)
+		}
+		if hash != rec.Hash {
+			return "", fmt.Errorf("line %d: record hash mismatch", line)
+		}
+		prevHash = rec.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return prevHash, nil
+}
@@ -0,0 +1,179 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"filesystem/pkg/toolresult"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func newLogger(t *testing.T, opts Options) *Logger {
+	t.Helper()
+	l, err := Open(opts)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func writeRecords(t *testing.T, l *Logger, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		rec := Record{Timestamp: time.Now().UTC(), Tool: "read_file", Outcome: OutcomeSuccess}
+		if err := l.Write(rec); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+}
+
+func TestHashChainVerifies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := newLogger(t, Options{Path: path})
+	writeRecords(t, l, 3)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if _, err := Verify(bytes.NewReader(data), ""); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// Tampering with any record breaks the chain
+	tampered := strings.Replace(string(data), "read_file", "write_file", 1)
+	if _, err := Verify(strings.NewReader(tampered), ""); err == nil {
+		t.Fatalf("expected tampered log to fail verification")
+	}
+
+	// Dropping a record breaks the chain
+	lines := strings.SplitN(string(data), "\n", 2)
+	if _, err := Verify(strings.NewReader(lines[1]), ""); err == nil {
+		t.Fatalf("expected truncated log to fail verification")
+	}
+}
+
+func TestChainContinuesAcrossReopenAndRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := newLogger(t, Options{Path: path, MaxSize: 600, MaxBackups: 10})
+	writeRecords(t, l, 2)
+	l.Close()
+
+	l = newLogger(t, Options{Path: path, MaxSize: 600, MaxBackups: 10})
+	writeRecords(t, l, 4)
+	l.Close()
+
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Fatalf("expected rotated file: %v", err)
+	}
+
+	// Verify from the oldest backup to the active file
+	files := []string{}
+	for i := 10; i >= 1; i-- {
+		if _, err := os.Stat(l.backupPath(i)); err == nil {
+			files = append(files, l.backupPath(i))
+		}
+	}
+	files = append(files, path)
+
+	prev := ""
+	total := 0
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		total += strings.Count(string(data), "\n")
+		prev, err = Verify(bytes.NewReader(data), prev)
+		if err != nil {
+			t.Fatalf("verify %s: %v", name, err)
+		}
+	}
+	if total != 6 {
+		t.Fatalf("expected 6 records, got %d", total)
+	}
+}
+
+func TestMiddlewareRecordsCall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := newLogger(t, Options{Path: path})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	handler := l.Middleware(logger)(func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		RecordPath(ctx, "/data/out.txt")
+		RecordBytesWritten(ctx, 11)
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	var req mcp.CallToolRequest
+	req.Params.Name = "write_file"
+	req.Params.Arguments = map[string]interface{}{"path": "out.txt", "content": "secret data"}
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if strings.Contains(string(data), "secret data") {
+		t.Fatalf("content was not redacted: %s", data)
+	}
+
+	var rec Record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if rec.Tool != "write_file" || rec.Outcome != OutcomeSuccess || rec.BytesWritten != 11 {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+	if len(rec.Paths) != 1 || rec.Paths[0] != "/data/out.txt" {
+		t.Fatalf("unexpected paths: %v", rec.Paths)
+	}
+
+	var args map[string]string
+	if err := json.Unmarshal(rec.Arguments, &args); err != nil {
+		t.Fatalf("arguments: %v", err)
+	}
+	if args["path"] != "out.txt" || !strings.HasPrefix(args["content"], "[redacted 11 bytes sha256:") {
+		t.Fatalf("unexpected arguments: %v", args)
+	}
+}
+
+func TestMiddlewareRecordsErrorCode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := newLogger(t, Options{Path: path})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	handler := l.Middleware(logger)(func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return toolresult.Errorf(toolresult.CodeNotFound, "Error: no such file"), nil
+	})
+
+	var req mcp.CallToolRequest
+	req.Params.Name = "read_file"
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	var rec Record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if rec.Outcome != OutcomeError || rec.Error != "Error: no such file" || rec.ErrorCode != toolresult.CodeNotFound {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+}
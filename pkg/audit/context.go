@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+// maxRecordedPaths bounds the paths kept per call per Rule 2
+const maxRecordedPaths = 256
+
+// entryKey is the context key for the in-flight call entry
+type entryKey struct{}
+
+// entry collects details that only the handlers know about a call
+type entry struct {
+	mu           sync.Mutex
+	paths        []string
+	policyRules  []string
+	bytesRead    int64
+	bytesWritten int64
+}
+
+// withEntry returns a context carrying a fresh entry
+func withEntry(ctx context.Context) (context.Context, *entry) {
+	e := &entry{}
+	return context.WithValue(ctx, entryKey{}, e), e
+}
+
+// entryFrom returns the entry stored in ctx, if any
+func entryFrom(ctx context.Context) *entry {
+	e, _ := ctx.Value(entryKey{}).(*entry)
+	return e
+}
+
+// RecordPath notes a resolved real path touched by the current call.
+// It is a no-op when auditing is disabled.
+func RecordPath(ctx context.Context, path string) {
+	e := entryFrom(ctx)
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.paths) < maxRecordedPaths {
+		e.paths = append(e.paths, path)
+	}
+}
+
+// RecordPolicyRule notes a policy rule that matched the current call
+func RecordPolicyRule(ctx context.Context, rule string) {
+	e := entryFrom(ctx)
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.policyRules) < maxRecordedPaths {
+		e.policyRules = append(e.policyRules, rule)
+	}
+}
+
+// RecordBytesRead adds to the number of bytes read by the current call
+func RecordBytesRead(ctx context.Context, n int64) {
+	e := entryFrom(ctx)
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.bytesRead += n
+}
+
+// RecordBytesWritten adds to the number of bytes written by the current call
+func RecordBytesWritten(ctx context.Context, n int64) {
+	e := entryFrom(ctx)
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.bytesWritten += n
+}
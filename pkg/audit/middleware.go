@@ -0,0 +1,159 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"filesystem/pkg/toolresult"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxArgumentLength is the longest string argument stored verbatim
+const maxArgumentLength = 256
+
+// maxRedactDepth bounds argument traversal per Rule 2
+const maxRedactDepth = 8
+
+// redactedFields are always hashed regardless of length since they carry
+// file contents
+var redactedFields = map[string]bool{
+	"content": true,
+	"oldText": true,
+	"newText": true,
+}
+
+// Middleware returns a tool handler middleware that writes one record per
+// call. Failures to write are logged and do not fail the call.
+func (l *Logger) Middleware(logger *slog.Logger) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			start := time.Now()
+			ctx, e := withEntry(ctx)
+
+			result, err := next(ctx, req)
+
+			rec := l.newRecord(ctx, req.Params.Name, req.Params.Arguments, e, start)
+			rec.Outcome, rec.Error, rec.ErrorCode = outcome(result, err)
+			if werr := l.Write(rec); werr != nil {
+				logger.Error("Failed to write audit record", "tool", rec.Tool, "error", werr)
+			}
+			return result, err
+		}
+	}
+}
+
+// ResourceMiddleware returns the counterpart of Middleware for resource
+// reads, recorded as the method resources/read with the URI as argument
+func (l *Logger) ResourceMiddleware(logger *slog.Logger) func(server.ResourceHandlerFunc) server.ResourceHandlerFunc {
+	return func(next server.ResourceHandlerFunc) server.ResourceHandlerFunc {
+		return func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			start := time.Now()
+			ctx, e := withEntry(ctx)
+
+			contents, err := next(ctx, req)
+
+			rec := l.newRecord(ctx, string(mcp.MethodResourcesRead), map[string]interface{}{"uri": req.Params.URI}, e, start)
+			rec.Outcome = OutcomeSuccess
+			if err != nil {
+				rec.Outcome, rec.Error, rec.ErrorCode = OutcomeError, err.Error(), toolresult.Code(err)
+			}
+			if werr := l.Write(rec); werr != nil {
+				logger.Error("Failed to write audit record", "tool", rec.Tool, "error", werr)
+			}
+			return contents, err
+		}
+	}
+}
+
+// newRecord builds the record for a finished call of tool from its
+// arguments and the details collected by the handler
+func (l *Logger) newRecord(ctx context.Context, tool string, arguments map[string]interface{}, e *entry, start time.Time) Record {
+	rec := Record{
+		Timestamp:  start.UTC(),
+		Tool:       tool,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		rec.Session = session.SessionID()
+		// Sessions of authenticated HTTP clients name their token
+		if authenticated, ok := session.(interface{ Principal() string }); ok {
+			rec.Principal = authenticated.Principal()
+		}
+	}
+	if args, err := json.Marshal(redact(arguments, "", 0)); err == nil {
+		rec.Arguments = args
+	}
+
+	e.mu.Lock()
+	rec.Paths = append([]string(nil), e.paths...)
+	rec.PolicyRules = append([]string(nil), e.policyRules...)
+	rec.BytesRead = e.bytesRead
+	rec.BytesWritten = e.bytesWritten
+	e.mu.Unlock()
+
+	return rec
+}
+
+// outcome derives the outcome, error text and error code from a handler's
+// return values. The JSON payload of a result is left out of the text.
+func outcome(result *mcp.CallToolResult, err error) (string, string, string) {
+	if err != nil {
+		return OutcomeError, err.Error(), toolresult.Code(err)
+	}
+	if result == nil || !result.IsError {
+		return OutcomeSuccess, "", ""
+	}
+	content := result.Content
+	var failure struct {
+		Error toolresult.Failure `json:"error"`
+	}
+	payload, ok := toolresult.Payload(result)
+	if ok && json.Unmarshal(payload, &failure) == nil && failure.Error.Code != "" {
+		content = content[:len(content)-1]
+	}
+	texts := make([]string, 0, len(content))
+	for _, c := range content {
+		if text, ok := c.(mcp.TextContent); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	return OutcomeError, strings.Join(texts, "\n"), failure.Error.Code
+}
+
+// redact copies the arguments, replacing file contents and long strings
+// with their length and SHA-256 digest
+func redact(value interface{}, key string, depth int) interface{} {
+	if depth > maxRedactDepth {
+		return "[truncated]"
+	}
+	switch v := value.(type) {
+	case string:
+		if redactedFields[key] || len(v) > maxArgumentLength {
+			sum := sha256.Sum256([]byte(v))
+			return fmt.Sprintf("[redacted %d bytes sha256:%s]", len(v), hex.EncodeToString(sum[:]))
+		}
+		return v
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = redact(item, k, depth+1)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			out = append(out, redact(item, key, depth+1))
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,152 @@
+// Package auth authenticates clients of the network transports with bearer
+// tokens. Only a hash of each token is configured, so the configuration
+// file does not reveal the tokens themselves.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// hashPrefix names the digest of a stored token hash
+	hashPrefix = "sha256:"
+
+	// tokenBytes is the number of random bytes in a generated token
+	tokenBytes = 32
+
+	// minTokenLength rejects tokens too short to resist guessing
+	minTokenLength = 16
+
+	// maxTokens bounds the configured tokens per Rule 2
+	maxTokens = 256
+)
+
+var (
+	// ErrMissingToken is returned for requests without a bearer token
+	ErrMissingToken = errors.New("missing bearer token")
+
+	// ErrInvalidToken is returned for tokens matching no configured hash
+	ErrInvalidToken = errors.New("invalid bearer token")
+)
+
+// Token is a named credential
+type Token struct {
+	// Name identifies the client in logs and audit records
+	Name string
+
+	// Hash is the token's digest as returned by Hash
+	Hash string
+}
+
+// Tokens checks bearer tokens against configured hashes
+type Tokens struct {
+	names  []string
+	hashes [][]byte
+}
+
+// New parses the hashes of tokens
+func New(tokens []Token) (*Tokens, error) {
+	// Input validation per Rule 7
+	if len(tokens) > maxTokens {
+		return nil, fmt.Errorf("too many tokens: %d, limit is %d", len(tokens), maxTokens)
+	}
+
+	t := &Tokens{
+		names:  make([]string, 0, len(tokens)),
+		hashes: make([][]byte, 0, len(tokens)),
+	}
+	seen := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		if token.Name == "" {
+			return nil, fmt.Errorf("token name cannot be empty")
+		}
+		if seen[token.Name] {
+			return nil, fmt.Errorf("duplicate token name: %s", token.Name)
+		}
+		seen[token.Name] = true
+
+		sum, err := ParseHash(token.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("token %s: %w", token.Name, err)
+		}
+		t.names = append(t.names, token.Name)
+		t.hashes = append(t.hashes, sum)
+	}
+	return t, nil
+}
+
+// Authenticate returns the name of the token matching token. Every hash is
+// compared in constant time so timing does not reveal which one matched.
+func (t *Tokens) Authenticate(token string) (string, error) {
+	if token == "" {
+		return "", ErrMissingToken
+	}
+	sum := sha256.Sum256([]byte(token))
+	match := -1
+	for i, hash := range t.hashes {
+		if subtle.ConstantTimeCompare(sum[:], hash) == 1 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return "", ErrInvalidToken
+	}
+	return t.names[match], nil
+}
+
+// Generate returns a new random token
+func Generate() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Hash returns the form of token stored in the configuration
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// ParseHash decodes a stored token hash
+func ParseHash(hash string) ([]byte, error) {
+	digest, ok := strings.CutPrefix(hash, hashPrefix)
+	if !ok {
+		return nil, fmt.Errorf("token hash must start with %q", hashPrefix)
+	}
+	sum, err := hex.DecodeString(digest)
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("token hash must be %d hex digits", sha256.Size*2)
+	}
+	return sum, nil
+}
+
+// CheckToken rejects tokens too short to be safely hashed without a salt
+func CheckToken(token string) error {
+	if len(token) < minTokenLength {
+		return fmt.Errorf("token must be at least %d characters", minTokenLength)
+	}
+	return nil
+}
+
+// BearerToken returns the token of a request's Authorization header
+func BearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", ErrMissingToken
+	}
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", ErrInvalidToken
+	}
+	return strings.TrimSpace(token), nil
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTokensAuthenticate(t *testing.T) {
+	token, err := Generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if err := CheckToken(token); err != nil {
+		t.Fatalf("generated token should be long enough: %v", err)
+	}
+	if !strings.HasPrefix(Hash(token), "sha256:") {
+		t.Fatalf("unexpected hash format: %s", Hash(token))
+	}
+
+	tokens, err := New([]Token{
+		{Name: "agent", Hash: Hash(token)},
+		{Name: "editor", Hash: Hash("editor-token-0123456789")},
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if name, err := tokens.Authenticate(token); err != nil || name != "agent" {
+		t.Fatalf("expected agent, got %q %v", name, err)
+	}
+	if name, err := tokens.Authenticate("editor-token-0123456789"); err != nil || name != "editor" {
+		t.Fatalf("expected editor, got %q %v", name, err)
+	}
+	if _, err := tokens.Authenticate("guess"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+
+	for _, bad := range [][]Token{
+		{{Name: "", Hash: Hash(token)}},
+		{{Name: "a", Hash: Hash(token)}, {Name: "a", Hash: Hash(token)}},
+		{{Name: "a", Hash: token}},
+		{{Name: "a", Hash: "sha256:abcd"}},
+	} {
+		if _, err := New(bad); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header string
+		token  string
+		err    error
+	}{
+		{"", "", ErrMissingToken},
+		{"Bearer abc", "abc", nil},
+		{"bearer  abc ", "abc", nil},
+		{"Basic abc", "", ErrInvalidToken},
+		{"Bearer", "", ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		token, err := BearerToken(r)
+		if token != tt.token || !errors.Is(err, tt.err) {
+			t.Errorf("%q: got %q %v, want %q %v", tt.header, token, err, tt.token, tt.err)
+		}
+	}
+}
@@ -2,12 +2,21 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"gopkg.in/yaml.v3"
 
+	"filesystem/pkg/auth"
+	"filesystem/pkg/policy"
+	"filesystem/pkg/quota"
+	"filesystem/pkg/secrets"
 	"filesystem/pkg/security"
 )
 
@@ -17,10 +26,275 @@ type Config struct {
 	LogLevel string `yaml:"log_level"`
 
 	// AllowedDirectories contains the list of directories this server can access
-	AllowedDirectories []string `yaml:"allowed_directories"`
+	AllowedDirectories []AllowedDirectory `yaml:"allowed_directories"`
+
+	// DenyPatterns lists globs for sensitive paths that are never accessible.
+	// When omitted a default set is used; an explicit empty list disables it.
+	DenyPatterns []string `yaml:"deny_patterns"`
+
+	// Hardlinks is the policy for regular files with more than one link
+	// (allow, verify, deny)
+	Hardlinks security.HardlinkPolicy `yaml:"hardlinks"`
 
 	// Server configuration
 	Server ServerConfig `yaml:"server"`
+
+	// Audit configures the tool call audit log
+	Audit AuditConfig `yaml:"audit"`
+
+	// Landlock configures kernel-enforced self-sandboxing on Linux
+	Landlock LandlockConfig `yaml:"landlock"`
+
+	// Approval configures the human approval queue for destructive tools
+	Approval ApprovalConfig `yaml:"approval"`
+
+	// Policy holds declarative rules checked before each operation
+	Policy policy.Config `yaml:"policy"`
+
+	// Quotas limits writes, file creation and call rate per session and root
+	Quotas quota.Config `yaml:"quotas"`
+
+	// SecretScanning masks or refuses secrets in file content sent to clients
+	SecretScanning secrets.Config `yaml:"secret_scanning"`
+
+	// FilenamePolicy restricts the names of created and renamed files
+	FilenamePolicy security.NamingPolicy `yaml:"filename_policy"`
+
+	// Admin enables tools that grant and revoke allowed directories at runtime
+	Admin AdminConfig `yaml:"admin"`
+
+	// ClientRoots limits each session to the roots its client advertises
+	ClientRoots ClientRootsConfig `yaml:"client_roots"`
+
+	// Auth requires bearer tokens from clients of the sse and http transports
+	Auth AuthConfig `yaml:"auth"`
+
+	// Timeouts bounds how long each tool call may run
+	Timeouts TimeoutsConfig `yaml:"timeouts"`
+}
+
+// TimeoutsConfig holds tool call timeouts
+type TimeoutsConfig struct {
+	// Default applies to tools without a timeout of their own
+	Default time.Duration `yaml:"default"`
+
+	// Tools sets the timeout of individual tools by name
+	Tools map[string]time.Duration `yaml:"tools"`
+}
+
+// For returns the timeout of the named tool
+func (t TimeoutsConfig) For(tool string) time.Duration {
+	if timeout, ok := t.Tools[tool]; ok {
+		return timeout
+	}
+	if t.Default == 0 {
+		return DefaultToolTimeout
+	}
+	return t.Default
+}
+
+// AuthConfig holds bearer token authentication configuration
+type AuthConfig struct {
+	// Enabled refuses HTTP requests without one of the tokens
+	Enabled bool `yaml:"enabled"`
+
+	// Tokens are the accepted tokens
+	Tokens []TokenConfig `yaml:"tokens"`
+}
+
+// TokenConfig is one accepted bearer token
+type TokenConfig struct {
+	// Name identifies the client in logs and audit records
+	Name string `yaml:"name"`
+
+	// Hash is the token's SHA-256 digest as printed by the token command
+	Hash string `yaml:"hash"`
+
+	// AllowedDirectories limits the token to these directories within the
+	// allowed directories, with at most the given mode; empty grants all of
+	// the allowed directories
+	AllowedDirectories []AllowedDirectory `yaml:"allowed_directories"`
+}
+
+// Scope returns the directories a token is limited to, or nil when it may
+// use all of the allowed directories
+func (t TokenConfig) Scope() []security.Root {
+	if len(t.AllowedDirectories) == 0 {
+		return nil
+	}
+	scope := make([]security.Root, 0, len(t.AllowedDirectories))
+	for _, dir := range t.AllowedDirectories {
+		scope = append(scope, security.Root{Path: dir.Path, Mode: dir.Mode})
+	}
+	return scope
+}
+
+// ClientRootsConfig holds MCP client roots configuration
+type ClientRootsConfig struct {
+	// Enabled asks each client for its roots and limits its session to the
+	// parts of the allowed directories inside them
+	Enabled bool `yaml:"enabled"`
+
+	// Timeout is how long a tool call waits for the client's roots
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// AdminConfig holds runtime directory grant configuration
+type AdminConfig struct {
+	// Enabled adds the add_allowed_directory and remove_allowed_directory tools
+	Enabled bool `yaml:"enabled"`
+
+	// Parents are the directories beneath which access may be granted;
+	// grants take the mode and symlink policy of their parent
+	Parents []AllowedDirectory `yaml:"parents"`
+
+	// MaxTTL caps how long a grant lasts; zero allows grants that never expire
+	MaxTTL time.Duration `yaml:"max_ttl"`
+}
+
+// ApprovalConfig holds approval queue configuration
+type ApprovalConfig struct {
+	// Enabled holds the listed tools until approved over the control socket
+	Enabled bool `yaml:"enabled"`
+
+	// Socket is the path of the Unix control socket
+	Socket string `yaml:"socket"`
+
+	// TTL is how long an operation may wait before it expires
+	TTL time.Duration `yaml:"ttl"`
+
+	// Tools are the tools that require approval; empty selects
+	// write_file, edit_file and move_file
+	Tools []string `yaml:"tools"`
+}
+
+// LandlockConfig holds Landlock sandbox configuration
+type LandlockConfig struct {
+	// Enabled restricts the process to the allowed directories at startup
+	Enabled bool `yaml:"enabled"`
+
+	// ABI is the minimum Landlock ABI version required; zero means 1
+	ABI int `yaml:"abi"`
+
+	// BestEffort continues with fewer or no restrictions when the kernel
+	// does not support the requested ABI
+	BestEffort bool `yaml:"best_effort"`
+}
+
+// AuditConfig holds audit log configuration
+type AuditConfig struct {
+	// Path of the JSONL audit log; empty disables auditing
+	Path string `yaml:"path"`
+
+	// MaxSizeMB is the size in megabytes at which the log is rotated
+	MaxSizeMB int `yaml:"max_size_mb"`
+
+	// MaxBackups is the number of rotated log files kept
+	MaxBackups int `yaml:"max_backups"`
+}
+
+// DefaultDenyPatterns are applied when no deny patterns are configured
+var DefaultDenyPatterns = []string{
+	"**/.env",
+	"**/*.pem",
+	"**/.ssh/**",
+	"**/.git/objects/**",
+}
+
+// Transports clients can connect with
+const (
+	TransportStdio = "stdio"
+	TransportSSE   = "sse"
+	TransportHTTP  = "http"
+	TransportUnix  = "unix"
+)
+
+// DefaultHTTPAddress is the listen address of the HTTP transports, which
+// only accepts connections from the local machine
+const DefaultHTTPAddress = "127.0.0.1:8080"
+
+// DefaultHTTPKeepAlive is the default interval of event stream keep-alives
+const DefaultHTTPKeepAlive = 30 * time.Second
+
+// DefaultHTTPIdleTimeout is how long idle HTTP connections stay open by default
+const DefaultHTTPIdleTimeout = 2 * time.Minute
+
+// DefaultShutdownTimeout is how long shutdown waits for running tool calls
+// by default
+const DefaultShutdownTimeout = 30 * time.Second
+
+// DefaultUnixMode lets only the user running the server use the socket
+const DefaultUnixMode = "0600"
+
+// DefaultApprovalTTL is how long operations wait for approval by default
+const DefaultApprovalTTL = 10 * time.Minute
+
+// DefaultToolTimeout is how long a tool call may run by default
+const DefaultToolTimeout = 2 * time.Minute
+
+// DefaultClientRootsTimeout is how long a tool call waits for the client's
+// roots by default
+const DefaultClientRootsTimeout = 5 * time.Second
+
+// DefaultApprovalSocket returns the control socket path used when none is
+// configured, inside $XDG_RUNTIME_DIR when it is set
+func DefaultApprovalSocket() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "filesystem-mcp-approval.sock")
+}
+
+// DefaultUnixSocket returns the path of the unix transport's socket when
+// none is configured, inside $XDG_RUNTIME_DIR when it is set
+func DefaultUnixSocket() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "filesystem-mcp.sock")
+}
+
+// AllowedDirectory is a directory the server can access and the access mode it grants
+type AllowedDirectory struct {
+	// Path of the directory
+	Path string `yaml:"path"`
+
+	// Mode is the access mode (read-write, read-only, write-only, no-delete)
+	Mode security.AccessMode `yaml:"mode"`
+
+	// Symlinks is the symlink policy (follow, same-root, never, list-only)
+	Symlinks security.SymlinkPolicy `yaml:"symlinks"`
+}
+
+// UnmarshalYAML accepts either a plain path string or a mapping with path and mode
+func (d *AllowedDirectory) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		d.Path = value.Value
+		d.Mode = security.ModeReadWrite
+		d.Symlinks = security.SymlinkFollow
+		return nil
+	}
+
+	// Decode through an alias type to avoid recursing into this method
+	type plain AllowedDirectory
+	var p plain
+	if err := value.Decode(&p); err != nil {
+		return err
+	}
+	*d = AllowedDirectory(p)
+	return nil
+}
+
+// NewAllowedDirectories creates read-write allowed directories that follow
+// symlinks from plain paths
+func NewAllowedDirectories(paths []string) []AllowedDirectory {
+	dirs := make([]AllowedDirectory, 0, len(paths))
+	for _, p := range paths {
+		dirs = append(dirs, AllowedDirectory{Path: p, Mode: security.ModeReadWrite, Symlinks: security.SymlinkFollow})
+	}
+	return dirs
 }
 
 // ServerConfig holds server-specific configuration
@@ -31,8 +305,170 @@ type ServerConfig struct {
 	// Version of the MCP server
 	Version string `yaml:"version"`
 
-	// Transport specifies the transport method (stdio, sse, etc.)
+	// Transport is how clients connect: stdio, sse (HTTP with server sent
+	// events), http (streamable HTTP) or unix (a Unix socket)
 	Transport string `yaml:"transport"`
+
+	// HTTP configures the sse and http transports
+	HTTP HTTPConfig `yaml:"http"`
+
+	// Unix configures the unix transport
+	Unix UnixConfig `yaml:"unix"`
+
+	// ShutdownTimeout is how long shutdown waits for running tool calls
+	// before cancelling them
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// UnixConfig holds settings for the Unix socket transport
+type UnixConfig struct {
+	// Path of the socket
+	Path string `yaml:"path"`
+
+	// Mode is the octal permission of the socket file, such as "0660"
+	Mode string `yaml:"mode"`
+
+	// AllowedUIDs are the users whose processes may connect, checked with
+	// the socket's peer credentials; empty allows only the server's user
+	AllowedUIDs []int `yaml:"allowed_uids"`
+}
+
+// FileMode returns the permission of the socket file
+func (u UnixConfig) FileMode() os.FileMode {
+	mode, err := strconv.ParseUint(u.Mode, 8, 32)
+	if err != nil {
+		mode, _ = strconv.ParseUint(DefaultUnixMode, 8, 32)
+	}
+	return os.FileMode(mode)
+}
+
+// HTTPConfig holds settings for the HTTP based transports
+type HTTPConfig struct {
+	// Address is the host and port to listen on
+	Address string `yaml:"address"`
+
+	// BasePath prefixes the endpoint paths, such as /filesystem
+	BasePath string `yaml:"base_path"`
+
+	// KeepAlive is the interval of keep-alive comments on idle event streams
+	KeepAlive time.Duration `yaml:"keep_alive"`
+
+	// IdleTimeout closes keep-alive connections idle between requests
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+
+	// AllowedOrigins are browser origins accepted besides localhost;
+	// requests without an Origin header are always accepted
+	AllowedOrigins []string `yaml:"allowed_origins"`
+
+	// TLS serves HTTPS, optionally requiring client certificates
+	TLS TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig holds the certificate files of the HTTP transports
+type TLSConfig struct {
+	// CertFile and KeyFile are the PEM encoded server certificate and key
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+
+	// ClientCAFile enables mutual TLS: clients must present a certificate
+	// signed by one of the PEM encoded CAs in this file
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// Enabled reports whether HTTPS is configured
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != ""
+}
+
+// Validate checks the server settings, filling in defaults
+func (s *ServerConfig) Validate() error {
+	if s.Name == "" {
+		s.Name = "secure-filesystem-server" // Default value
+	}
+	if s.Version == "" {
+		s.Version = "1.0.0" // Default value
+	}
+	if s.Transport == "" {
+		s.Transport = TransportStdio // Default value
+	}
+
+	switch s.Transport {
+	case TransportStdio, TransportSSE, TransportHTTP, TransportUnix:
+	default:
+		return fmt.Errorf("invalid transport: %s", s.Transport)
+	}
+
+	if s.ShutdownTimeout < 0 {
+		return fmt.Errorf("invalid shutdown_timeout: %s", s.ShutdownTimeout)
+	}
+	if s.ShutdownTimeout == 0 {
+		s.ShutdownTimeout = DefaultShutdownTimeout
+	}
+
+	h := &s.HTTP
+	if h.Address == "" {
+		h.Address = DefaultHTTPAddress
+	}
+	if _, _, err := net.SplitHostPort(h.Address); err != nil {
+		return fmt.Errorf("invalid http address %q: %w", h.Address, err)
+	}
+	if h.BasePath != "" {
+		if !strings.HasPrefix(h.BasePath, "/") || strings.ContainsAny(h.BasePath, "?#") {
+			return fmt.Errorf("invalid http base_path: %s", h.BasePath)
+		}
+		h.BasePath = strings.TrimSuffix(h.BasePath, "/")
+	}
+	if h.KeepAlive < 0 {
+		return fmt.Errorf("invalid http keep_alive: %s", h.KeepAlive)
+	}
+	if h.KeepAlive == 0 {
+		h.KeepAlive = DefaultHTTPKeepAlive
+	}
+	if h.IdleTimeout < 0 {
+		return fmt.Errorf("invalid http idle_timeout: %s", h.IdleTimeout)
+	}
+	if h.IdleTimeout == 0 {
+		h.IdleTimeout = DefaultHTTPIdleTimeout
+	}
+	for _, origin := range h.AllowedOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return fmt.Errorf("invalid http allowed origin: %s", origin)
+		}
+	}
+
+	// A key without a certificate, or client CAs without either, would
+	// silently serve plain HTTP
+	t := &h.TLS
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("http tls requires both cert_file and key_file")
+	}
+	if t.ClientCAFile != "" && t.CertFile == "" {
+		return fmt.Errorf("http tls client_ca_file requires cert_file and key_file")
+	}
+	for _, path := range []*string{&t.CertFile, &t.KeyFile, &t.ClientCAFile} {
+		if *path != "" {
+			*path = security.ExpandHomePath(*path)
+		}
+	}
+
+	u := &s.Unix
+	if u.Path == "" {
+		u.Path = DefaultUnixSocket()
+	}
+	u.Path = security.ExpandHomePath(u.Path)
+	if u.Mode == "" {
+		u.Mode = DefaultUnixMode
+	}
+	if mode, err := strconv.ParseUint(u.Mode, 8, 32); err != nil || mode > 0777 {
+		return fmt.Errorf("invalid unix mode %q: must be octal permissions such as 0600", u.Mode)
+	}
+	for _, uid := range u.AllowedUIDs {
+		if uid < 0 {
+			return fmt.Errorf("invalid unix allowed uid: %d", uid)
+		}
+	}
+	return nil
 }
 
 // Load reads and validates configuration from the specified file path
@@ -90,32 +526,190 @@ func validateConfig(cfg *Config) error {
 	}
 
 	// Validate server configuration
-	if cfg.Server.Name == "" {
-		cfg.Server.Name = "secure-filesystem-server" // Default value
+	if err := cfg.Server.Validate(); err != nil {
+		return err
 	}
 
-	if cfg.Server.Version == "" {
-		cfg.Server.Version = "1.0.0" // Default value
+	// Validate allowed directories (at least one required)
+	if len(cfg.AllowedDirectories) == 0 {
+		return fmt.Errorf("at least one allowed directory must be specified")
 	}
 
-	if cfg.Server.Transport == "" {
-		cfg.Server.Transport = "stdio" // Default value
+	// Validate access modes and symlink policies, defaulting to read-write
+	// and follow
+	for i := range cfg.AllowedDirectories {
+		if err := validateDirectory(&cfg.AllowedDirectories[i]); err != nil {
+			return err
+		}
 	}
 
-	// Validate allowed directories (at least one required)
-	if len(cfg.AllowedDirectories) == 0 {
-		return fmt.Errorf("at least one allowed directory must be specified")
+	// Validate audit log settings; zero values select the defaults
+	if cfg.Audit.MaxSizeMB < 0 {
+		return fmt.Errorf("invalid audit max_size_mb: %d", cfg.Audit.MaxSizeMB)
+	}
+	if cfg.Audit.MaxBackups < 0 {
+		return fmt.Errorf("invalid audit max_backups: %d", cfg.Audit.MaxBackups)
+	}
+	if cfg.Audit.Path != "" {
+		cfg.Audit.Path = security.ExpandHomePath(cfg.Audit.Path)
+	}
+
+	if cfg.Landlock.ABI < 0 {
+		return fmt.Errorf("invalid landlock abi: %d", cfg.Landlock.ABI)
+	}
+
+	// Validate approval settings; the socket and TTL have defaults
+	if cfg.Approval.TTL < 0 {
+		return fmt.Errorf("invalid approval ttl: %s", cfg.Approval.TTL)
+	}
+	if cfg.Approval.TTL == 0 {
+		cfg.Approval.TTL = DefaultApprovalTTL
+	}
+	if cfg.Approval.Socket == "" {
+		cfg.Approval.Socket = DefaultApprovalSocket()
+	}
+	cfg.Approval.Socket = security.ExpandHomePath(cfg.Approval.Socket)
+	for _, tool := range cfg.Approval.Tools {
+		if tool == "" {
+			return fmt.Errorf("approval tool name cannot be empty")
+		}
+	}
+
+	if _, err := policy.New(cfg.Policy); err != nil {
+		return err
+	}
+	if err := cfg.Quotas.Validate(); err != nil {
+		return err
+	}
+	if _, err := secrets.New(cfg.SecretScanning); err != nil {
+		return err
+	}
+	if err := cfg.FilenamePolicy.Validate(); err != nil {
+		return fmt.Errorf("invalid filename policy: %w", err)
+	}
+
+	if cfg.ClientRoots.Timeout < 0 {
+		return fmt.Errorf("invalid client_roots timeout: %s", cfg.ClientRoots.Timeout)
+	}
+	if cfg.ClientRoots.Timeout == 0 {
+		cfg.ClientRoots.Timeout = DefaultClientRootsTimeout
 	}
 
+	if cfg.Timeouts.Default < 0 {
+		return fmt.Errorf("invalid timeouts default: %s", cfg.Timeouts.Default)
+	}
+	if cfg.Timeouts.Default == 0 {
+		cfg.Timeouts.Default = DefaultToolTimeout
+	}
+	for tool, timeout := range cfg.Timeouts.Tools {
+		if tool == "" {
+			return fmt.Errorf("timeout tool name cannot be empty")
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("invalid timeout for %s: %s", tool, timeout)
+		}
+	}
+
+	// Validate admin grant settings; grants need somewhere to live
+	if cfg.Admin.MaxTTL < 0 {
+		return fmt.Errorf("invalid admin max_ttl: %s", cfg.Admin.MaxTTL)
+	}
+	if cfg.Admin.Enabled && len(cfg.Admin.Parents) == 0 {
+		return fmt.Errorf("admin tools require at least one parent directory")
+	}
+	for i := range cfg.Admin.Parents {
+		if err := validateDirectory(&cfg.Admin.Parents[i]); err != nil {
+			return fmt.Errorf("admin parent: %w", err)
+		}
+	}
+
+	// Validate tokens; their directories take the symlink policy of the
+	// allowed directory they lie in
+	if _, err := auth.New(cfg.AuthTokens()); err != nil {
+		return fmt.Errorf("invalid auth configuration: %w", err)
+	}
+	for i := range cfg.Auth.Tokens {
+		dirs := cfg.Auth.Tokens[i].AllowedDirectories
+		for j := range dirs {
+			if dirs[j].Symlinks != "" {
+				return fmt.Errorf("token %s: directories cannot set a symlink policy", cfg.Auth.Tokens[i].Name)
+			}
+			if err := validateDirectory(&dirs[j]); err != nil {
+				return fmt.Errorf("token %s: %w", cfg.Auth.Tokens[i].Name, err)
+			}
+		}
+	}
+
+	hardlinks, err := security.ParseHardlinkPolicy(string(cfg.Hardlinks))
+	if err != nil {
+		return err
+	}
+	cfg.Hardlinks = hardlinks
+
+	// Apply default deny patterns only when the key is absent
+	if cfg.DenyPatterns == nil {
+		cfg.DenyPatterns = append([]string(nil), DefaultDenyPatterns...)
+	}
+	for _, pattern := range cfg.DenyPatterns {
+		if !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("invalid deny pattern: %s", pattern)
+		}
+	}
+
+	return nil
+}
+
+// validateDirectory checks the path, access mode and symlink policy of an
+// allowed directory, defaulting to read-write and follow
+func validateDirectory(dir *AllowedDirectory) error {
+	if dir.Path == "" {
+		return fmt.Errorf("allowed directory path cannot be empty")
+	}
+	mode, err := security.ParseAccessMode(string(dir.Mode))
+	if err != nil {
+		return fmt.Errorf("allowed directory %s: %w", dir.Path, err)
+	}
+	dir.Mode = mode
+
+	symlinks, err := security.ParseSymlinkPolicy(string(dir.Symlinks))
+	if err != nil {
+		return fmt.Errorf("allowed directory %s: %w", dir.Path, err)
+	}
+	dir.Symlinks = symlinks
 	return nil
 }
 
 // normalizeDirectories processes and validates allowed directories
 func normalizeDirectories(cfg *Config) error {
-	normalizedDirs := make([]string, 0, len(cfg.AllowedDirectories))
+	dirs, err := normalizeDirectoryList(cfg.AllowedDirectories)
+	if err != nil {
+		return err
+	}
+	cfg.AllowedDirectories = dirs
+
+	parents, err := normalizeDirectoryList(cfg.Admin.Parents)
+	if err != nil {
+		return fmt.Errorf("admin parent: %w", err)
+	}
+	cfg.Admin.Parents = parents
+
+	for i := range cfg.Auth.Tokens {
+		dirs, err := normalizeDirectoryList(cfg.Auth.Tokens[i].AllowedDirectories)
+		if err != nil {
+			return fmt.Errorf("token %s: %w", cfg.Auth.Tokens[i].Name, err)
+		}
+		cfg.Auth.Tokens[i].AllowedDirectories = dirs
+	}
+	return nil
+}
+
+// normalizeDirectoryList makes each directory absolute and checks that it exists
+func normalizeDirectoryList(entries []AllowedDirectory) ([]AllowedDirectory, error) {
+	normalizedDirs := make([]AllowedDirectory, 0, len(entries))
 
 	// Process each directory
-	for _, dir := range cfg.AllowedDirectories {
+	for _, entry := range entries {
+		dir := entry.Path
 
 		// Expand home directory if needed
 		dir = security.ExpandHomePath(dir)
@@ -123,37 +717,107 @@ func normalizeDirectories(cfg *Config) error {
 		// Convert to absolute path
 		absDir, err := filepath.Abs(dir)
 		if err != nil {
-			return fmt.Errorf("failed to get absolute path for %s: %w", dir, err)
+			return nil, fmt.Errorf("failed to get absolute path for %s: %w", dir, err)
 		}
 
 		// Validate directory exists and is accessible
 		info, err := os.Stat(absDir)
 		if err != nil {
-			return fmt.Errorf("directory %s is not accessible: %w", absDir, err)
+			return nil, fmt.Errorf("directory %s is not accessible: %w", absDir, err)
 		}
 
 		if !info.IsDir() {
-			return fmt.Errorf("path %s is not a directory", absDir)
+			return nil, fmt.Errorf("path %s is not a directory", absDir)
 		}
 
 		// Clean and normalize path
 		normalizedDir := filepath.Clean(absDir)
-		normalizedDirs = append(normalizedDirs, normalizedDir)
+		normalizedDirs = append(normalizedDirs, AllowedDirectory{Path: normalizedDir, Mode: entry.Mode, Symlinks: entry.Symlinks})
 	}
 
-	cfg.AllowedDirectories = normalizedDirs
-	return nil
+	return normalizedDirs, nil
+}
+
+// DirectoryPaths returns the paths of all allowed directories
+func (c *Config) DirectoryPaths() []string {
+	paths := make([]string, 0, len(c.AllowedDirectories))
+	for _, dir := range c.AllowedDirectories {
+		paths = append(paths, dir.Path)
+	}
+	return paths
+}
+
+// Roots returns the allowed directories as security roots
+func (c *Config) Roots() []security.Root {
+	roots := make([]security.Root, 0, len(c.AllowedDirectories))
+	for _, dir := range c.AllowedDirectories {
+		roots = append(roots, security.Root{Path: dir.Path, Mode: dir.Mode, Symlinks: dir.Symlinks})
+	}
+	return roots
+}
+
+// SecurityOptions returns the path validator options described by the configuration
+func (c *Config) SecurityOptions() security.Options {
+	return security.Options{
+		Roots:          c.Roots(),
+		DenyPatterns:   c.DenyPatterns,
+		HardlinkPolicy: c.Hardlinks,
+	}
+}
+
+// AuthTokens returns the configured bearer tokens
+func (c *Config) AuthTokens() []auth.Token {
+	tokens := make([]auth.Token, 0, len(c.Auth.Tokens))
+	for _, t := range c.Auth.Tokens {
+		tokens = append(tokens, auth.Token{Name: t.Name, Hash: t.Hash})
+	}
+	return tokens
+}
+
+// GrantPolicy returns the limits on runtime directory grants, or nil when
+// the admin tools are disabled
+func (c *Config) GrantPolicy() *security.GrantPolicy {
+	if !c.Admin.Enabled {
+		return nil
+	}
+	parents := make([]security.Root, 0, len(c.Admin.Parents))
+	for _, dir := range c.Admin.Parents {
+		parents = append(parents, security.Root{Path: dir.Path, Mode: dir.Mode, Symlinks: dir.Symlinks})
+	}
+	return &security.GrantPolicy{Parents: parents, MaxTTL: c.Admin.MaxTTL}
 }
 
 // Default returns a default configuration
 func Default() *Config {
 	return &Config{
 		LogLevel:           "info",
-		AllowedDirectories: []string{"."},
+		AllowedDirectories: NewAllowedDirectories([]string{"."}),
+		DenyPatterns:       append([]string(nil), DefaultDenyPatterns...),
+		Hardlinks:          security.HardlinkAllow,
+		Approval: ApprovalConfig{
+			Socket: DefaultApprovalSocket(),
+			TTL:    DefaultApprovalTTL,
+		},
+		ClientRoots: ClientRootsConfig{
+			Timeout: DefaultClientRootsTimeout,
+		},
+		Timeouts: TimeoutsConfig{
+			Default: DefaultToolTimeout,
+		},
 		Server: ServerConfig{
-			Name:      "secure-filesystem-server",
-			Version:   "1.0.0",
-			Transport: "stdio",
+			Name:            "secure-filesystem-server",
+			Version:         "1.0.0",
+			Transport:       TransportStdio,
+			ShutdownTimeout: DefaultShutdownTimeout,
+			HTTP: HTTPConfig{
+				Address:     DefaultHTTPAddress,
+				KeepAlive:   DefaultHTTPKeepAlive,
+				IdleTimeout: DefaultHTTPIdleTimeout,
+			},
+			Unix: UnixConfig{
+				Path: DefaultUnixSocket(),
+				Mode: DefaultUnixMode,
+			},
 		},
 	}
 }
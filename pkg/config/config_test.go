@@ -1,11 +1,16 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"filesystem/pkg/security"
 )
 
 func writeConfig(t *testing.T, dir, content string) string {
@@ -36,7 +41,7 @@ server:
 	if cfg.LogLevel != "warn" {
 		t.Fatalf("log level expected warn got %s", cfg.LogLevel)
 	}
-	if len(cfg.AllowedDirectories) != 1 || cfg.AllowedDirectories[0] != filepath.Clean(tmp) {
+	if len(cfg.AllowedDirectories) != 1 || cfg.AllowedDirectories[0].Path != filepath.Clean(tmp) {
 		t.Fatalf("dirs not normalized: %v", cfg.AllowedDirectories)
 	}
 	if cfg.Server.Name != "srv" || cfg.Server.Version != "v1" || cfg.Server.Transport != "stdio" {
@@ -107,7 +112,297 @@ log_level: info
 	}
 
 	expect := []string{filepath.Clean(homeSub), filepath.Clean(relDir)}
-	if !reflect.DeepEqual(cfg.AllowedDirectories, expect) {
-		t.Fatalf("expected %v got %v", expect, cfg.AllowedDirectories)
+	if !reflect.DeepEqual(cfg.DirectoryPaths(), expect) {
+		t.Fatalf("expected %v got %v", expect, cfg.DirectoryPaths())
+	}
+}
+
+func TestLoadAccessModes(t *testing.T) {
+	dir := t.TempDir()
+	ro := filepath.Join(dir, "ro")
+	if err := os.Mkdir(ro, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	cfgStr := fmt.Sprintf(`allowed_directories:
+  - %q
+  - path: %q
+    mode: read-only
+`, dir, ro)
+	path := writeConfig(t, dir, cfgStr)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(cfg.AllowedDirectories) != 2 {
+		t.Fatalf("expected 2 directories got %v", cfg.AllowedDirectories)
+	}
+	if cfg.AllowedDirectories[0].Mode != security.ModeReadWrite {
+		t.Fatalf("expected default read-write got %s", cfg.AllowedDirectories[0].Mode)
+	}
+	if cfg.AllowedDirectories[1].Path != ro || cfg.AllowedDirectories[1].Mode != security.ModeReadOnly {
+		t.Fatalf("unexpected read-only entry: %+v", cfg.AllowedDirectories[1])
+	}
+}
+
+func TestLoadInvalidAccessMode(t *testing.T) {
+	dir := t.TempDir()
+	cfgStr := fmt.Sprintf(`allowed_directories:
+  - path: %q
+    mode: everything
+`, dir)
+	path := writeConfig(t, dir, cfgStr)
+	if _, err := Load(path); err == nil {
+		t.Fatalf("expected error for invalid access mode")
+	}
+}
+
+func TestLoadDenyPatterns(t *testing.T) {
+	dir := t.TempDir()
+	defaults := writeConfig(t, dir, fmt.Sprintf("allowed_directories:\n  - %q\n", dir))
+	cfg, err := Load(defaults)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.DenyPatterns, DefaultDenyPatterns) {
+		t.Fatalf("expected default deny patterns got %v", cfg.DenyPatterns)
+	}
+
+	disabled := writeConfig(t, dir, fmt.Sprintf("allowed_directories:\n  - %q\ndeny_patterns: []\n", dir))
+	cfg, err = Load(disabled)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(cfg.DenyPatterns) != 0 {
+		t.Fatalf("expected deny patterns disabled got %v", cfg.DenyPatterns)
+	}
+
+	invalid := writeConfig(t, dir, fmt.Sprintf("allowed_directories:\n  - %q\ndeny_patterns: [\"[\"]\n", dir))
+	if _, err := Load(invalid); err == nil {
+		t.Fatalf("expected error for invalid deny pattern")
+	}
+}
+
+func TestWatchDetectsChange(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, "allowed_directories:\n  - "+dir+"\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go Watch(ctx, path, 10*time.Millisecond, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+
+	// Grow the file so the change is visible even with coarse mtimes
+	time.Sleep(30 * time.Millisecond)
+	writeConfig(t, dir, "log_level: debug\nallowed_directories:\n  - "+dir+"\n")
+
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected change notification")
+	}
+}
+
+func TestLoadSymlinkPolicies(t *testing.T) {
+	dir := t.TempDir()
+	cfgStr := fmt.Sprintf(`allowed_directories:
+  - %q
+  - path: %q
+    symlinks: list-only
+`, dir, dir)
+	cfg, err := Load(writeConfig(t, dir, cfgStr))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.AllowedDirectories[0].Symlinks != security.SymlinkFollow {
+		t.Fatalf("expected default follow got %s", cfg.AllowedDirectories[0].Symlinks)
+	}
+	if cfg.AllowedDirectories[1].Symlinks != security.SymlinkListOnly {
+		t.Fatalf("expected list-only got %s", cfg.AllowedDirectories[1].Symlinks)
+	}
+
+	cfgStr = fmt.Sprintf(`allowed_directories:
+  - path: %q
+    symlinks: sometimes
+`, dir)
+	if _, err := Load(writeConfig(t, dir, cfgStr)); err == nil {
+		t.Fatalf("expected error for invalid symlink policy")
+	}
+}
+
+func TestLoadApproval(t *testing.T) {
+	dir := t.TempDir()
+	cfgStr := fmt.Sprintf(`allowed_directories: [%q]
+approval:
+  enabled: true
+  ttl: 5m
+`, dir)
+	cfg, err := Load(writeConfig(t, dir, cfgStr))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !cfg.Approval.Enabled || cfg.Approval.TTL != 5*time.Minute {
+		t.Fatalf("unexpected approval config: %+v", cfg.Approval)
+	}
+	if cfg.Approval.Socket != DefaultApprovalSocket() {
+		t.Fatalf("expected default socket, got %s", cfg.Approval.Socket)
+	}
+
+	bad := fmt.Sprintf("allowed_directories: [%q]\napproval:\n  ttl: -1m\n", dir)
+	if _, err := Load(writeConfig(t, dir, bad)); err == nil {
+		t.Fatalf("expected error for negative approval ttl")
+	}
+}
+
+func TestLoadHTTPTransport(t *testing.T) {
+	dir := t.TempDir()
+	cfgStr := fmt.Sprintf(`allowed_directories: [%q]
+server:
+  transport: http
+  http:
+    base_path: /fs/
+    keep_alive: 15s
+`, dir)
+	cfg, err := Load(writeConfig(t, dir, cfgStr))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	h := cfg.Server.HTTP
+	if h.Address != DefaultHTTPAddress || h.BasePath != "/fs" || h.KeepAlive != 15*time.Second || h.IdleTimeout != DefaultHTTPIdleTimeout {
+		t.Fatalf("unexpected http config: %+v", h)
+	}
+	if cfg.Server.ShutdownTimeout != DefaultShutdownTimeout {
+		t.Fatalf("unexpected shutdown timeout: %s", cfg.Server.ShutdownTimeout)
+	}
+
+	for _, bad := range []string{
+		"server:\n  transport: websocket\n",
+		"server:\n  transport: sse\n  http:\n    address: localhost\n",
+		"server:\n  transport: sse\n  http:\n    base_path: fs\n",
+		"server:\n  transport: sse\n  http:\n    allowed_origins: [editor.example]\n",
+		"server:\n  shutdown_timeout: -1s\n",
+	} {
+		content := fmt.Sprintf("allowed_directories: [%q]\n%s", dir, bad)
+		if _, err := Load(writeConfig(t, dir, content)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLoadAuthTokens(t *testing.T) {
+	dir := t.TempDir()
+	docs := filepath.Join(dir, "docs")
+	if err := os.Mkdir(docs, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	hash := "sha256:" + strings.Repeat("ab", 32)
+	cfgStr := fmt.Sprintf(`allowed_directories: [%q]
+auth:
+  enabled: true
+  tokens:
+    - name: reader
+      hash: %s
+      allowed_directories:
+        - path: %q
+          mode: read-only
+    - name: owner
+      hash: %s
+`, dir, hash, docs, hash)
+	cfg, err := Load(writeConfig(t, dir, cfgStr))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	scope := cfg.Auth.Tokens[0].Scope()
+	if len(scope) != 1 || scope[0].Path != docs || scope[0].Mode != security.ModeReadOnly {
+		t.Fatalf("unexpected reader scope: %+v", scope)
+	}
+	if cfg.Auth.Tokens[1].Scope() != nil {
+		t.Fatalf("a token without directories should not be limited")
+	}
+
+	for _, bad := range []string{
+		"auth:\n  tokens:\n    - name: a\n      hash: secret\n",
+		fmt.Sprintf("auth:\n  tokens:\n    - name: a\n      hash: %s\n      allowed_directories:\n        - path: %q\n          symlinks: never\n", hash, docs),
+		"server:\n  http:\n    tls:\n      key_file: key.pem\n",
+		"server:\n  http:\n    tls:\n      client_ca_file: ca.pem\n",
+	} {
+		content := fmt.Sprintf("allowed_directories: [%q]\n%s", dir, bad)
+		if _, err := Load(writeConfig(t, dir, content)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLoadUnixTransport(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "fs.sock")
+	cfgStr := fmt.Sprintf(`allowed_directories: [%q]
+server:
+  transport: unix
+  unix:
+    path: %q
+    mode: 0660
+    allowed_uids: [1000, 1001]
+`, dir, socket)
+	cfg, err := Load(writeConfig(t, dir, cfgStr))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	u := cfg.Server.Unix
+	if u.Path != socket || u.FileMode() != 0660 || len(u.AllowedUIDs) != 2 {
+		t.Fatalf("unexpected unix config: %+v", u)
+	}
+
+	cfg, err = Load(writeConfig(t, dir, fmt.Sprintf("allowed_directories: [%q]\nserver:\n  transport: unix\n", dir)))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Server.Unix.Path != DefaultUnixSocket() || cfg.Server.Unix.FileMode() != 0600 {
+		t.Fatalf("unexpected unix defaults: %+v", cfg.Server.Unix)
+	}
+
+	for _, bad := range []string{
+		"server:\n  transport: unix\n  unix:\n    mode: rw\n",
+		"server:\n  transport: unix\n  unix:\n    mode: \"01777\"\n",
+		"server:\n  transport: unix\n  unix:\n    allowed_uids: [-1]\n",
+	} {
+		content := fmt.Sprintf("allowed_directories: [%q]\n%s", dir, bad)
+		if _, err := Load(writeConfig(t, dir, content)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLoadTimeouts(t *testing.T) {
+	dir := t.TempDir()
+	cfgStr := fmt.Sprintf(`allowed_directories: [%q]
+timeouts:
+  tools:
+    search_files: 30s
+`, dir)
+	cfg, err := Load(writeConfig(t, dir, cfgStr))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := cfg.Timeouts.For("search_files"); got != 30*time.Second {
+		t.Fatalf("unexpected search_files timeout: %s", got)
+	}
+	if got := cfg.Timeouts.For("read_file"); got != DefaultToolTimeout {
+		t.Fatalf("unexpected default timeout: %s", got)
+	}
+
+	for _, bad := range []string{
+		"timeouts:\n  default: -1s\n",
+		"timeouts:\n  tools:\n    search_files: 0s\n",
+	} {
+		content := fmt.Sprintf("allowed_directories: [%q]\n%s", dir, bad)
+		if _, err := Load(writeConfig(t, dir, content)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
 	}
 }
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// Watch polls the configuration file and calls onChange whenever its size
+// or modification time changes. Errors such as the file being briefly absent
+// while an editor replaces it are ignored until the next poll. Watch returns
+// when ctx is cancelled.
+func Watch(ctx context.Context, configPath string, interval time.Duration, onChange func()) {
+	// Input validation per Rule 7
+	if configPath == "" || interval <= 0 || onChange == nil {
+		return
+	}
+
+	var lastMod time.Time
+	var lastSize int64
+	if info, err := os.Stat(configPath); err == nil {
+		lastMod, lastSize = info.ModTime(), info.Size()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(configPath)
+			if err != nil {
+				continue
+			}
+			if info.ModTime().Equal(lastMod) && info.Size() == lastSize {
+				continue
+			}
+			lastMod, lastSize = info.ModTime(), info.Size()
+			onChange()
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package filesystem
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+
+	"filesystem/pkg/security"
+)
+
+// anchor splits a validated path into the allowed directory containing it
+// and the path relative to that directory
+func (ops *Operations) anchor(validPath string) (string, string, error) {
+	root, ok := ops.pathValidator.RootOf(validPath)
+	if !ok {
+		return "", "", fmt.Errorf("%w - path outside allowed directories: %s", security.ErrAccessDenied, validPath)
+	}
+	rel, err := filepath.Rel(root, validPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve path relative to %s: %w", root, err)
+	}
+	return root, rel, nil
+}
+
+// openFile opens a validated path anchored on its allowed directory
+func (ops *Operations) openFile(validPath string, flag int, perm fs.FileMode) (*os.File, error) {
+	root, rel, err := ops.anchor(validPath)
+	if err != nil {
+		return nil, err
+	}
+	return openBeneath(root, rel, flag, perm)
+}
+
+// statFile returns file information for a validated path anchored on its allowed directory
+func (ops *Operations) statFile(validPath string) (os.FileInfo, error) {
+	root, rel, err := ops.anchor(validPath)
+	if err != nil {
+		return nil, err
+	}
+	return statBeneath(root, rel)
+}
+
+// readDir lists a validated directory anchored on its allowed directory.
+// As with openRegularFile, the file type is checked before opening and
+// again on the descriptor, and O_NONBLOCK keeps a FIFO swapped in between
+// from blocking the open.
+func (ops *Operations) readDir(validPath string) ([]os.DirEntry, error) {
+	if info, err := ops.statFile(validPath); err == nil {
+		if err := ops.pathValidator.CheckFileType(validPath, info); err != nil {
+			return nil, err
+		}
+	}
+
+	dir, err := ops.openFile(validPath, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+
+	info, err := dir.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat directory: %w", err)
+	}
+	if err := ops.pathValidator.CheckFileType(validPath, info); err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: validPath, Err: syscall.ENOTDIR}
+	}
+
+	// Sort by name to match os.ReadDir, which listings relied on before
+	entries, err := dir.ReadDir(-1)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, err
+}
+
+// mkdirAll creates a validated directory and its parents anchored on its allowed directory
+func (ops *Operations) mkdirAll(validPath string, perm fs.FileMode) error {
+	root, rel, err := ops.anchor(validPath)
+	if err != nil {
+		return err
+	}
+	return mkdirAllBeneath(root, rel, perm)
+}
+
+// rename moves a validated path to another, each anchored on its allowed directory
+func (ops *Operations) rename(srcPath, destPath string) error {
+	srcRoot, srcRel, err := ops.anchor(srcPath)
+	if err != nil {
+		return err
+	}
+	destRoot, destRel, err := ops.anchor(destPath)
+	if err != nil {
+		return err
+	}
+	return renameBeneath(srcRoot, srcRel, destRoot, destRel)
+}
+
+// readlink returns the target of a validated symlink anchored on its allowed directory
+func (ops *Operations) readlink(validPath string) (string, error) {
+	root, rel, err := ops.anchor(validPath)
+	if err != nil {
+		return "", err
+	}
+	return readlinkBeneath(root, rel)
+}
+
+// symlink creates a validated path as a symlink to target, anchored on its allowed directory
+func (ops *Operations) symlink(target, validPath string) error {
+	root, rel, err := ops.anchor(validPath)
+	if err != nil {
+		return err
+	}
+	return symlinkBeneath(target, root, rel)
+}
+
+// removeAll removes a validated path and its contents anchored on its allowed directory
+func (ops *Operations) removeAll(validPath string) error {
+	root, rel, err := ops.anchor(validPath)
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return fmt.Errorf("%w - cannot remove allowed directory %s", security.ErrAccessDenied, validPath)
+	}
+	return removeAllBeneath(root, rel)
+}
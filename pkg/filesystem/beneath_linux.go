@@ -0,0 +1,352 @@
+//go:build linux
+// +build linux
+
+package filesystem
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// openat2 resolve flags from linux/openat2.h
+const (
+	resolveNoMagiclinks = 0x02
+	resolveBeneath      = 0x08
+)
+
+// maxPathComponents bounds directory creation loops and removal depth per Rule 2
+const maxPathComponents = 4096
+
+// atRemoveDir is AT_REMOVEDIR, which makes unlinkat remove a directory
+const atRemoveDir = 0x200
+
+// maxLinkTarget bounds the length of a symlink target read with readlinkat
+const maxLinkTarget = 4096
+
+// openHow mirrors struct open_how from linux/openat2.h
+type openHow struct {
+	Flags   uint64
+	Mode    uint64
+	Resolve uint64
+}
+
+var (
+	openat2Once      sync.Once
+	openat2Available bool
+)
+
+// resolveBeneathSupported reports whether the kernel provides openat2.
+// The probe runs once; kernels older than 5.6 or seccomp filters that
+// block the syscall fall back to path-based resolution.
+func resolveBeneathSupported() bool {
+	openat2Once.Do(func() {
+		how := openHow{Flags: unix.O_PATH | syscall.O_CLOEXEC}
+		fd, err := openat2(-100, "/", &how) // AT_FDCWD
+		if err == nil {
+			syscall.Close(fd)
+			openat2Available = true
+		}
+	})
+	return openat2Available
+}
+
+// openat2 invokes the raw syscall, retrying on EINTR
+func openat2(dirfd int, path string, how *openHow) (int, error) {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return -1, err
+	}
+	for {
+		fd, _, errno := syscall.Syscall6(unix.SYS_OPENAT2,
+			uintptr(dirfd),
+			uintptr(unsafe.Pointer(p)),
+			uintptr(unsafe.Pointer(how)),
+			unsafe.Sizeof(*how),
+			0, 0)
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return -1, errno
+		}
+		return int(fd), nil
+	}
+}
+
+// openRootDir opens an allowed directory as the anchor for further lookups
+func openRootDir(root string) (int, error) {
+	fd, err := syscall.Open(root, unix.O_PATH|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, &os.PathError{Op: "open", Path: root, Err: err}
+	}
+	return fd, nil
+}
+
+// openAt resolves rel beneath dirfd, refusing any escape through "..",
+// absolute symlinks or magic links
+func openAt(dirfd int, rel string, flag int, perm fs.FileMode) (int, error) {
+	how := openHow{
+		Flags:   uint64(flag | syscall.O_CLOEXEC),
+		Resolve: resolveBeneath | resolveNoMagiclinks,
+	}
+	if flag&os.O_CREATE != 0 {
+		how.Mode = uint64(perm.Perm())
+	}
+	return openat2(dirfd, rel, &how)
+}
+
+// openBeneath opens root/rel with resolution confined to root
+func openBeneath(root, rel string, flag int, perm fs.FileMode) (*os.File, error) {
+	fullPath := filepath.Join(root, rel)
+	if !resolveBeneathSupported() {
+		return os.OpenFile(fullPath, flag, perm)
+	}
+
+	dirfd, err := openRootDir(root)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(dirfd)
+
+	fd, err := openAt(dirfd, rel, flag, perm)
+	if err != nil {
+		return nil, &os.PathError{Op: "openat2", Path: fullPath, Err: err}
+	}
+	return os.NewFile(uintptr(fd), fullPath), nil
+}
+
+// statBeneath stats root/rel with resolution confined to root
+func statBeneath(root, rel string) (os.FileInfo, error) {
+	if !resolveBeneathSupported() {
+		return os.Stat(filepath.Join(root, rel))
+	}
+	f, err := openBeneath(root, rel, unix.O_PATH, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// mkdirAllBeneath creates root/rel and any missing parents one component
+// at a time, each step confined beneath the directory created before it
+func mkdirAllBeneath(root, rel string, perm fs.FileMode) error {
+	fullPath := filepath.Join(root, rel)
+	if !resolveBeneathSupported() {
+		return os.MkdirAll(fullPath, perm)
+	}
+
+	cur, err := openRootDir(root)
+	if err != nil {
+		return err
+	}
+	defer func() { syscall.Close(cur) }()
+
+	components := strings.Split(filepath.Clean(rel), string(filepath.Separator))
+	if len(components) > maxPathComponents {
+		return &os.PathError{Op: "mkdir", Path: fullPath, Err: syscall.ENAMETOOLONG}
+	}
+
+	for _, component := range components {
+		if component == "." || component == "" {
+			continue
+		}
+		if err := syscall.Mkdirat(cur, component, uint32(perm.Perm())); err != nil && !errors.Is(err, syscall.EEXIST) {
+			return &os.PathError{Op: "mkdirat", Path: fullPath, Err: err}
+		}
+		next, err := openAt(cur, component, unix.O_PATH|syscall.O_DIRECTORY, 0)
+		if err != nil {
+			return &os.PathError{Op: "openat2", Path: fullPath, Err: err}
+		}
+		syscall.Close(cur)
+		cur = next
+	}
+
+	return nil
+}
+
+// renameBeneath renames srcRoot/srcRel to destRoot/destRel with both parent
+// directories resolved beneath their allowed directories
+func renameBeneath(srcRoot, srcRel, destRoot, destRel string) error {
+	srcPath := filepath.Join(srcRoot, srcRel)
+	destPath := filepath.Join(destRoot, destRel)
+	if !resolveBeneathSupported() {
+		return os.Rename(srcPath, destPath)
+	}
+
+	srcDir, err := openBeneath(srcRoot, filepath.Dir(srcRel), unix.O_PATH|syscall.O_DIRECTORY, 0)
+	if err != nil {
+		return err
+	}
+	defer srcDir.Close()
+
+	destDir, err := openBeneath(destRoot, filepath.Dir(destRel), unix.O_PATH|syscall.O_DIRECTORY, 0)
+	if err != nil {
+		return err
+	}
+	defer destDir.Close()
+
+	err = syscall.Renameat(int(srcDir.Fd()), filepath.Base(srcRel), int(destDir.Fd()), filepath.Base(destRel))
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: srcPath, New: destPath, Err: err}
+	}
+	return nil
+}
+
+// openParentBeneath opens the directory containing root/rel, resolved
+// beneath root, for *at syscalls on the final component of rel
+func openParentBeneath(root, rel string) (*os.File, error) {
+	return openBeneath(root, filepath.Dir(rel), unix.O_PATH|syscall.O_DIRECTORY, 0)
+}
+
+// readlinkBeneath returns the target of the symlink root/rel, its parent
+// directory resolved beneath root
+func readlinkBeneath(root, rel string) (string, error) {
+	fullPath := filepath.Join(root, rel)
+	if !resolveBeneathSupported() {
+		return os.Readlink(fullPath)
+	}
+
+	dir, err := openParentBeneath(root, rel)
+	if err != nil {
+		return "", err
+	}
+	defer dir.Close()
+
+	name, err := syscall.BytePtrFromString(filepath.Base(rel))
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, maxLinkTarget)
+	n, _, errno := syscall.Syscall6(syscall.SYS_READLINKAT,
+		dir.Fd(),
+		uintptr(unsafe.Pointer(name)),
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(len(buf)),
+		0, 0)
+	if errno != 0 {
+		return "", &os.PathError{Op: "readlinkat", Path: fullPath, Err: errno}
+	}
+	if int(n) >= len(buf) {
+		return "", &os.PathError{Op: "readlinkat", Path: fullPath, Err: syscall.ENAMETOOLONG}
+	}
+	return string(buf[:n]), nil
+}
+
+// symlinkBeneath creates root/rel as a symlink to target, its parent
+// directory resolved beneath root. target itself is stored as given.
+func symlinkBeneath(target, root, rel string) error {
+	fullPath := filepath.Join(root, rel)
+	if !resolveBeneathSupported() {
+		return os.Symlink(target, fullPath)
+	}
+
+	dir, err := openParentBeneath(root, rel)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	oldname, err := syscall.BytePtrFromString(target)
+	if err != nil {
+		return err
+	}
+	newname, err := syscall.BytePtrFromString(filepath.Base(rel))
+	if err != nil {
+		return err
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_SYMLINKAT,
+		uintptr(unsafe.Pointer(oldname)),
+		dir.Fd(),
+		uintptr(unsafe.Pointer(newname)))
+	if errno != 0 {
+		return &os.LinkError{Op: "symlinkat", Old: target, New: fullPath, Err: errno}
+	}
+	return nil
+}
+
+// removeAllBeneath removes root/rel and everything beneath it. Each entry
+// is removed relative to a descriptor of its parent directory, and
+// directories are entered without following symlinks, so a symlink swapped
+// in during the removal is unlinked rather than followed. A path that does
+// not exist is not an error, as with os.RemoveAll.
+func removeAllBeneath(root, rel string) error {
+	fullPath := filepath.Join(root, rel)
+	if !resolveBeneathSupported() {
+		return os.RemoveAll(fullPath)
+	}
+
+	dir, err := openParentBeneath(root, rel)
+	if err != nil {
+		if errors.Is(err, syscall.ENOENT) {
+			return nil
+		}
+		return err
+	}
+	defer dir.Close()
+
+	if err := removeAllAt(int(dir.Fd()), filepath.Base(rel), 0); err != nil {
+		return &os.PathError{Op: "unlinkat", Path: fullPath, Err: err}
+	}
+	return nil
+}
+
+// removeAllAt removes name in the directory dirfd together with its
+// contents, descending at most maxPathComponents levels per Rule 2
+func removeAllAt(dirfd int, name string, depth int) error {
+	err := unlinkAt(dirfd, name, 0)
+	if err == nil || errors.Is(err, syscall.ENOENT) {
+		return nil
+	}
+	if !errors.Is(err, syscall.EISDIR) && !errors.Is(err, syscall.EPERM) {
+		return err
+	}
+	if depth >= maxPathComponents {
+		return syscall.ENAMETOOLONG
+	}
+
+	fd, err := syscall.Openat(dirfd, name, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	sub := os.NewFile(uintptr(fd), name)
+	names, err := sub.Readdirnames(-1)
+	if err == nil {
+		for _, child := range names {
+			if err = removeAllAt(int(sub.Fd()), child, depth+1); err != nil {
+				break
+			}
+		}
+	}
+	sub.Close()
+	if err != nil {
+		return err
+	}
+
+	err = unlinkAt(dirfd, name, atRemoveDir)
+	if errors.Is(err, syscall.ENOENT) {
+		return nil
+	}
+	return err
+}
+
+// unlinkAt invokes unlinkat with flags, which syscall.Unlinkat does not take
+func unlinkAt(dirfd int, name string, flags int) error {
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return err
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_UNLINKAT, uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(flags))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
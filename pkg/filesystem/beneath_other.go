@@ -0,0 +1,50 @@
+//go:build !linux
+// +build !linux
+
+package filesystem
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// resolveBeneathSupported reports whether fd-anchored resolution is available
+func resolveBeneathSupported() bool {
+	return false
+}
+
+// openBeneath opens root/rel using path-based resolution
+func openBeneath(root, rel string, flag int, perm fs.FileMode) (*os.File, error) {
+	return os.OpenFile(filepath.Join(root, rel), flag, perm)
+}
+
+// statBeneath stats root/rel using path-based resolution
+func statBeneath(root, rel string) (os.FileInfo, error) {
+	return os.Stat(filepath.Join(root, rel))
+}
+
+// mkdirAllBeneath creates root/rel and its parents using path-based resolution
+func mkdirAllBeneath(root, rel string, perm fs.FileMode) error {
+	return os.MkdirAll(filepath.Join(root, rel), perm)
+}
+
+// renameBeneath renames srcRoot/srcRel to destRoot/destRel using path-based resolution
+func renameBeneath(srcRoot, srcRel, destRoot, destRel string) error {
+	return os.Rename(filepath.Join(srcRoot, srcRel), filepath.Join(destRoot, destRel))
+}
+
+// readlinkBeneath returns the target of the symlink root/rel using path-based resolution
+func readlinkBeneath(root, rel string) (string, error) {
+	return os.Readlink(filepath.Join(root, rel))
+}
+
+// symlinkBeneath creates root/rel as a symlink to target using path-based resolution
+func symlinkBeneath(target, root, rel string) error {
+	return os.Symlink(target, filepath.Join(root, rel))
+}
+
+// removeAllBeneath removes root/rel and its contents using path-based resolution
+func removeAllBeneath(root, rel string) error {
+	return os.RemoveAll(filepath.Join(root, rel))
+}
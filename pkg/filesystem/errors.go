@@ -0,0 +1,18 @@
+package filesystem
+
+import "errors"
+
+// Errors of the operations, matched with errors.Is
+var (
+	// ErrTooLarge refuses a file or content over the size limit
+	ErrTooLarge = errors.New("exceeds maximum allowed size")
+
+	// ErrTooDeep stops a walk nested deeper than the depth limit
+	ErrTooDeep = errors.New("maximum directory depth exceeded")
+
+	// ErrExists refuses a move onto an existing destination
+	ErrExists = errors.New("destination already exists")
+
+	// ErrNoMatch fails an edit whose old text is not in the file
+	ErrNoMatch = errors.New("could not find matching lines for edit")
+)
@@ -0,0 +1,45 @@
+package filesystem
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// openRegularFile opens a validated path for reading or writing its contents.
+// The file type is checked before opening so device nodes are never opened,
+// and again on the descriptor so a file swapped in between is caught.
+// O_NONBLOCK keeps a FIFO from blocking the open itself, and truncation is
+// deferred until the file has passed inspection.
+func (ops *Operations) openRegularFile(validPath string, flag int, perm fs.FileMode) (*os.File, error) {
+	if info, err := ops.statFile(validPath); err == nil {
+		if err := ops.pathValidator.CheckFileType(validPath, info); err != nil {
+			return nil, err
+		}
+	}
+
+	truncate := flag&os.O_TRUNC != 0
+	file, err := ops.openFile(validPath, (flag&^os.O_TRUNC)|syscall.O_NONBLOCK, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if err := ops.pathValidator.CheckOpenedFile(validPath, info); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if truncate {
+		if err := file.Truncate(0); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to truncate file: %w", err)
+		}
+	}
+	return file, nil
+}
@@ -0,0 +1,42 @@
+package filesystem
+
+import (
+	"path/filepath"
+)
+
+// checkNewName applies the naming policy to an entry about to be created
+// at validPath. exclude is an existing sibling the name may still match
+// by case, such as the source of a rename within one directory.
+func (ops *Operations) checkNewName(validPath, exclude string) error {
+	if !ops.naming.Enabled() {
+		return nil
+	}
+
+	name := filepath.Base(validPath)
+	if err := ops.naming.CheckName(name); err != nil {
+		ops.logger.Warn("Name rejected by naming policy", "path", validPath, "error", err)
+		return err
+	}
+
+	if !ops.naming.RejectCaseCollisions {
+		return nil
+	}
+
+	// A missing parent has no siblings to collide with
+	dir := filepath.Dir(validPath)
+	entries, err := ops.readDir(dir)
+	if err != nil {
+		return nil
+	}
+	siblings := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if filepath.Join(dir, entry.Name()) != exclude {
+			siblings = append(siblings, entry.Name())
+		}
+	}
+	if err := ops.naming.CaseCollision(name, siblings); err != nil {
+		ops.logger.Warn("Name rejected by naming policy", "path", validPath, "error", err)
+		return err
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package filesystem
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,6 +18,7 @@ import (
 	"github.com/bmatcuk/doublestar/v4"
 	"github.com/sergi/go-diff/diffmatchpatch"
 
+	"filesystem/pkg/secrets"
 	"filesystem/pkg/security"
 )
 
@@ -26,6 +28,9 @@ const maxWriteSize int64 = 1 * 1024 * 1024 // 1MB
 // maxTreeDepth defines the maximum depth DirectoryTree will recurse
 const maxTreeDepth int = 20
 
+// maxCopyDepth defines the maximum depth a cross-device move will copy
+const maxCopyDepth int = 1024
+
 // FileInfo represents detailed file information
 type FileInfo struct {
 	Size        int64     `json:"size"`
@@ -44,6 +49,20 @@ type TreeEntry struct {
 	Children *[]TreeEntry `json:"children,omitempty"`
 }
 
+// DirectoryEntry is one entry of a directory listing
+type DirectoryEntry struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// FileContent is the outcome of reading one file of a batch: its content,
+// or the error that prevented reading it
+type FileContent struct {
+	Path    string
+	Content string
+	Err     error
+}
+
 // EditOperation represents a file edit operation
 type EditOperation struct {
 	OldText string `json:"oldText"`
@@ -54,108 +73,215 @@ type EditOperation struct {
 type Operations struct {
 	logger        *slog.Logger
 	pathValidator *security.PathValidator
+	secrets       *secrets.Scanner
+	naming        security.NamingPolicy
+}
+
+// Options configures optional filesystem operation behavior
+type Options struct {
+	// Secrets scans content returned by ReadFile and ReadMultipleFiles;
+	// nil returns content unchanged
+	Secrets *secrets.Scanner
+
+	// Naming restricts the names of created and renamed entries
+	Naming security.NamingPolicy
 }
 
 // NewOperations creates a new filesystem operations instance
 func NewOperations(validator *security.PathValidator, logger *slog.Logger) *Operations {
+	return NewOperationsWithOptions(validator, Options{}, logger)
+}
+
+// NewOperationsWithOptions creates a filesystem operations instance with options
+func NewOperationsWithOptions(validator *security.PathValidator, opts Options, logger *slog.Logger) *Operations {
+	logger.Debug("Filesystem path resolution configured",
+		"resolve_beneath", resolveBeneathSupported())
+
 	return &Operations{
 		logger:        logger,
 		pathValidator: validator,
+		secrets:       opts.Secrets,
+		naming:        opts.Naming,
+	}
+}
+
+// ReadFile reads a file's content, masking or refusing secrets when a
+// scanner is configured
+func (ops *Operations) ReadFile(ctx context.Context, filePath string) (string, error) {
+	content, validPath, err := ops.readFile(ctx, filePath)
+	if err != nil {
+		return "", err
+	}
+	return ops.scanSecrets(validPath, content)
+}
+
+// scanSecrets logs each secret found in content and applies the scanner's
+// action
+func (ops *Operations) scanSecrets(validPath, content string) (string, error) {
+	masked, findings := ops.secrets.Scan(content)
+	if len(findings) == 0 {
+		return content, nil
+	}
+
+	for _, finding := range findings {
+		ops.logger.Warn("Secret detected in file",
+			"path", validPath,
+			"line", finding.Line,
+			"pattern", finding.Pattern)
 	}
+
+	if ops.secrets.Refuses() {
+		first := findings[0]
+		return "", fmt.Errorf("%w - %s contains %d possible secrets (first: %s at line %d)", security.ErrAccessDenied,
+			validPath, len(findings), first.Pattern, first.Line)
+	}
+	return masked, nil
 }
 
-// ReadFile reads a file's content
-func (ops *Operations) ReadFile(filePath string) (string, error) {
+// readFile reads a file's raw content and returns it with the validated path
+func (ops *Operations) readFile(ctx context.Context, filePath string) (string, string, error) {
 	// Input validation per Rule 7
 	if filePath == "" {
-		return "", fmt.Errorf("file path cannot be empty")
+		return "", "", fmt.Errorf("file path cannot be empty")
+	}
+	if err := ctx.Err(); err != nil {
+		return "", "", err
 	}
 
-	validPath, err := ops.pathValidator.ValidatePath(filePath)
+	validPath, err := ops.pathValidator.ValidatePath(filePath, security.OpRead)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	ops.logger.Debug("Reading file", "path", validPath)
+	ops.logger.DebugContext(ctx, "Reading file", "path", validPath)
 
-	info, err := os.Stat(validPath)
+	// Open once and stat the descriptor so the checked file is the one read
+	file, err := ops.openRegularFile(validPath, os.O_RDONLY, 0)
 	if err != nil {
-		ops.logger.Error("Failed to stat file", "path", validPath, "error", err)
-		return "", fmt.Errorf("failed to stat file: %w", err)
+		ops.logger.ErrorContext(ctx, "Failed to open file", "path", validPath, "error", err)
+		return "", "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		ops.logger.ErrorContext(ctx, "Failed to stat file", "path", validPath, "error", err)
+		return "", "", fmt.Errorf("failed to stat file: %w", err)
 	}
 
 	if info.Size() > maxReadSize {
-		ops.logger.Warn("File size exceeds limit", "path", validPath, "size", info.Size())
-		return "", fmt.Errorf("file exceeds maximum allowed size")
+		ops.logger.WarnContext(ctx, "File size exceeds limit", "path", validPath, "size", info.Size())
+		return "", "", fmt.Errorf("file %w", ErrTooLarge)
 	}
 
-	data, err := os.ReadFile(validPath)
+	data, err := io.ReadAll(io.LimitReader(contextReader{ctx: ctx, r: file}, maxReadSize))
 	if err != nil {
-		ops.logger.Error("Failed to read file", "path", validPath, "error", err)
-		return "", fmt.Errorf("failed to read file: %w", err)
+		ops.logger.ErrorContext(ctx, "Failed to read file", "path", validPath, "error", err)
+		return "", "", fmt.Errorf("failed to read file: %w", err)
 	}
 
-	ops.logger.Debug("File read successfully", "path", validPath, "size", len(data))
-	return string(data), nil
+	ops.logger.DebugContext(ctx, "File read successfully", "path", validPath, "size", len(data))
+	return string(data), validPath, nil
 }
 
 // ReadMultipleFiles reads multiple files and returns their contents
-func (ops *Operations) ReadMultipleFiles(filePaths []string) (string, error) {
+func (ops *Operations) ReadMultipleFiles(ctx context.Context, filePaths []string) (string, error) {
+	files, err := ops.ReadFiles(ctx, filePaths)
+	if err != nil {
+		return "", err
+	}
+
+	return FormatFileContents(files), nil
+}
+
+// FormatFileContents formats a batch read as ReadMultipleFiles returns it
+func FormatFileContents(files []FileContent) string {
+	results := make([]string, 0, len(files))
+	for _, file := range files {
+		if file.Err != nil {
+			results = append(results, fmt.Sprintf("%s: Error - %s", file.Path, file.Err.Error()))
+			continue
+		}
+		results = append(results, fmt.Sprintf("%s:\n%s\n", file.Path, file.Content))
+	}
+	return strings.Join(results, "\n---\n")
+}
+
+// ReadFiles reads multiple files, reporting each file's content or error
+func (ops *Operations) ReadFiles(ctx context.Context, filePaths []string) ([]FileContent, error) {
 	// Input validation per Rule 7
 	if len(filePaths) == 0 {
-		return "", fmt.Errorf("no file paths provided")
+		return nil, fmt.Errorf("no file paths provided")
 	}
 
-	results := make([]string, 0, len(filePaths))
+	files := make([]FileContent, 0, len(filePaths))
 
 	// Process files
 	for _, filePath := range filePaths {
+		// A cancelled batch fails as a whole rather than per file
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 
-		content, err := ops.ReadFile(filePath)
+		content, err := ops.ReadFile(ctx, filePath)
 		if err != nil {
 			// Continue processing other files even if one fails
-			result := fmt.Sprintf("%s: Error - %s", filePath, err.Error())
-			results = append(results, result)
-			ops.logger.Warn("Failed to read file in batch", "path", filePath, "error", err)
-		} else {
-			result := fmt.Sprintf("%s:\n%s\n", filePath, content)
-			results = append(results, result)
+			ops.logger.WarnContext(ctx, "Failed to read file in batch", "path", filePath, "error", err)
 		}
+		files = append(files, FileContent{Path: filePath, Content: content, Err: err})
 	}
 
-	return strings.Join(results, "\n---\n"), nil
+	return files, nil
 }
 
 // WriteFile writes content to a file
-func (ops *Operations) WriteFile(filePath, content string) error {
+func (ops *Operations) WriteFile(ctx context.Context, filePath, content string) error {
 	// Input validation per Rule 7
 	if filePath == "" {
 		return fmt.Errorf("file path cannot be empty")
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-	validPath, err := ops.pathValidator.ValidatePath(filePath)
+	validPath, err := ops.pathValidator.ValidatePath(filePath, security.OpWrite)
 	if err != nil {
 		return err
 	}
 
 	if int64(len(content)) > maxWriteSize {
-		ops.logger.Warn("Content size exceeds limit", "path", validPath, "size", len(content))
-		return fmt.Errorf("content exceeds maximum allowed size")
+		ops.logger.WarnContext(ctx, "Content size exceeds limit", "path", validPath, "size", len(content))
+		return fmt.Errorf("content %w", ErrTooLarge)
+	}
+
+	// Only new files are subject to the naming policy
+	if _, err := ops.statFile(validPath); os.IsNotExist(err) {
+		if err := ops.checkNewName(validPath, ""); err != nil {
+			return err
+		}
 	}
 
-	ops.logger.Debug("Writing file", "path", validPath, "size", len(content))
-	err = os.WriteFile(validPath, []byte(content), 0644)
+	ops.logger.DebugContext(ctx, "Writing file", "path", validPath, "size", len(content))
+	file, err := ops.openRegularFile(validPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
-		ops.logger.Error("Failed to write file", "path", validPath, "error", err)
+		ops.logger.ErrorContext(ctx, "Failed to open file for writing", "path", validPath, "error", err)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	_, err = file.WriteString(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		ops.logger.ErrorContext(ctx, "Failed to write file", "path", validPath, "error", err)
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
-	ops.logger.Info("File written successfully", "path", validPath, "size", len(content))
+	ops.logger.InfoContext(ctx, "File written successfully", "path", validPath, "size", len(content))
 	return nil
 }
 
 // EditFile applies edits to a file and returns a diff
-func (ops *Operations) EditFile(filePath string, edits []EditOperation, dryRun bool) (string, error) {
+func (ops *Operations) EditFile(ctx context.Context, filePath string, edits []EditOperation, dryRun bool) (string, error) {
 	// Input validation per Rule 7
 	if filePath == "" {
 		return "", fmt.Errorf("file path cannot be empty")
@@ -164,15 +290,15 @@ func (ops *Operations) EditFile(filePath string, edits []EditOperation, dryRun b
 		return "", fmt.Errorf("no edits provided")
 	}
 
-	validPath, err := ops.pathValidator.ValidatePath(filePath)
+	validPath, err := ops.pathValidator.ValidatePath(filePath, security.OpWrite)
 	if err != nil {
 		return "", err
 	}
 
-	ops.logger.Debug("Editing file", "path", validPath, "edits_count", len(edits), "dry_run", dryRun)
+	ops.logger.DebugContext(ctx, "Editing file", "path", validPath, "edits_count", len(edits), "dry_run", dryRun)
 
-	// Read original content
-	originalContent, err := ops.ReadFile(validPath)
+	// Read original content; edits must apply to the unmasked text
+	originalContent, _, err := ops.readFile(ctx, validPath)
 	if err != nil {
 		return "", err
 	}
@@ -188,16 +314,19 @@ func (ops *Operations) EditFile(filePath string, edits []EditOperation, dryRun b
 
 	// Write file if not dry run
 	if !dryRun {
-		err = ops.WriteFile(validPath, modifiedContent)
+		err = ops.WriteFile(ctx, validPath, modifiedContent)
 		if err != nil {
 			return "", err
 		}
-		ops.logger.Info("File edits applied", "path", validPath, "edits_count", len(edits))
+		ops.logger.InfoContext(ctx, "File edits applied", "path", validPath, "edits_count", len(edits))
 	} else {
-		ops.logger.Debug("Dry run completed", "path", validPath)
+		ops.logger.DebugContext(ctx, "Dry run completed", "path", validPath)
 	}
 
-	return diff, nil
+	// The diff carries file content back to the client, so secrets in it
+	// are masked even when reads would be refused
+	maskedDiff, _ := ops.secrets.Scan(diff)
+	return maskedDiff, nil
 }
 
 // applyEdits applies a series of edits to content
@@ -248,7 +377,7 @@ func (ops *Operations) applyLineBasedEdit(content *string, oldText, newText stri
 		}
 	}
 
-	return fmt.Errorf("could not find matching lines for edit")
+	return ErrNoMatch
 }
 
 // linesMatch checks if two line slices match with whitespace normalization
@@ -301,96 +430,161 @@ func (ops *Operations) createUnifiedDiff(original, modified, filename string) st
 }
 
 // CreateDirectory creates a directory and all parent directories
-func (ops *Operations) CreateDirectory(dirPath string) error {
+func (ops *Operations) CreateDirectory(ctx context.Context, dirPath string) error {
 	// Input validation per Rule 7
 	if dirPath == "" {
 		return fmt.Errorf("directory path cannot be empty")
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-	validPath, err := ops.pathValidator.ValidatePath(dirPath)
+	validPath, err := ops.pathValidator.ValidatePath(dirPath, security.OpWrite)
 	if err != nil {
 		return err
 	}
 
-	ops.logger.Debug("Creating directory", "path", validPath)
+	if _, err := ops.statFile(validPath); os.IsNotExist(err) {
+		if err := ops.checkNewName(validPath, ""); err != nil {
+			return err
+		}
+	}
+
+	ops.logger.DebugContext(ctx, "Creating directory", "path", validPath)
 
-	err = os.MkdirAll(validPath, 0755)
+	err = ops.mkdirAll(validPath, 0755)
 	if err != nil {
-		ops.logger.Error("Failed to create directory", "path", validPath, "error", err)
+		ops.logger.ErrorContext(ctx, "Failed to create directory", "path", validPath, "error", err)
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	ops.logger.Info("Directory created successfully", "path", validPath)
+	ops.logger.InfoContext(ctx, "Directory created successfully", "path", validPath)
 	return nil
 }
 
 // ListDirectory lists the contents of a directory
-func (ops *Operations) ListDirectory(dirPath string) (string, error) {
+func (ops *Operations) ListDirectory(ctx context.Context, dirPath string) (string, error) {
+	entries, err := ops.ListDirectoryEntries(ctx, dirPath)
+	if err != nil {
+		return "", err
+	}
+
+	return FormatListing(entries), nil
+}
+
+// FormatListing formats directory entries as ListDirectory returns them
+func FormatListing(entries []DirectoryEntry) string {
+	results := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		prefix := "[FILE]"
+		if entry.Type == "directory" {
+			prefix = "[DIR]"
+		}
+		results = append(results, fmt.Sprintf("%s %s", prefix, entry.Name))
+	}
+	return strings.Join(results, "\n")
+}
+
+// ListDirectoryEntries returns the entries of a directory
+func (ops *Operations) ListDirectoryEntries(ctx context.Context, dirPath string) ([]DirectoryEntry, error) {
 	// Input validation per Rule 7
 	if dirPath == "" {
-		return "", fmt.Errorf("directory path cannot be empty")
+		return nil, fmt.Errorf("directory path cannot be empty")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	validPath, err := ops.pathValidator.ValidatePath(dirPath, security.OpRead)
+	if err != nil {
+		return nil, err
 	}
 
-	ops.logger.Debug("Listing directory", "path", dirPath)
+	ops.logger.DebugContext(ctx, "Listing directory", "path", validPath)
 
-	entries, err := os.ReadDir(dirPath)
+	entries, err := ops.readDir(validPath)
 	if err != nil {
-		ops.logger.Error("Failed to read directory", "path", dirPath, "error", err)
-		return "", fmt.Errorf("failed to read directory: %w", err)
+		ops.logger.ErrorContext(ctx, "Failed to read directory", "path", validPath, "error", err)
+		return nil, fmt.Errorf("failed to read directory: %w", err)
 	}
 
-	results := make([]string, 0, len(entries))
+	results := make([]DirectoryEntry, 0, len(entries))
 
 	// Process entries
 	for _, entry := range entries {
-		prefix := "[FILE]"
+		// Hide entries matching deny patterns
+		if !ops.pathValidator.ShouldList(filepath.Join(validPath, entry.Name())) {
+			continue
+		}
+
+		entryType := "file"
 		if entry.IsDir() {
-			prefix = "[DIR]"
+			entryType = "directory"
 		}
-		results = append(results, fmt.Sprintf("%s %s", prefix, entry.Name()))
+		results = append(results, DirectoryEntry{Name: entry.Name(), Type: entryType})
 	}
 
-	ops.logger.Debug("Directory listed successfully", "path", dirPath, "entries_count", len(results))
-	return strings.Join(results, "\n"), nil
+	ops.logger.DebugContext(ctx, "Directory listed successfully", "path", dirPath, "entries_count", len(results))
+	return results, nil
 }
 
 // DirectoryTree returns a JSON representation of a directory tree
-func (ops *Operations) DirectoryTree(dirPath string) (string, error) {
-	// Input validation per Rule 7
-	if dirPath == "" {
-		return "", fmt.Errorf("directory path cannot be empty")
+func (ops *Operations) DirectoryTree(ctx context.Context, dirPath string) (string, error) {
+	tree, err := ops.DirectoryTreeEntries(ctx, dirPath)
+	if err != nil {
+		return "", err
 	}
 
-	validPath, err := ops.pathValidator.ValidatePath(dirPath)
+	text, err := FormatTree(tree)
 	if err != nil {
+		ops.logger.ErrorContext(ctx, "Failed to marshal tree to JSON", "error", err)
 		return "", err
 	}
+	return text, nil
+}
 
-	ops.logger.Debug("Building directory tree", "path", validPath)
+// FormatTree formats a tree as the indented JSON DirectoryTree returns
+func FormatTree(tree []TreeEntry) (string, error) {
+	jsonData, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to create JSON tree: %w", err)
+	}
+	return string(jsonData), nil
+}
 
-	// Track visited real paths to avoid infinite recursion
-	visited := make(map[string]bool)
+// DirectoryTreeEntries returns the tree of entries beneath a directory
+func (ops *Operations) DirectoryTreeEntries(ctx context.Context, dirPath string) ([]TreeEntry, error) {
+	// Input validation per Rule 7
+	if dirPath == "" {
+		return nil, fmt.Errorf("directory path cannot be empty")
+	}
 
-	tree, err := ops.buildTree(validPath, visited, 0)
+	validPath, err := ops.pathValidator.ValidatePath(dirPath, security.OpRead)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// Convert to JSON
-	jsonData, err := json.MarshalIndent(tree, "", "  ")
+	ops.logger.DebugContext(ctx, "Building directory tree", "path", validPath)
+
+	// Track visited real paths to avoid infinite recursion
+	visited := make(map[string]bool)
+
+	tree, err := ops.buildTree(ctx, newProgress(ctx, false), validPath, visited, 0)
 	if err != nil {
-		ops.logger.Error("Failed to marshal tree to JSON", "error", err)
-		return "", fmt.Errorf("failed to create JSON tree: %w", err)
+		return nil, err
 	}
 
-	ops.logger.Debug("Directory tree built successfully", "path", dirPath)
-	return string(jsonData), nil
+	ops.logger.DebugContext(ctx, "Directory tree built successfully", "path", dirPath)
+	return tree, nil
 }
 
 // buildTree recursively builds a tree structure
-func (ops *Operations) buildTree(dirPath string, visited map[string]bool, depth int) ([]TreeEntry, error) {
+func (ops *Operations) buildTree(ctx context.Context, progress *progressReporter, dirPath string, visited map[string]bool, depth int) ([]TreeEntry, error) {
 	if depth > maxTreeDepth {
-		return nil, fmt.Errorf("maximum directory depth exceeded")
+		return nil, ErrTooDeep
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 	realPath, err := filepath.EvalSymlinks(dirPath)
 	if err != nil {
@@ -404,12 +598,12 @@ func (ops *Operations) buildTree(dirPath string, visited map[string]bool, depth
 	}
 
 	if visited[realPath] {
-		ops.logger.Debug("Skipping already visited path", "path", realPath)
+		ops.logger.DebugContext(ctx, "Skipping already visited path", "path", realPath)
 		return []TreeEntry{}, nil
 	}
 	visited[realPath] = true
 
-	entries, err := os.ReadDir(dirPath)
+	entries, err := ops.readDir(dirPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read directory: %w", err)
 	}
@@ -418,26 +612,41 @@ func (ops *Operations) buildTree(dirPath string, visited map[string]bool, depth
 
 	// Process entries
 	for _, entry := range entries {
+		subPath := filepath.Join(dirPath, entry.Name())
+		progress.entry(dirPath)
+
+		// Hide entries matching deny patterns or the symlink policy
+		if !ops.pathValidator.ShouldList(subPath) {
+			continue
+		}
 
 		treeEntry := TreeEntry{
 			Name: entry.Name(),
 			Type: "file",
 		}
 
-		if entry.IsDir() {
+		// Links are only descended into when the symlink policy follows them
+		isDir := entry.IsDir()
+		if entry.Type()&fs.ModeSymlink != 0 {
+			isDir = ops.isFollowableDir(subPath)
+		}
+
+		if isDir {
 			treeEntry.Type = "directory"
 
 			// Recursively build subtree
-			subPath := filepath.Join(dirPath, entry.Name())
-			validPath, err := ops.pathValidator.ValidatePath(subPath)
+			validPath, err := ops.pathValidator.ValidatePath(subPath, security.OpRead)
 			if err != nil {
-				ops.logger.Warn("Path validation failed", "path", subPath, "error", err)
+				ops.logger.WarnContext(ctx, "Path validation failed", "path", subPath, "error", err)
 				// Skip this directory if validation fails
 				continue
 			}
-			children, err := ops.buildTree(validPath, visited, depth+1)
+			children, err := ops.buildTree(ctx, progress, validPath, visited, depth+1)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
 			if err != nil {
-				ops.logger.Warn("Failed to build subtree", "path", subPath, "error", err)
+				ops.logger.WarnContext(ctx, "Failed to build subtree", "path", subPath, "error", err)
 				// Continue with empty children rather than failing
 				children = []TreeEntry{}
 			}
@@ -450,8 +659,25 @@ func (ops *Operations) buildTree(dirPath string, visited map[string]bool, depth
 	return result, nil
 }
 
-// MoveFile moves or renames a file or directory
-func (ops *Operations) MoveFile(sourcePath, destPath string) error {
+// isFollowableDir reports whether a symlink may be dereferenced under its
+// directory's symlink policy and resolves to a directory
+func (ops *Operations) isFollowableDir(linkPath string) bool {
+	if !ops.pathValidator.SymlinkPolicyFor(linkPath).Follows() {
+		return false
+	}
+	validPath, err := ops.pathValidator.ValidatePath(linkPath, security.OpRead)
+	if err != nil {
+		ops.logger.Debug("Not following symlink", "path", linkPath, "error", err)
+		return false
+	}
+	info, err := os.Stat(validPath)
+	return err == nil && info.IsDir()
+}
+
+// MoveFile moves or renames a file or directory. When ctx ends during the
+// copy of a cross-device move, or the copy fails, the partial copy is
+// removed again so the source stays as it was.
+func (ops *Operations) MoveFile(ctx context.Context, sourcePath, destPath string) error {
 	// Input validation per Rule 7
 	if sourcePath == "" {
 		return fmt.Errorf("source path cannot be empty")
@@ -460,100 +686,169 @@ func (ops *Operations) MoveFile(sourcePath, destPath string) error {
 		return fmt.Errorf("destination path cannot be empty")
 	}
 
-	srcValid, err := ops.pathValidator.ValidatePath(sourcePath)
+	// Moving removes the source entry, so it requires delete access
+	srcValid, err := ops.pathValidator.ValidatePath(sourcePath, security.OpDelete)
 	if err != nil {
 		return err
 	}
-	destValid, err := ops.pathValidator.ValidatePath(destPath)
+	destValid, err := ops.pathValidator.ValidatePath(destPath, security.OpWrite)
 	if err != nil {
 		return err
 	}
 
-	ops.logger.Debug("Moving file", "source", srcValid, "destination", destValid)
+	ops.logger.DebugContext(ctx, "Moving file", "source", srcValid, "destination", destValid)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	// Check if destination already exists to avoid overwriting
-	if _, err := os.Stat(destValid); err == nil {
-		ops.logger.Warn("Destination already exists", "path", destValid)
-		return fmt.Errorf("destination already exists")
+	if _, err := ops.statFile(destValid); err == nil {
+		ops.logger.WarnContext(ctx, "Destination already exists", "path", destValid)
+		return ErrExists
 	} else if !os.IsNotExist(err) {
-		ops.logger.Error("Failed to check destination", "path", destValid, "error", err)
+		ops.logger.ErrorContext(ctx, "Failed to check destination", "path", destValid, "error", err)
 		return fmt.Errorf("failed to check destination: %w", err)
 	}
 
-	err = os.Rename(srcValid, destValid)
+	// Renaming only the case of a name must not collide with itself
+	if err := ops.checkNewName(destValid, srcValid); err != nil {
+		return err
+	}
+
+	err = ops.rename(srcValid, destValid)
 	if err != nil {
 		// Detect cross-device rename and fallback to copy/remove
 		if linkErr, ok := err.(*os.LinkError); ok && errors.Is(linkErr.Err, syscall.EXDEV) {
-			ops.logger.Debug("Cross-device rename detected, falling back to copy", "source", srcValid, "destination", destValid)
-
-			if copyErr := copyRecursive(srcValid, destValid); copyErr != nil {
-				ops.logger.Error("Copy fallback failed", "error", copyErr)
+			ops.logger.DebugContext(ctx, "Cross-device rename detected, falling back to copy", "source", srcValid, "destination", destValid)
+
+			if copyErr := ops.copyRecursive(ctx, newProgress(ctx, true), srcValid, destValid); copyErr != nil {
+				ops.logger.ErrorContext(ctx, "Copy fallback failed", "error", copyErr)
+				// The destination did not exist before, so all of it is ours
+				if rmErr := ops.removeAll(destValid); rmErr != nil {
+					ops.logger.ErrorContext(ctx, "Failed to remove partial copy", "path", destValid, "error", rmErr)
+				}
 				return fmt.Errorf("failed to copy during move: %w", copyErr)
 			}
-			if rmErr := os.RemoveAll(srcValid); rmErr != nil {
-				ops.logger.Error("Failed to remove source after copy", "error", rmErr)
+			if rmErr := ops.removeAll(srcValid); rmErr != nil {
+				ops.logger.ErrorContext(ctx, "Failed to remove source after copy", "error", rmErr)
 				return fmt.Errorf("failed to remove source after copy: %w", rmErr)
 			}
 		} else {
-			ops.logger.Error("Failed to move file", "source", srcValid, "destination", destValid, "error", err)
+			ops.logger.ErrorContext(ctx, "Failed to move file", "source", srcValid, "destination", destValid, "error", err)
 			return fmt.Errorf("failed to move file: %w", err)
 		}
 	}
 
-	ops.logger.Info("File moved successfully", "source", srcValid, "destination", destValid)
+	ops.logger.InfoContext(ctx, "File moved successfully", "source", srcValid, "destination", destValid)
 	return nil
 }
 
 // copyRecursive copies a file or directory from src to dst.
-// It preserves file permissions and directory structure.
-func copyRecursive(src, dst string) error {
-	info, err := os.Stat(src)
+// It preserves file permissions and directory structure. Every file is
+// opened, created and listed anchored on its allowed directory, so a
+// symlink swapped into either tree during the copy cannot redirect it.
+func (ops *Operations) copyRecursive(ctx context.Context, progress *progressReporter, src, dst string) error {
+	info, err := ops.statFile(src)
 	if err != nil {
 		return err
 	}
 	if info.IsDir() {
-		return copyDir(src, dst)
+		return ops.copyDir(ctx, progress, src, dst)
 	}
-	return copyFile(src, dst, info.Mode())
+	progress.entry(filepath.Dir(src))
+	return ops.copyFile(ctx, progress, src, dst)
 }
 
 // copyDir recursively copies a directory tree.
-func copyDir(srcDir, dstDir string) error {
-	return filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		rel, err := filepath.Rel(srcDir, path)
-		if err != nil {
-			return err
-		}
-		target := filepath.Join(dstDir, rel)
-		info, err := d.Info()
-		if err != nil {
-			return err
+func (ops *Operations) copyDir(ctx context.Context, progress *progressReporter, srcDir, dstDir string) error {
+	return ops.copyTree(ctx, progress, srcDir, dstDir, 0)
+}
+
+// copyTree copies the directory srcDir, depth levels below the top of the
+// copy, to dstDir
+func (ops *Operations) copyTree(ctx context.Context, progress *progressReporter, srcDir, dstDir string, depth int) error {
+	if depth > maxCopyDepth {
+		return ErrTooDeep
+	}
+	progress.entry(srcDir)
+	info, err := ops.statFile(srcDir)
+	if err != nil {
+		return err
+	}
+	if err := ops.mkdirAll(dstDir, info.Mode().Perm()); err != nil {
+		return err
+	}
+	entries, err := ops.readDir(srcDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(srcDir, entry.Name())
+		target := filepath.Join(dstDir, entry.Name())
+		switch {
+		case entry.IsDir():
+			if err := ops.copyTree(ctx, progress, path, target, depth+1); err != nil {
+				return err
+			}
+		case entry.Type()&fs.ModeSymlink != 0:
+			if err := ops.copySymlink(ctx, progress, path, target); err != nil {
+				return err
+			}
+		default:
+			if err := ops.copyFile(ctx, progress, path, target); err != nil {
+				return err
+			}
 		}
-		if d.IsDir() {
-			return os.MkdirAll(target, info.Mode())
+	}
+	return nil
+}
+
+// copySymlink copies the target of a link when the symlink policy allows
+// dereferencing it and the target is an allowed regular file. Otherwise the
+// link itself is recreated, as a same-device rename would have kept it.
+func (ops *Operations) copySymlink(ctx context.Context, progress *progressReporter, src, dst string) error {
+	if ops.pathValidator.SymlinkPolicyFor(src).Follows() {
+		validPath, err := ops.pathValidator.ValidatePath(src, security.OpRead)
+		if err == nil {
+			info, statErr := ops.statFile(validPath)
+			if statErr == nil && info.Mode().IsRegular() {
+				return ops.copyFile(ctx, progress, validPath, dst)
+			}
 		}
-		return copyFile(path, target, info.Mode())
-	})
+	}
+
+	linkTarget, err := ops.readlink(src)
+	if err != nil {
+		return err
+	}
+	if err := ops.mkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	return ops.symlink(linkTarget, dst)
 }
 
-// copyFile copies a single file from src to dst using the provided permissions.
-func copyFile(src, dst string, perm fs.FileMode) error {
-	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+// copyFile copies a single regular file from src to dst with the
+// permissions of src. FIFOs and device nodes are refused as they would
+// block or copy unbounded data. The copy stops with ctx's error once ctx
+// is done.
+func (ops *Operations) copyFile(ctx context.Context, progress *progressReporter, src, dst string) error {
+	if err := ops.mkdirAll(filepath.Dir(dst), 0750); err != nil {
 		return err
 	}
-	in, err := os.Open(src)
+	in, err := ops.openRegularFile(src, os.O_RDONLY, 0)
 	if err != nil {
 		return err
 	}
 	defer in.Close()
-	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := ops.openRegularFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
 	if err != nil {
 		return err
 	}
-	if _, err := io.Copy(out, in); err != nil {
+	if _, err := io.Copy(out, contextReader{ctx: ctx, r: in, progress: progress}); err != nil {
 		if cerr := out.Close(); cerr != nil {
 			return fmt.Errorf("copy error: %v; close error: %v", err, cerr)
 		}
@@ -562,8 +857,37 @@ func copyFile(src, dst string, perm fs.FileMode) error {
 	return out.Close()
 }
 
+// contextReader fails reads once its context is done, counting the bytes
+// read as progress
+type contextReader struct {
+	ctx      context.Context
+	r        io.Reader
+	progress *progressReporter
+}
+
+// Read implements io.Reader
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := c.r.Read(p)
+	c.progress.copied(n)
+	return n, err
+}
+
+// fileSearch holds the state of one SearchFiles call
+type fileSearch struct {
+	ctx      context.Context
+	progress *progressReporter
+	root     string
+	pattern  string
+	excludes []string
+	visited  map[string]bool
+	results  []string
+}
+
 // SearchFiles recursively searches for files matching a pattern
-func (ops *Operations) SearchFiles(rootPath, pattern string, excludePatterns []string) ([]string, error) {
+func (ops *Operations) SearchFiles(ctx context.Context, rootPath, pattern string, excludePatterns []string) ([]string, error) {
 	// Input validation per Rule 7
 	if rootPath == "" {
 		return nil, fmt.Errorf("root path cannot be empty")
@@ -572,20 +896,64 @@ func (ops *Operations) SearchFiles(rootPath, pattern string, excludePatterns []s
 		return nil, fmt.Errorf("search pattern cannot be empty")
 	}
 
-	ops.logger.Debug("Searching files", "root", rootPath, "pattern", pattern, "excludes", excludePatterns)
+	ops.logger.DebugContext(ctx, "Searching files", "root", rootPath, "pattern", pattern, "excludes", excludePatterns)
+
+	search := &fileSearch{
+		ctx:      ctx,
+		progress: newProgress(ctx, false),
+		root:     rootPath,
+		pattern:  strings.ToLower(pattern),
+		excludes: excludePatterns,
+		visited:  make(map[string]bool),
+	}
+
+	if err := ops.searchDir(search, rootPath, rootPath, 0); err != nil {
+		ops.logger.ErrorContext(ctx, "Failed to search files", "error", err)
+		return nil, fmt.Errorf("failed to search files: %w", err)
+	}
 
-	var results []string
-	lowerPattern := strings.ToLower(pattern)
+	ops.logger.DebugContext(ctx, "File search completed", "root", rootPath, "results_count", len(search.results))
+	return search.results, nil
+}
 
-	err := filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
+// searchDir walks walkRoot, reporting matches under displayRoot so results
+// reached through a followed symlink keep the link's path
+func (ops *Operations) searchDir(search *fileSearch, walkRoot, displayRoot string, depth int) error {
+	if depth > maxTreeDepth {
+		ops.logger.WarnContext(search.ctx, "Maximum search depth exceeded", "path", displayRoot)
+		return nil
+	}
+	if realRoot, err := filepath.EvalSymlinks(walkRoot); err == nil {
+		if search.visited[realRoot] {
+			return nil
+		}
+		search.visited[realRoot] = true
+	}
+
+	return filepath.WalkDir(walkRoot, func(path string, d fs.DirEntry, err error) error {
+		// Checked per entry, as a single directory may hold many
+		if ctxErr := search.ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		if err != nil {
-			ops.logger.Warn("Error walking directory", "path", path, "error", err)
+			ops.logger.WarnContext(search.ctx, "Error walking directory", "path", path, "error", err)
 			return nil // Continue walking
 		}
 
-		// Validate each path before processing to ensure we stay within allowed directories
-		if _, valErr := ops.pathValidator.ValidatePath(path); valErr != nil {
-			ops.logger.Warn("Path validation failed", "path", path, "error", valErr)
+		displayPath := displayRoot
+		if rel, relErr := filepath.Rel(walkRoot, path); relErr == nil && rel != "." {
+			displayPath = filepath.Join(displayRoot, rel)
+		}
+		if d.IsDir() {
+			search.progress.entry(displayPath)
+		} else {
+			search.progress.entry(filepath.Dir(displayPath))
+		}
+
+		// Silently skip paths hidden by deny patterns or the symlink policy;
+		// the log stays off the client so it cannot reveal them
+		if !ops.pathValidator.ShouldList(path) {
+			ops.logger.Debug("Skipping hidden path", "path", path)
 			if d.IsDir() {
 				return filepath.SkipDir
 			}
@@ -593,30 +961,47 @@ func (ops *Operations) SearchFiles(rootPath, pattern string, excludePatterns []s
 		}
 
 		// Check exclude patterns
-		relativePath, relErr := filepath.Rel(rootPath, path)
-		if relErr == nil && ops.shouldExclude(relativePath, excludePatterns) {
+		relativePath, relErr := filepath.Rel(search.root, displayPath)
+		if relErr == nil && ops.shouldExclude(relativePath, search.excludes) {
 			if d.IsDir() {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
-		// Check if filename matches pattern
-		filename := strings.ToLower(d.Name())
-		if strings.Contains(filename, lowerPattern) {
-			results = append(results, path)
+		// Links that may not be followed are matched by name but never resolved
+		isLink := d.Type()&fs.ModeSymlink != 0
+		if isLink && !ops.pathValidator.SymlinkPolicyFor(path).Follows() {
+			search.match(d.Name(), displayPath)
+			return nil
 		}
 
+		// Validate each path before processing to ensure we stay within allowed directories
+		validPath, valErr := ops.pathValidator.ValidatePath(path, security.OpRead)
+		if valErr != nil {
+			ops.logger.WarnContext(search.ctx, "Path validation failed", "path", path, "error", valErr)
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		search.match(d.Name(), displayPath)
+
+		// WalkDir does not descend into links, so follow permitted ones here
+		if isLink && ops.isFollowableDir(path) {
+			return ops.searchDir(search, validPath, displayPath, depth+1)
+		}
 		return nil
 	})
+}
 
-	if err != nil {
-		ops.logger.Error("Failed to search files", "error", err)
-		return nil, fmt.Errorf("failed to search files: %w", err)
+// match records path when name contains the search pattern
+func (search *fileSearch) match(name, path string) {
+	// Check if filename matches pattern
+	if strings.Contains(strings.ToLower(name), search.pattern) {
+		search.results = append(search.results, path)
 	}
-
-	ops.logger.Debug("File search completed", "root", rootPath, "results_count", len(results))
-	return results, nil
 }
 
 // shouldExclude checks if a path should be excluded based on patterns
@@ -639,22 +1024,25 @@ func (ops *Operations) shouldExclude(relativePath string, excludePatterns []stri
 }
 
 // GetFileInfo retrieves detailed information about a file or directory
-func (ops *Operations) GetFileInfo(filePath string) (*FileInfo, error) {
+func (ops *Operations) GetFileInfo(ctx context.Context, filePath string) (*FileInfo, error) {
 	// Input validation per Rule 7
 	if filePath == "" {
 		return nil, fmt.Errorf("file path cannot be empty")
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	validPath, err := ops.pathValidator.ValidatePath(filePath)
+	validPath, err := ops.pathValidator.ValidatePath(filePath, security.OpRead)
 	if err != nil {
 		return nil, err
 	}
 
-	ops.logger.Debug("Getting file info", "path", validPath)
+	ops.logger.DebugContext(ctx, "Getting file info", "path", validPath)
 
-	stat, err := os.Stat(validPath)
+	stat, err := ops.statFile(validPath)
 	if err != nil {
-		ops.logger.Error("Failed to get file info", "path", validPath, "error", err)
+		ops.logger.ErrorContext(ctx, "Failed to get file info", "path", validPath, "error", err)
 		return nil, fmt.Errorf("failed to get file info: %w", err)
 	}
 
@@ -676,6 +1064,6 @@ func (ops *Operations) GetFileInfo(filePath string) (*FileInfo, error) {
 		info.Accessed = stat.ModTime()
 	}
 
-	ops.logger.Debug("File info retrieved successfully", "path", validPath)
+	ops.logger.DebugContext(ctx, "File info retrieved successfully", "path", validPath)
 	return info, nil
 }
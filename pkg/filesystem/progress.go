@@ -0,0 +1,107 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// progressInterval is the least time between two progress reports of one
+// operation; the first is sent at once
+const progressInterval = 500 * time.Millisecond
+
+// Progress describes how far a directory walk or copy has got
+type Progress struct {
+	// Entries is the number of directory entries scanned or copied
+	Entries int64
+
+	// Bytes is the number of file bytes copied
+	Bytes int64
+
+	// Directory is the directory being worked on
+	Directory string
+
+	// copying measures the work done in bytes rather than entries
+	copying bool
+}
+
+// Done is the amount of work finished, which only grows: bytes for copies
+// and entries for walks
+func (p Progress) Done() int64 {
+	if p.copying {
+		return p.Bytes
+	}
+	return p.Entries
+}
+
+// String describes the progress for people
+func (p Progress) String() string {
+	if p.copying {
+		return fmt.Sprintf("copied %d bytes in %d entries, now in %s", p.Bytes, p.Entries, p.Directory)
+	}
+	return fmt.Sprintf("scanned %d entries, now in %s", p.Entries, p.Directory)
+}
+
+// ProgressFunc receives the progress of an operation
+type ProgressFunc func(Progress)
+
+// progressKey carries a ProgressFunc in a context
+type progressKey struct{}
+
+// WithProgress returns a context in which walks and copies report their
+// progress to fn, at once and then at most every progressInterval
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// progressReporter counts the work of one operation and reports it. A nil
+// reporter, used when nobody asked for progress, ignores all calls.
+type progressReporter struct {
+	fn       ProgressFunc
+	progress Progress
+	last     time.Time
+	sent     int64
+}
+
+// newProgress returns the reporter for an operation run with ctx, or nil
+func newProgress(ctx context.Context, copying bool) *progressReporter {
+	fn, ok := ctx.Value(progressKey{}).(ProgressFunc)
+	if !ok || fn == nil {
+		return nil
+	}
+	return &progressReporter{
+		fn:       fn,
+		progress: Progress{copying: copying},
+	}
+}
+
+// entry counts one entry of dir
+func (r *progressReporter) entry(dir string) {
+	if r == nil {
+		return
+	}
+	r.progress.Entries++
+	r.progress.Directory = dir
+	r.report()
+}
+
+// copied counts n bytes copied
+func (r *progressReporter) copied(n int) {
+	if r == nil {
+		return
+	}
+	r.progress.Bytes += int64(n)
+	r.report()
+}
+
+// report sends the progress when the interval has passed and work was done
+// since the last report
+func (r *progressReporter) report() {
+	now := time.Now()
+	if now.Sub(r.last) < progressInterval || r.progress.Done() <= r.sent {
+		return
+	}
+	r.last = now
+	r.sent = r.progress.Done()
+	r.fn(r.progress)
+}
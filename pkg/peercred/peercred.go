@@ -0,0 +1,10 @@
+// Package peercred identifies the process at the other end of a Unix
+// socket, for servers that admit local clients by user
+package peercred
+
+// Credentials identify the process at the other end of a Unix socket
+type Credentials struct {
+	UID int
+	GID int
+	PID int
+}
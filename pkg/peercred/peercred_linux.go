@@ -0,0 +1,34 @@
+//go:build linux
+// +build linux
+
+package peercred
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// Supported reports whether Of works here
+const Supported = true
+
+// Of reads SO_PEERCRED: the credentials of the process that connected, as
+// they were when it called connect
+func Of(conn *net.UnixConn) (Credentials, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to access socket: %w", err)
+	}
+
+	var ucred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		ucred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return Credentials{}, fmt.Errorf("failed to access socket: %w", err)
+	}
+	if credErr != nil {
+		return Credentials{}, fmt.Errorf("failed to read peer credentials: %w", credErr)
+	}
+	return Credentials{UID: int(ucred.Uid), GID: int(ucred.Gid), PID: int(ucred.Pid)}, nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package peercred
+
+import (
+	"fmt"
+	"net"
+)
+
+// Supported reports whether Of works here
+const Supported = false
+
+// Of reports that SO_PEERCRED is only available on Linux
+func Of(conn *net.UnixConn) (Credentials, error) {
+	return Credentials{}, fmt.Errorf("peer credentials are only available on Linux")
+}
@@ -0,0 +1,330 @@
+package policy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Effect is what a matching rule does with a call
+type Effect string
+
+const (
+	// EffectAllow permits the call and stops evaluation
+	EffectAllow Effect = "allow"
+
+	// EffectDeny refuses the call and stops evaluation
+	EffectDeny Effect = "deny"
+
+	// EffectAudit records that the rule matched and continues with the
+	// next rule
+	EffectAudit Effect = "audit"
+)
+
+// maxRules bounds the rule list per Rule 2
+const maxRules = 1000
+
+// Config is the policy section of the configuration file
+type Config struct {
+	// Default is the effect when no rule allows or denies a call; empty
+	// means allow
+	Default Effect `yaml:"default"`
+
+	// Rules are evaluated in order; the first allow or deny wins
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule matches calls by tool, path, size and time. Empty conditions match
+// everything.
+type Rule struct {
+	// Name identifies the rule in denials and the audit log
+	Name string `yaml:"name"`
+
+	// Tools are the tool names the rule applies to
+	Tools []string `yaml:"tools"`
+
+	// Paths are globs matched against the path relative to its allowed
+	// directory, or against the full path when they start with /
+	Paths []string `yaml:"paths"`
+
+	// MinSize and MaxSize bound the content size of writes, or the file
+	// size for other tools, inclusively
+	MinSize Size `yaml:"min_size"`
+	MaxSize Size `yaml:"max_size"`
+
+	// Days are the weekdays (mon, tue, ...) on which the rule applies
+	Days []string `yaml:"days"`
+
+	// Hours is a local time window such as "09:00-17:00"; the end is
+	// exclusive and windows may wrap past midnight
+	Hours string `yaml:"hours"`
+
+	// Effect is allow, deny or audit
+	Effect Effect `yaml:"effect"`
+}
+
+// Request describes one path touched by a tool call
+type Request struct {
+	// Tool is the name of the tool being called
+	Tool string
+
+	// Path is the resolved absolute path
+	Path string
+
+	// Root is the allowed directory containing Path
+	Root string
+
+	// Size is the content or file size in bytes, or negative when unknown
+	Size int64
+
+	// Time is when the call was made
+	Time time.Time
+}
+
+// Decision is the outcome of evaluating a Request
+type Decision struct {
+	// Allowed reports whether the call may proceed
+	Allowed bool
+
+	// Rule names the allow or deny rule that decided, empty for the default
+	Rule string
+
+	// Audited names the audit rules that matched
+	Audited []string
+}
+
+// Policy is a compiled, immutable rule set
+type Policy struct {
+	defaultAllow bool
+	rules        []compiledRule
+}
+
+// compiledRule is a Rule with its time window parsed
+type compiledRule struct {
+	Rule
+	days      map[time.Weekday]bool
+	startMin  int
+	endMin    int
+	hasWindow bool
+}
+
+// weekdays maps configuration day names to weekdays
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// New validates cfg and compiles it into a Policy
+func New(cfg Config) (*Policy, error) {
+	p := &Policy{defaultAllow: true}
+	switch cfg.Default {
+	case "", EffectAllow:
+	case EffectDeny:
+		p.defaultAllow = false
+	default:
+		return nil, fmt.Errorf("invalid policy default: %s", cfg.Default)
+	}
+
+	if len(cfg.Rules) > maxRules {
+		return nil, fmt.Errorf("too many policy rules: %d (max %d)", len(cfg.Rules), maxRules)
+	}
+
+	names := make(map[string]bool, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("policy rule %d: name is required", i+1)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("policy rule %s: duplicate name", rule.Name)
+		}
+		names[rule.Name] = true
+
+		compiled, err := compile(rule)
+		if err != nil {
+			return nil, fmt.Errorf("policy rule %s: %w", rule.Name, err)
+		}
+		p.rules = append(p.rules, compiled)
+	}
+	return p, nil
+}
+
+// compile checks a rule and parses its day and hour conditions
+func compile(rule Rule) (compiledRule, error) {
+	c := compiledRule{Rule: rule}
+
+	switch rule.Effect {
+	case EffectAllow, EffectDeny, EffectAudit:
+	default:
+		return c, fmt.Errorf("invalid effect: %q (must be allow, deny or audit)", rule.Effect)
+	}
+
+	for _, pattern := range rule.Paths {
+		if !doublestar.ValidatePattern(pattern) {
+			return c, fmt.Errorf("invalid path pattern: %s", pattern)
+		}
+	}
+
+	if rule.MinSize < 0 || rule.MaxSize < 0 {
+		return c, fmt.Errorf("sizes cannot be negative")
+	}
+	if rule.MaxSize > 0 && rule.MinSize > rule.MaxSize {
+		return c, fmt.Errorf("min_size exceeds max_size")
+	}
+
+	if len(rule.Days) > 0 {
+		c.days = make(map[time.Weekday]bool, len(rule.Days))
+		for _, day := range rule.Days {
+			weekday, ok := weekdays[strings.ToLower(day)]
+			if !ok {
+				return c, fmt.Errorf("invalid day: %s", day)
+			}
+			c.days[weekday] = true
+		}
+	}
+
+	if rule.Hours != "" {
+		start, end, ok := strings.Cut(rule.Hours, "-")
+		if !ok {
+			return c, fmt.Errorf("invalid hours: %s (expected HH:MM-HH:MM)", rule.Hours)
+		}
+		var err error
+		if c.startMin, err = parseClock(start); err != nil {
+			return c, err
+		}
+		if c.endMin, err = parseClock(end); err != nil {
+			return c, err
+		}
+		c.hasWindow = true
+	}
+
+	return c, nil
+}
+
+// parseClock converts HH:MM into minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day: %s", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Evaluate applies the rules in order to req. A nil Policy allows
+// everything.
+func (p *Policy) Evaluate(req Request) Decision {
+	if p == nil {
+		return Decision{Allowed: true}
+	}
+
+	var audited []string
+	for i := range p.rules {
+		rule := &p.rules[i]
+		if !rule.matches(req) {
+			continue
+		}
+		switch rule.Effect {
+		case EffectAudit:
+			audited = append(audited, rule.Name)
+		case EffectAllow:
+			return Decision{Allowed: true, Rule: rule.Name, Audited: audited}
+		case EffectDeny:
+			return Decision{Allowed: false, Rule: rule.Name, Audited: audited}
+		}
+	}
+	return Decision{Allowed: p.defaultAllow, Audited: audited}
+}
+
+// matches reports whether every condition of the rule holds for req
+func (r *compiledRule) matches(req Request) bool {
+	if len(r.Tools) > 0 && !contains(r.Tools, req.Tool) {
+		return false
+	}
+	if len(r.Paths) > 0 && !matchesPath(r.Paths, req.Path, req.Root) {
+		return false
+	}
+	if r.MinSize > 0 || r.MaxSize > 0 {
+		if req.Size < 0 {
+			return false
+		}
+		if req.Size < int64(r.MinSize) || (r.MaxSize > 0 && req.Size > int64(r.MaxSize)) {
+			return false
+		}
+	}
+	if r.days != nil && !r.days[req.Time.Weekday()] {
+		return false
+	}
+	if r.hasWindow && !r.inWindow(req.Time) {
+		return false
+	}
+	return true
+}
+
+// inWindow reports whether t falls in the rule's hours, which wrap past
+// midnight when the end is before the start
+func (r *compiledRule) inWindow(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if r.startMin <= r.endMin {
+		return minute >= r.startMin && minute < r.endMin
+	}
+	return minute >= r.startMin || minute < r.endMin
+}
+
+// matchesPath reports whether path matches any pattern. Relative patterns
+// are matched against the path relative to root.
+func matchesPath(patterns []string, path, root string) bool {
+	absSlash := filepath.ToSlash(filepath.Clean(path))
+	relSlash := ""
+	if root != "" {
+		if rel, err := filepath.Rel(root, path); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			relSlash = filepath.ToSlash(rel)
+		}
+	}
+
+	for _, pattern := range patterns {
+		target := relSlash
+		if strings.HasPrefix(pattern, "/") {
+			target = absSlash
+		}
+		if target == "" {
+			continue
+		}
+		if matched, err := doublestar.Match(pattern, target); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether list holds value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// DeniedError reports a call refused by a policy rule
+type DeniedError struct {
+	// Rule is the name of the deny rule, empty when the default denied
+	Rule string
+	Tool string
+	Path string
+}
+
+// Error names the rule so the client can tell which policy applied
+func (e *DeniedError) Error() string {
+	if e.Rule == "" {
+		return fmt.Sprintf("access denied by policy - no rule allows %s on %s", e.Tool, e.Path)
+	}
+	return fmt.Sprintf("access denied by policy rule %q - %s on %s", e.Rule, e.Tool, e.Path)
+}
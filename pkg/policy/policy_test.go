@@ -0,0 +1,177 @@
+package policy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// docsPolicy allows Markdown under docs/ up to 200KB and denies other
+// writes there
+const docsPolicy = `
+rules:
+  - name: audit-large-reads
+    tools: [read_file]
+    min_size: 1MB
+    effect: audit
+  - name: docs-markdown
+    tools: [write_file]
+    paths: ["docs/**/*.md"]
+    max_size: 200KB
+    effect: allow
+  - name: docs-other
+    tools: [write_file]
+    paths: ["docs/**"]
+    effect: deny
+`
+
+func mustPolicy(t *testing.T, text string) *Policy {
+	t.Helper()
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(text), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	return p
+}
+
+func TestEvaluateFirstMatchWins(t *testing.T) {
+	p := mustPolicy(t, docsPolicy)
+	write := func(path string, size int64) Decision {
+		return p.Evaluate(Request{Tool: "write_file", Path: "/root/" + path, Root: "/root", Size: size, Time: time.Now()})
+	}
+
+	if d := write("docs/guide/intro.md", 1024); !d.Allowed || d.Rule != "docs-markdown" {
+		t.Fatalf("small markdown should be allowed by docs-markdown: %+v", d)
+	}
+	if d := write("docs/guide/intro.md", 300*1024); d.Allowed || d.Rule != "docs-other" {
+		t.Fatalf("large markdown should be denied by docs-other: %+v", d)
+	}
+	if d := write("docs/script.sh", 10); d.Allowed || d.Rule != "docs-other" {
+		t.Fatalf("non-markdown should be denied: %+v", d)
+	}
+	if d := write("src/main.go", 10); !d.Allowed || d.Rule != "" {
+		t.Fatalf("paths outside docs should fall through to the default: %+v", d)
+	}
+
+	d := p.Evaluate(Request{Tool: "read_file", Path: "/root/big.bin", Root: "/root", Size: 2 << 20})
+	if !d.Allowed || len(d.Audited) != 1 || d.Audited[0] != "audit-large-reads" {
+		t.Fatalf("expected audit rule to match and allow: %+v", d)
+	}
+	if d := p.Evaluate(Request{Tool: "read_file", Path: "/root/big.bin", Root: "/root", Size: -1}); len(d.Audited) != 0 {
+		t.Fatalf("size rules must not match unknown sizes: %+v", d)
+	}
+}
+
+func TestEvaluateDefaultDeny(t *testing.T) {
+	p := mustPolicy(t, `
+default: deny
+rules:
+  - name: reads
+    tools: [read_file, list_directory]
+    effect: allow
+`)
+	if d := p.Evaluate(Request{Tool: "read_file", Path: "/a"}); !d.Allowed {
+		t.Fatalf("read should be allowed: %+v", d)
+	}
+	if d := p.Evaluate(Request{Tool: "write_file", Path: "/a"}); d.Allowed || d.Rule != "" {
+		t.Fatalf("write should be denied by default: %+v", d)
+	}
+
+	var nilPolicy *Policy
+	if d := nilPolicy.Evaluate(Request{Tool: "write_file"}); !d.Allowed {
+		t.Fatalf("nil policy should allow")
+	}
+}
+
+func TestEvaluateTimeWindow(t *testing.T) {
+	p := mustPolicy(t, `
+rules:
+  - name: no-night-writes
+    tools: [write_file]
+    hours: "22:00-06:00"
+    effect: deny
+  - name: no-weekend-moves
+    tools: [move_file]
+    days: [sat, sun]
+    effect: deny
+`)
+	at := func(day, clock string) time.Time {
+		tm, err := time.Parse("2006-01-02 15:04", day+" "+clock)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		return tm
+	}
+
+	// 2024-06-01 is a Saturday, 2024-06-03 a Monday
+	if d := p.Evaluate(Request{Tool: "write_file", Time: at("2024-06-03", "23:30")}); d.Allowed {
+		t.Fatalf("late write should be denied")
+	}
+	if d := p.Evaluate(Request{Tool: "write_file", Time: at("2024-06-03", "05:59")}); d.Allowed {
+		t.Fatalf("early write should be denied")
+	}
+	if d := p.Evaluate(Request{Tool: "write_file", Time: at("2024-06-03", "06:00")}); !d.Allowed {
+		t.Fatalf("daytime write should be allowed")
+	}
+	if d := p.Evaluate(Request{Tool: "move_file", Time: at("2024-06-01", "12:00")}); d.Allowed {
+		t.Fatalf("weekend move should be denied")
+	}
+	if d := p.Evaluate(Request{Tool: "move_file", Time: at("2024-06-03", "12:00")}); !d.Allowed {
+		t.Fatalf("weekday move should be allowed")
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	cases := map[string]Config{
+		"missing name":   {Rules: []Rule{{Effect: EffectDeny}}},
+		"duplicate name": {Rules: []Rule{{Name: "a", Effect: EffectDeny}, {Name: "a", Effect: EffectAllow}}},
+		"bad effect":     {Rules: []Rule{{Name: "a", Effect: "maybe"}}},
+		"bad glob":       {Rules: []Rule{{Name: "a", Effect: EffectDeny, Paths: []string{"docs/[**"}}}},
+		"bad day":        {Rules: []Rule{{Name: "a", Effect: EffectDeny, Days: []string{"someday"}}}},
+		"bad hours":      {Rules: []Rule{{Name: "a", Effect: EffectDeny, Hours: "nine to five"}}},
+		"size order":     {Rules: []Rule{{Name: "a", Effect: EffectDeny, MinSize: 10, MaxSize: 5}}},
+		"bad default":    {Default: EffectAudit},
+	}
+	for name, cfg := range cases {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]Size{
+		"512":    512,
+		"200KB":  200 * 1024,
+		"200 kb": 200 * 1024,
+		"5MiB":   5 << 20,
+		"1G":     1 << 30,
+		"7B":     7,
+	}
+	for input, want := range cases {
+		got, err := ParseSize(input)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "KB", "-1", "1.5MB", "lots"} {
+		if _, err := ParseSize(input); err == nil {
+			t.Errorf("ParseSize(%q) should fail", input)
+		}
+	}
+}
+
+func TestDeniedErrorNamesRule(t *testing.T) {
+	var err error = &DeniedError{Rule: "docs-other", Tool: "write_file", Path: "/root/docs/a.sh"}
+	var denied *DeniedError
+	if !errors.As(err, &denied) || !strings.Contains(err.Error(), `"docs-other"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Size is a byte count written in configuration as a number or with a
+// unit such as 200KB or 5MB. Units are powers of 1024.
+type Size int64
+
+// sizeUnits maps unit suffixes to multipliers, longest suffixes first so
+// that KB is not read as B
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KIB", 1 << 10},
+	{"MIB", 1 << 20},
+	{"GIB", 1 << 30},
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"B", 1},
+}
+
+// ParseSize parses a byte count with an optional unit
+func ParseSize(value string) (Size, error) {
+	text := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(text, unit.suffix) {
+			text = strings.TrimSpace(strings.TrimSuffix(text, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size: %s", value)
+	}
+	if n > (1<<62)/multiplier {
+		return 0, fmt.Errorf("size too large: %s", value)
+	}
+	return Size(n * multiplier), nil
+}
+
+// UnmarshalYAML accepts plain numbers and numbers with a unit
+func (s *Size) UnmarshalYAML(value *yaml.Node) error {
+	size, err := ParseSize(value.Value)
+	if err != nil {
+		return err
+	}
+	*s = size
+	return nil
+}
@@ -0,0 +1,204 @@
+package quota
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"filesystem/pkg/security"
+	"filesystem/pkg/toolresult"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// maxRequestPaths bounds the paths examined per call per Rule 2
+	maxRequestPaths = 100
+
+	// maxPathLevels bounds the directory levels walked per path per Rule 2
+	maxPathLevels = 4096
+)
+
+// RootResolver maps an absolute path to the allowed directory containing it
+type RootResolver func(path string) (string, bool)
+
+// Middleware returns a tool handler middleware that refuses calls over a
+// session or root budget and charges successful writes and creations.
+// The expected usage is reserved while the call runs and refunded when it
+// fails. Paths outside every root are left to the path validator to refuse.
+func (t *Tracker) Middleware(resolve RootResolver) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			session := ""
+			if s := server.ClientSessionFromContext(ctx); s != nil {
+				session = s.SessionID()
+			}
+
+			paths, roots := resolvePaths(req.Params.Arguments, resolve)
+			expected := expectedUsage(req, paths)
+			if err := t.Admit(session, roots, expected); err != nil {
+				return toolresult.Errorf(toolresult.CodeQuotaExceeded, "Error: %s", err.Error()), nil
+			}
+
+			result, err := next(ctx, req)
+			if err != nil || result == nil || result.IsError {
+				t.Charge(session, roots, expected, Usage{})
+				return result, err
+			}
+
+			t.Charge(session, roots, expected, actualUsage(req, paths, expected))
+			return result, err
+		}
+	}
+}
+
+// RateLimit returns a tool handler middleware that counts a call against
+// the per-minute operation limits without reserving any usage. It is for
+// calls charged later by Middleware, such as calls held for approval.
+func (t *Tracker) RateLimit(resolve RootResolver) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			session := ""
+			if s := server.ClientSessionFromContext(ctx); s != nil {
+				session = s.SessionID()
+			}
+
+			_, roots := resolvePaths(req.Params.Arguments, resolve)
+			if err := t.Admit(session, roots, Usage{}); err != nil {
+				return toolresult.Errorf(toolresult.CodeQuotaExceeded, "Error: %s", err.Error()), nil
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// resolvePaths returns the real paths named by a call that fall inside an
+// allowed directory, and the distinct roots containing them. Symlinks are
+// resolved first, so a path is charged to the root it is written to rather
+// than the one its name lies under.
+func resolvePaths(args map[string]interface{}, resolve RootResolver) ([]string, []string) {
+	var requested []string
+	for _, key := range []string{"path", "source", "destination"} {
+		if p, ok := args[key].(string); ok && p != "" {
+			requested = append(requested, p)
+		}
+	}
+	if list, ok := args["paths"].([]interface{}); ok {
+		for i := 0; i < len(list) && i < maxRequestPaths; i++ {
+			if p, ok := list[i].(string); ok && p != "" {
+				requested = append(requested, p)
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(requested))
+	roots := make([]string, 0, len(requested))
+	seen := make(map[string]bool, len(requested))
+	for _, p := range requested {
+		abs, err := filepath.Abs(security.ExpandHomePath(p))
+		if err != nil {
+			continue
+		}
+		resolved := realPath(abs)
+		root, ok := resolve(resolved)
+		if !ok {
+			continue
+		}
+		paths = append(paths, resolved)
+		if !seen[root] {
+			seen[root] = true
+			roots = append(roots, root)
+		}
+	}
+	return paths, roots
+}
+
+// expectedUsage estimates what a call will consume before it runs. An
+// edit rewrites the whole file, so its current size is the estimate.
+func expectedUsage(req mcp.CallToolRequest, paths []string) Usage {
+	var usage Usage
+	switch req.Params.Name {
+	case "write_file":
+		if content, ok := req.Params.Arguments["content"].(string); ok {
+			usage.Bytes = int64(len(content))
+		}
+		usage.Files = countMissing(paths)
+	case "edit_file":
+		if dryRun, ok := req.Params.Arguments["dryRun"].(bool); ok && dryRun {
+			break
+		}
+		if len(paths) > 0 {
+			if info, err := os.Stat(paths[0]); err == nil {
+				usage.Bytes = info.Size()
+			}
+		}
+	case "create_directory":
+		// Missing parents are created too
+		for _, p := range paths {
+			usage.Files += countMissingLevels(p)
+		}
+	}
+	return usage
+}
+
+// actualUsage returns what a successful call consumed; an edit is charged
+// the size of the file it wrote
+func actualUsage(req mcp.CallToolRequest, paths []string, expected Usage) Usage {
+	if req.Params.Name != "edit_file" || expected.Bytes == 0 {
+		return expected
+	}
+	info, err := os.Stat(paths[0])
+	if err != nil {
+		return Usage{}
+	}
+	return Usage{Bytes: info.Size()}
+}
+
+// countMissing returns how many of the paths do not exist yet
+func countMissing(paths []string) int64 {
+	var missing int64
+	for _, p := range paths {
+		if _, err := os.Lstat(p); os.IsNotExist(err) {
+			missing++
+		}
+	}
+	return missing
+}
+
+// countMissingLevels returns how many directories creating path and its
+// missing parents adds
+func countMissingLevels(path string) int64 {
+	var missing int64
+	for i := 0; i < maxPathLevels; i++ {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			break
+		}
+		missing++
+		parent := filepath.Dir(path)
+		if parent == path {
+			break
+		}
+		path = parent
+	}
+	return missing
+}
+
+// realPath resolves the symlinks in path. Components that do not exist yet
+// are kept as they are beneath the real path of the deepest existing one.
+func realPath(path string) string {
+	missing := ""
+	dir := path
+	for i := 0; i < maxPathLevels; i++ {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, missing)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		missing = filepath.Join(filepath.Base(dir), missing)
+		dir = parent
+	}
+	return path
+}
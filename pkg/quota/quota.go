@@ -0,0 +1,315 @@
+package quota
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"filesystem/pkg/policy"
+)
+
+const (
+	// rateWindow is the window operations per minute are counted over
+	rateWindow = time.Minute
+
+	// maxTrackedSessions bounds the session table per Rule 2; the least
+	// recently active session is dropped when it is full
+	maxTrackedSessions = 10000
+
+	// maxOperationsPerMinute bounds the timestamps kept per key per Rule 2
+	maxOperationsPerMinute = 100000
+)
+
+// Scope names used in quota errors
+const (
+	ScopeSession = "session"
+	ScopeRoot    = "root"
+)
+
+// Limits are the budgets for one session or one root. Zero disables a limit.
+type Limits struct {
+	// MaxBytesWritten caps the bytes written by write_file and edit_file
+	MaxBytesWritten policy.Size `yaml:"max_bytes_written"`
+
+	// MaxFilesCreated caps new files and directories
+	MaxFilesCreated int64 `yaml:"max_files_created"`
+
+	// OperationsPerMinute caps tool calls in any one-minute window
+	OperationsPerMinute int `yaml:"operations_per_minute"`
+}
+
+// Config is the quotas section of the configuration file
+type Config struct {
+	// Session limits apply to each client session across all roots
+	Session Limits `yaml:"session"`
+
+	// Root limits apply to each allowed directory across all sessions
+	Root Limits `yaml:"root"`
+
+	// Period resets the byte and file budgets at this interval; zero
+	// keeps them for the lifetime of the session or process
+	Period time.Duration `yaml:"period"`
+}
+
+// Enabled reports whether any limit is configured
+func (c Config) Enabled() bool {
+	return c.Session != (Limits{}) || c.Root != (Limits{})
+}
+
+// Validate checks that no limit is negative
+func (c Config) Validate() error {
+	for scope, limits := range map[string]Limits{ScopeSession: c.Session, ScopeRoot: c.Root} {
+		if limits.MaxBytesWritten < 0 || limits.MaxFilesCreated < 0 || limits.OperationsPerMinute < 0 {
+			return fmt.Errorf("%s quota limits cannot be negative", scope)
+		}
+		if limits.OperationsPerMinute > maxOperationsPerMinute {
+			return fmt.Errorf("%s operations_per_minute cannot exceed %d", scope, maxOperationsPerMinute)
+		}
+	}
+	if c.Period < 0 {
+		return fmt.Errorf("invalid quota period: %s", c.Period)
+	}
+	return nil
+}
+
+// ExceededError reports a call refused by a quota, with what is left
+type ExceededError struct {
+	// Scope is ScopeSession or ScopeRoot
+	Scope string
+
+	// Key is the session ID or root path
+	Key string
+
+	// Resource is "bytes written", "files created" or "operations per minute"
+	Resource string
+
+	// Limit and Remaining describe the budget; Requested is what the call
+	// needed
+	Limit     int64
+	Remaining int64
+	Requested int64
+
+	// RetryAfter is when an operation slot frees up, for rate limits
+	RetryAfter time.Duration
+}
+
+// Error describes the exhausted budget and the remaining allowance
+func (e *ExceededError) Error() string {
+	target := e.Scope
+	if e.Scope == ScopeRoot {
+		target = fmt.Sprintf("root %s", e.Key)
+	}
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limit exceeded - %s allows %d operations per minute; 0 remaining, retry in %s",
+			target, e.Limit, e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("quota exceeded - %s %s limit is %d; %d remaining, this call needs %d",
+		target, e.Resource, e.Limit, e.Remaining, e.Requested)
+}
+
+// Usage is what a call consumes
+type Usage struct {
+	Bytes int64
+	Files int64
+}
+
+// counter tracks consumption for one session or root
+type counter struct {
+	bytes       int64
+	files       int64
+	periodStart time.Time
+	operations  []time.Time
+	lastSeen    time.Time
+}
+
+// Tracker keeps per-session and per-root counters. Counters survive
+// configuration reloads; only the limits are replaced.
+type Tracker struct {
+	mu       sync.Mutex
+	cfg      Config
+	sessions map[string]*counter
+	roots    map[string]*counter
+
+	// now is replaceable in tests
+	now func() time.Time
+}
+
+// NewTracker creates a tracker enforcing cfg
+func NewTracker(cfg Config) *Tracker {
+	return &Tracker{
+		cfg:      cfg,
+		sessions: make(map[string]*counter),
+		roots:    make(map[string]*counter),
+		now:      time.Now,
+	}
+}
+
+// SetConfig replaces the limits, keeping the usage counted so far
+func (t *Tracker) SetConfig(cfg Config) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cfg = cfg
+}
+
+// Forget drops the counters of a session that has ended
+func (t *Tracker) Forget(session string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.sessions, session)
+}
+
+// Admit counts one operation against the session and each root, checks
+// that the expected usage fits the remaining budgets and reserves it, so
+// concurrent calls cannot together exceed a budget each fits alone. Every
+// admitted call must be settled with Charge. Nothing is counted or
+// reserved when any check fails.
+func (t *Tracker) Admit(session string, roots []string, expected Usage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+
+	sessionCounter := t.sessionCounter(session, now)
+	if err := t.check(sessionCounter, t.cfg.Session, ScopeSession, session, expected, now); err != nil {
+		return err
+	}
+	rootCounters := make([]*counter, 0, len(roots))
+	for _, root := range roots {
+		c := t.rootCounter(root, now)
+		if err := t.check(c, t.cfg.Root, ScopeRoot, root, expected, now); err != nil {
+			return err
+		}
+		rootCounters = append(rootCounters, c)
+	}
+
+	sessionCounter.operations = append(sessionCounter.operations, now)
+	sessionCounter.add(expected)
+	for _, c := range rootCounters {
+		c.operations = append(c.operations, now)
+		c.add(expected)
+	}
+	return nil
+}
+
+// Charge settles a call admitted with reserved, replacing the reservation
+// with what the call used: nothing for a call that failed, or its actual
+// usage once it completed
+func (t *Tracker) Charge(session string, roots []string, reserved, used Usage) {
+	delta := Usage{Bytes: used.Bytes - reserved.Bytes, Files: used.Files - reserved.Files}
+	if delta == (Usage{}) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+
+	t.sessionCounter(session, now).add(delta)
+	for _, root := range roots {
+		t.rootCounter(root, now).add(delta)
+	}
+}
+
+// add changes the usage counted by u. A refund never takes a counter
+// below zero, as the period may have reset it since the reservation.
+func (c *counter) add(u Usage) {
+	c.bytes = max(c.bytes+u.Bytes, 0)
+	c.files = max(c.files+u.Files, 0)
+}
+
+// check compares a counter with its limits. t.mu must be held.
+func (t *Tracker) check(c *counter, limits Limits, scope, key string, expected Usage, now time.Time) error {
+	t.resetPeriod(c, now)
+
+	if limits.OperationsPerMinute > 0 {
+		c.operations = pruneOperations(c.operations, now)
+		if len(c.operations) >= limits.OperationsPerMinute {
+			return &ExceededError{
+				Scope:      scope,
+				Key:        key,
+				Resource:   "operations per minute",
+				Limit:      int64(limits.OperationsPerMinute),
+				Requested:  1,
+				RetryAfter: c.operations[0].Add(rateWindow).Sub(now),
+			}
+		}
+	} else {
+		c.operations = nil
+	}
+
+	if max := int64(limits.MaxBytesWritten); max > 0 && c.bytes+expected.Bytes > max {
+		return &ExceededError{Scope: scope, Key: key, Resource: "bytes written",
+			Limit: max, Remaining: remaining(max, c.bytes), Requested: expected.Bytes}
+	}
+	if max := limits.MaxFilesCreated; max > 0 && c.files+expected.Files > max {
+		return &ExceededError{Scope: scope, Key: key, Resource: "files created",
+			Limit: max, Remaining: remaining(max, c.files), Requested: expected.Files}
+	}
+	return nil
+}
+
+// resetPeriod clears the byte and file budgets once the period has passed
+func (t *Tracker) resetPeriod(c *counter, now time.Time) {
+	if t.cfg.Period > 0 && now.Sub(c.periodStart) >= t.cfg.Period {
+		c.bytes, c.files = 0, 0
+		c.periodStart = now
+	}
+}
+
+// sessionCounter returns the counter for a session, evicting the least
+// recently active one when the table is full. t.mu must be held.
+func (t *Tracker) sessionCounter(session string, now time.Time) *counter {
+	c, ok := t.sessions[session]
+	if !ok {
+		if len(t.sessions) >= maxTrackedSessions {
+			t.evictIdleSession()
+		}
+		c = &counter{periodStart: now}
+		t.sessions[session] = c
+	}
+	c.lastSeen = now
+	return c
+}
+
+// rootCounter returns the counter for a root. t.mu must be held.
+func (t *Tracker) rootCounter(root string, now time.Time) *counter {
+	c, ok := t.roots[root]
+	if !ok {
+		c = &counter{periodStart: now}
+		t.roots[root] = c
+	}
+	c.lastSeen = now
+	return c
+}
+
+// evictIdleSession drops the least recently active session. t.mu must be
+// held.
+func (t *Tracker) evictIdleSession() {
+	var oldest *string
+	var oldestSeen time.Time
+	for id, c := range t.sessions {
+		if oldest == nil || c.lastSeen.Before(oldestSeen) {
+			id := id
+			oldest, oldestSeen = &id, c.lastSeen
+		}
+	}
+	if oldest != nil {
+		delete(t.sessions, *oldest)
+	}
+}
+
+// pruneOperations drops timestamps older than the rate window
+func pruneOperations(ops []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-rateWindow)
+	i := 0
+	for i < len(ops) && !ops[i].After(cutoff) {
+		i++
+	}
+	return ops[i:]
+}
+
+// remaining returns what is left of a budget, never negative
+func remaining(limit, used int64) int64 {
+	if used >= limit {
+		return 0
+	}
+	return limit - used
+}
@@ -0,0 +1,210 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestAdmitBytesAndFiles(t *testing.T) {
+	tr := NewTracker(Config{Session: Limits{MaxBytesWritten: 100, MaxFilesCreated: 2}})
+
+	if err := tr.Admit("s1", nil, Usage{Bytes: 60, Files: 1}); err != nil {
+		t.Fatalf("admit: %v", err)
+	}
+	tr.Charge("s1", nil, Usage{Bytes: 60, Files: 1}, Usage{Bytes: 60, Files: 1})
+
+	err := tr.Admit("s1", nil, Usage{Bytes: 50})
+	var exceeded *ExceededError
+	if !errors.As(err, &exceeded) || exceeded.Remaining != 40 || exceeded.Resource != "bytes written" {
+		t.Fatalf("expected bytes quota error with 40 remaining, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "40 remaining") {
+		t.Fatalf("error should state the remaining budget: %v", err)
+	}
+
+	// Other sessions have their own budget
+	if err := tr.Admit("s2", nil, Usage{Bytes: 100}); err != nil {
+		t.Fatalf("second session should not be limited: %v", err)
+	}
+
+	tr.Charge("s1", nil, Usage{}, Usage{Files: 1})
+	if err := tr.Admit("s1", nil, Usage{Files: 1}); !errors.As(err, &exceeded) || exceeded.Resource != "files created" {
+		t.Fatalf("expected files quota error, got %v", err)
+	}
+
+	tr.Forget("s1")
+	if err := tr.Admit("s1", nil, Usage{Bytes: 100, Files: 2}); err != nil {
+		t.Fatalf("forgotten session should start fresh: %v", err)
+	}
+}
+
+func TestAdmitRootLimitsAcrossSessions(t *testing.T) {
+	tr := NewTracker(Config{Root: Limits{MaxBytesWritten: 100}})
+	tr.Charge("s1", []string{"/a"}, Usage{}, Usage{Bytes: 80})
+
+	var exceeded *ExceededError
+	if err := tr.Admit("s2", []string{"/a"}, Usage{Bytes: 30}); !errors.As(err, &exceeded) || exceeded.Scope != ScopeRoot {
+		t.Fatalf("expected root quota error, got %v", err)
+	}
+	if err := tr.Admit("s2", []string{"/b"}, Usage{Bytes: 30}); err != nil {
+		t.Fatalf("other root should not be limited: %v", err)
+	}
+}
+
+func TestAdmitReservesUntilCharged(t *testing.T) {
+	tr := NewTracker(Config{Session: Limits{MaxBytesWritten: 100}})
+
+	// Two calls running at once cannot both spend the same budget
+	if err := tr.Admit("s", nil, Usage{Bytes: 60}); err != nil {
+		t.Fatalf("admit: %v", err)
+	}
+	var exceeded *ExceededError
+	if err := tr.Admit("s", nil, Usage{Bytes: 60}); !errors.As(err, &exceeded) || exceeded.Remaining != 40 {
+		t.Fatalf("expected the reservation to count against the budget, got %v", err)
+	}
+
+	// A failed call refunds its reservation
+	tr.Charge("s", nil, Usage{Bytes: 60}, Usage{})
+	if err := tr.Admit("s", nil, Usage{Bytes: 60}); err != nil {
+		t.Fatalf("refunded budget should be available: %v", err)
+	}
+
+	// A completed call is charged what it used rather than what it reserved
+	tr.Charge("s", nil, Usage{Bytes: 60}, Usage{Bytes: 20})
+	if err := tr.Admit("s", nil, Usage{Bytes: 80}); err != nil {
+		t.Fatalf("unused reservation should be returned: %v", err)
+	}
+}
+
+func TestAdmitRateLimit(t *testing.T) {
+	tr := NewTracker(Config{Session: Limits{OperationsPerMinute: 2}})
+	now := time.Now()
+	tr.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if err := tr.Admit("s", nil, Usage{}); err != nil {
+			t.Fatalf("admit %d: %v", i, err)
+		}
+	}
+	err := tr.Admit("s", nil, Usage{})
+	var exceeded *ExceededError
+	if !errors.As(err, &exceeded) || exceeded.RetryAfter <= 0 {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+
+	now = now.Add(rateWindow + time.Second)
+	if err := tr.Admit("s", nil, Usage{}); err != nil {
+		t.Fatalf("rate limit should reset after a minute: %v", err)
+	}
+}
+
+func TestPeriodResetsBudgets(t *testing.T) {
+	tr := NewTracker(Config{Session: Limits{MaxBytesWritten: 10}, Period: time.Hour})
+	now := time.Now()
+	tr.now = func() time.Time { return now }
+
+	tr.Charge("s", nil, Usage{}, Usage{Bytes: 10})
+	if err := tr.Admit("s", nil, Usage{Bytes: 1}); err == nil {
+		t.Fatalf("expected quota error")
+	}
+	now = now.Add(time.Hour)
+	if err := tr.Admit("s", nil, Usage{Bytes: 1}); err != nil {
+		t.Fatalf("budget should reset after the period: %v", err)
+	}
+}
+
+func TestMiddlewareChargesSuccessfulWrites(t *testing.T) {
+	root := t.TempDir()
+	tr := NewTracker(Config{Root: Limits{MaxFilesCreated: 1}})
+	resolve := func(path string) (string, bool) {
+		return root, strings.HasPrefix(path, root)
+	}
+	write := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		path := req.Params.Arguments["path"].(string)
+		if err := os.WriteFile(path, []byte(req.Params.Arguments["content"].(string)), 0644); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText("ok"), nil
+	}
+	handler := tr.Middleware(resolve)(write)
+
+	call := func(name string) *mcp.CallToolResult {
+		var req mcp.CallToolRequest
+		req.Params.Name = "write_file"
+		req.Params.Arguments = map[string]interface{}{"path": filepath.Join(root, name), "content": "x"}
+		res, err := handler(context.Background(), req)
+		if err != nil {
+			t.Fatalf("call: %v", err)
+		}
+		return res
+	}
+
+	if res := call("a.txt"); res.IsError {
+		t.Fatalf("first file should be created: %+v", res)
+	}
+	if res := call("a.txt"); res.IsError {
+		t.Fatalf("overwriting does not create a file: %+v", res)
+	}
+	res := call("b.txt")
+	if !res.IsError || !strings.Contains(res.Content[0].(mcp.TextContent).Text, "files created limit is 1; 0 remaining") {
+		t.Fatalf("expected files quota error, got %+v", res)
+	}
+}
+
+func TestMiddlewareCountsMissingDirectories(t *testing.T) {
+	root, other := t.TempDir(), t.TempDir()
+	if err := os.Symlink(other, filepath.Join(root, "elsewhere")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	tr := NewTracker(Config{Root: Limits{MaxFilesCreated: 2}})
+	resolve := func(path string) (string, bool) {
+		for _, dir := range []string{root, other} {
+			if strings.HasPrefix(path, dir) {
+				return dir, true
+			}
+		}
+		return "", false
+	}
+	mkdir := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := os.MkdirAll(req.Params.Arguments["path"].(string), 0755); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText("ok"), nil
+	}
+	handler := tr.Middleware(resolve)(mkdir)
+
+	call := func(path string) *mcp.CallToolResult {
+		var req mcp.CallToolRequest
+		req.Params.Name = "create_directory"
+		req.Params.Arguments = map[string]interface{}{"path": path}
+		res, err := handler(context.Background(), req)
+		if err != nil {
+			t.Fatalf("call: %v", err)
+		}
+		return res
+	}
+
+	// Every level of a/b/c is new, so the call needs three of two
+	res := call(filepath.Join(root, "a", "b", "c"))
+	if !res.IsError || !strings.Contains(res.Content[0].(mcp.TextContent).Text, "this call needs 3") {
+		t.Fatalf("expected each new level to be counted, got %+v", res)
+	}
+
+	// A path through a symlink is charged to the root it lands in
+	if res := call(filepath.Join(root, "elsewhere", "x", "y")); res.IsError {
+		t.Fatalf("create through symlink: %+v", res)
+	}
+	if res := call(filepath.Join(other, "z")); !res.IsError {
+		t.Fatalf("expected the symlinked root's budget to be used up, got %+v", res)
+	}
+	if res := call(filepath.Join(root, "a", "b")); res.IsError {
+		t.Fatalf("the root holding the symlink should keep its budget: %+v", res)
+	}
+}
@@ -0,0 +1,192 @@
+//go:build linux
+// +build linux
+
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"syscall"
+	"unsafe"
+
+	"filesystem/pkg/security"
+
+	"golang.org/x/sys/unix"
+)
+
+// Constants from linux/landlock.h and linux/prctl.h
+const (
+	landlockCreateRulesetVersion = 1
+	landlockRulePathBeneath      = 1
+	prSetNoNewPrivs              = 38
+)
+
+// Filesystem access rights from linux/landlock.h
+const (
+	accessExecute    = 1 << 0
+	accessWriteFile  = 1 << 1
+	accessReadFile   = 1 << 2
+	accessReadDir    = 1 << 3
+	accessRemoveDir  = 1 << 4
+	accessRemoveFile = 1 << 5
+	accessMakeDir    = 1 << 7
+	accessMakeReg    = 1 << 8
+	accessMakeSym    = 1 << 12
+
+	// accessABI1 covers every right defined by ABI 1, including execute
+	// and creating devices, FIFOs and sockets, none of which are granted
+	accessABI1 = 1<<13 - 1
+
+	accessRefer    = 1 << 13 // ABI 2
+	accessTruncate = 1 << 14 // ABI 3
+	accessIoctlDev = 1 << 15 // ABI 5
+
+	// accessFileRights are the only rights a rule on a non-directory may carry
+	accessFileRights = accessExecute | accessWriteFile | accessReadFile | accessTruncate | accessIoctlDev
+)
+
+// rulesetAttr mirrors struct landlock_ruleset_attr up to handled_access_fs
+type rulesetAttr struct {
+	handledAccessFS uint64
+}
+
+// pathBeneathAttr mirrors struct landlock_path_beneath_attr. The kernel
+// struct is packed to 12 bytes, which is a prefix of this layout.
+type pathBeneathAttr struct {
+	allowedAccess uint64
+	parentFd      int32
+}
+
+// Restrict applies a Landlock ruleset to every thread of the process so
+// that only the rule directories remain reachable, each with the rights its
+// access mode permits. It returns whether a ruleset was enforced.
+func Restrict(opts Options, logger *slog.Logger) (bool, error) {
+	minABI := opts.MinABI
+	if minABI <= 0 {
+		minABI = 1
+	}
+
+	abi, err := landlockABI()
+	if err != nil || abi < minABI {
+		logger.Warn("Kernel does not support the requested Landlock ABI",
+			"requested_abi", minABI,
+			"kernel_abi", abi,
+			"error", err)
+		if !opts.BestEffort {
+			return false, fmt.Errorf("landlock ABI %d requested but kernel supports %d", minABI, abi)
+		}
+		if abi == 0 {
+			logger.Warn("Landlock unavailable; continuing without sandbox")
+			return false, nil
+		}
+		logger.Warn("Continuing with the Landlock rights the kernel supports", "kernel_abi", abi)
+	}
+
+	handled := handledAccess(abi)
+	attr := rulesetAttr{handledAccessFS: handled}
+	rulesetFd, _, errno := syscall.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET,
+		uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return false, fmt.Errorf("failed to create landlock ruleset: %w", errno)
+	}
+	defer syscall.Close(int(rulesetFd))
+
+	for _, rule := range opts.Rules {
+		if err := addRule(int(rulesetFd), rule, handled); err != nil {
+			return false, err
+		}
+	}
+
+	// Both calls must reach every thread; the runtime refuses this when
+	// cgo is linked in
+	if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return false, fmt.Errorf("failed to set no_new_privs: %w%s", errno, cgoHint(errno))
+	}
+	if _, _, errno := syscall.AllThreadsSyscall(unix.SYS_LANDLOCK_RESTRICT_SELF, rulesetFd, 0, 0); errno != 0 {
+		return false, fmt.Errorf("failed to enforce landlock ruleset: %w%s", errno, cgoHint(errno))
+	}
+
+	logger.Info("Landlock sandbox enforced", "abi", abi, "rules", len(opts.Rules))
+	return true, nil
+}
+
+// landlockABI returns the highest Landlock ABI the kernel supports, or 0
+func landlockABI() (int, error) {
+	abi, _, errno := syscall.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, landlockCreateRulesetVersion)
+	if errno != 0 {
+		return 0, fmt.Errorf("landlock unavailable: %w", errno)
+	}
+	return int(abi), nil
+}
+
+// handledAccess returns every filesystem right the ABI can restrict, so that
+// anything not granted by a rule is denied
+func handledAccess(abi int) uint64 {
+	handled := uint64(accessABI1)
+	if abi >= 2 {
+		handled |= accessRefer
+	}
+	if abi >= 3 {
+		handled |= accessTruncate
+	}
+	if abi >= 5 {
+		handled |= accessIoctlDev
+	}
+	return handled
+}
+
+// rightsFor maps an access mode onto Landlock rights, limited to handled
+func rightsFor(mode security.AccessMode, handled uint64) uint64 {
+	rights := uint64(0)
+	if mode.Permits(security.OpRead) {
+		rights |= accessReadFile | accessReadDir
+	}
+	if mode.Permits(security.OpWrite) {
+		rights |= accessWriteFile | accessMakeReg | accessMakeDir | accessMakeSym | accessTruncate | accessRefer
+	}
+	if mode.Permits(security.OpDelete) {
+		rights |= accessRemoveFile | accessRemoveDir | accessRefer
+	}
+	return rights & handled
+}
+
+// addRule grants the rights of rule.Mode beneath rule.Path, or on the file
+// itself when rule.Path is not a directory
+func addRule(rulesetFd int, rule Rule, handled uint64) error {
+	fd, err := syscall.Open(rule.Path, unix.O_PATH|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s for landlock rule: %w", rule.Path, err)
+	}
+	defer syscall.Close(fd)
+
+	var stat syscall.Stat_t
+	if err := syscall.Fstat(fd, &stat); err != nil {
+		return fmt.Errorf("failed to stat %s for landlock rule: %w", rule.Path, err)
+	}
+
+	attr := pathBeneathAttr{
+		allowedAccess: rightsFor(rule.Mode, handled),
+		parentFd:      int32(fd),
+	}
+	if stat.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+		attr.allowedAccess &= accessFileRights
+	}
+	if attr.allowedAccess == 0 {
+		return nil
+	}
+	_, _, errno := syscall.Syscall6(unix.SYS_LANDLOCK_ADD_RULE,
+		uintptr(rulesetFd), landlockRulePathBeneath, uintptr(unsafe.Pointer(&attr)), 0, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to add landlock rule for %s: %w", rule.Path, errno)
+	}
+	return nil
+}
+
+// cgoHint explains the error AllThreadsSyscall returns in cgo binaries
+func cgoHint(errno syscall.Errno) string {
+	if errors.Is(errno, syscall.ENOTSUP) {
+		return " (rebuild with CGO_ENABLED=0)"
+	}
+	return ""
+}
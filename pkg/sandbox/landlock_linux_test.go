@@ -0,0 +1,101 @@
+//go:build linux
+// +build linux
+
+package sandbox
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"filesystem/pkg/security"
+)
+
+// prGetNoNewPrivs is PR_GET_NO_NEW_PRIVS from linux/prctl.h, a harmless
+// call to probe for all-thread syscalls
+const prGetNoNewPrivs = 39
+
+func TestRightsFor(t *testing.T) {
+	abi1 := handledAccess(1)
+	if got := rightsFor(security.ModeReadOnly, abi1); got != accessReadFile|accessReadDir {
+		t.Fatalf("read-only rights = %#x", got)
+	}
+	if got := rightsFor(security.ModeReadWrite, abi1); got&accessTruncate != 0 || got&accessRefer != 0 {
+		t.Fatalf("ABI 1 rights include unsupported bits: %#x", got)
+	}
+	if got := rightsFor(security.ModeNoDelete, handledAccess(3)); got&(accessRemoveFile|accessRemoveDir) != 0 || got&accessTruncate == 0 {
+		t.Fatalf("no-delete rights = %#x", got)
+	}
+	if got := rightsFor(security.ModeWriteOnly, handledAccess(3)); got&(accessReadFile|accessReadDir) != 0 {
+		t.Fatalf("write-only rights include read: %#x", got)
+	}
+}
+
+// TestRestrictConfinesProcess re-runs itself in a child process, since a
+// Landlock ruleset cannot be removed once applied
+func TestRestrictConfinesProcess(t *testing.T) {
+	if os.Getenv("SANDBOX_TEST_ROOT") != "" {
+		runConfinedChild()
+		return
+	}
+	if _, err := landlockABI(); err != nil {
+		t.Skipf("landlock unavailable: %v", err)
+	}
+	// Binaries that link cgo, as -race does, cannot restrict every thread
+	if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_PRCTL, prGetNoNewPrivs, 0, 0); errno == syscall.ENOTSUP {
+		t.Skip("test binary links cgo; all-thread syscalls are unavailable")
+	}
+
+	root := t.TempDir()
+	outside := t.TempDir()
+	for _, dir := range []string{root, outside} {
+		if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("x"), 0644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRestrictConfinesProcess$")
+	cmd.Env = append(os.Environ(), "SANDBOX_TEST_ROOT="+root, "SANDBOX_TEST_OUTSIDE="+outside)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("confined child failed: %v\n%s", err, out)
+	}
+}
+
+// runConfinedChild applies a read-only rule and exits non-zero if any
+// access outcome is wrong
+func runConfinedChild() {
+	root := os.Getenv("SANDBOX_TEST_ROOT")
+	outside := os.Getenv("SANDBOX_TEST_OUTSIDE")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	enforced, err := Restrict(Options{Rules: []Rule{{Path: root, Mode: security.ModeReadOnly}}}, logger)
+	if err != nil || !enforced {
+		os.Stderr.WriteString("restrict failed: " + errString(err) + "\n")
+		os.Exit(2)
+	}
+	if _, err := os.ReadFile(filepath.Join(root, "file.txt")); err != nil {
+		os.Stderr.WriteString("read inside root failed: " + err.Error() + "\n")
+		os.Exit(3)
+	}
+	if _, err := os.ReadFile(filepath.Join(outside, "file.txt")); err == nil {
+		os.Stderr.WriteString("read outside root succeeded\n")
+		os.Exit(4)
+	}
+	if err := os.WriteFile(filepath.Join(root, "new.txt"), []byte("x"), 0644); err == nil {
+		os.Stderr.WriteString("write in read-only root succeeded\n")
+		os.Exit(5)
+	}
+	os.Exit(0)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "not enforced"
+	}
+	return err.Error()
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package sandbox
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Restrict reports that Landlock is unavailable. With BestEffort the
+// process keeps running unrestricted.
+func Restrict(opts Options, logger *slog.Logger) (bool, error) {
+	if opts.BestEffort {
+		logger.Warn("Landlock is only available on Linux; continuing without sandbox")
+		return false, nil
+	}
+	return false, fmt.Errorf("landlock is only available on Linux")
+}
@@ -0,0 +1,29 @@
+// Package sandbox restricts the server process with kernel-enforced
+// filesystem rules so that a bug in path validation cannot reach outside
+// the configured directories.
+package sandbox
+
+import "filesystem/pkg/security"
+
+// Rule grants the rights an access mode permits beneath a directory, or on
+// a single file
+type Rule struct {
+	// Path of the directory or file
+	Path string
+
+	// Mode decides which rights are granted beneath Path
+	Mode security.AccessMode
+}
+
+// Options configures Restrict
+type Options struct {
+	// Rules lists the directories the process may still access
+	Rules []Rule
+
+	// MinABI is the lowest Landlock ABI version that satisfies the request
+	MinABI int
+
+	// BestEffort continues with the rights the kernel supports, or without
+	// any restriction, instead of failing when MinABI is not available
+	BestEffort bool
+}
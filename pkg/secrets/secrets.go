@@ -0,0 +1,241 @@
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Action is what happens to content in which secrets are found
+type Action string
+
+const (
+	// ActionOff disables scanning
+	ActionOff Action = "off"
+
+	// ActionMask replaces each secret with a placeholder
+	ActionMask Action = "mask"
+
+	// ActionRefuse fails the read
+	ActionRefuse Action = "refuse"
+)
+
+const (
+	// DefaultMinEntropy is the Shannon entropy in bits per character above
+	// which a KEY= style assignment is treated as a secret
+	DefaultMinEntropy = 3.5
+
+	// secretGroup is the capture group name marking the part of a match to
+	// mask; without it the whole match is masked
+	secretGroup = "secret"
+
+	// maxFindings bounds the findings reported per scan per Rule 2
+	maxFindings = 1000
+
+	// assignmentPattern is the built-in pattern that is subject to the
+	// entropy threshold
+	assignmentPattern = "high-entropy-assignment"
+)
+
+// Pattern is a named regular expression for one kind of secret
+type Pattern struct {
+	// Name identifies the pattern in logs and placeholders
+	Name string `yaml:"name"`
+
+	// Regex is an RE2 expression; a group named "secret" limits masking
+	// to that group
+	Regex string `yaml:"regex"`
+}
+
+// Config is the secret_scanning section of the configuration file
+type Config struct {
+	// Action is off, mask or refuse; empty means off
+	Action Action `yaml:"action"`
+
+	// Patterns are added to the built-in patterns
+	Patterns []Pattern `yaml:"patterns"`
+
+	// DisableBuiltin drops the built-in patterns
+	DisableBuiltin bool `yaml:"disable_builtin"`
+
+	// MinEntropy overrides DefaultMinEntropy for KEY= assignments
+	MinEntropy float64 `yaml:"min_entropy"`
+}
+
+// BuiltinPatterns cover private keys, AWS and GCP credentials, JWTs and
+// high-entropy KEY= assignments
+var BuiltinPatterns = []Pattern{
+	{Name: "private-key", Regex: `-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`},
+	{Name: "aws-access-key-id", Regex: `\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA)[0-9A-Z]{16}\b`},
+	{Name: "aws-secret-access-key", Regex: `(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})`},
+	{Name: "gcp-api-key", Regex: `\bAIza[0-9A-Za-z_\-]{35}\b`},
+	{Name: "gcp-oauth-client-secret", Regex: `\bGOCSPX-[0-9A-Za-z_\-]{28}\b`},
+	{Name: "jwt", Regex: `\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`},
+	{Name: assignmentPattern, Regex: `(?im)^[ \t]*(?:export[ \t]+)?[A-Z0-9_.-]*(?:KEY|SECRET|TOKEN|PASSWORD|PASSWD|CREDENTIAL)[A-Z0-9_.-]*[ \t]*[=:][ \t]*["']?(?P<secret>[^\s"'#]{16,})`},
+}
+
+// Finding is one secret located in scanned content
+type Finding struct {
+	// Pattern is the name of the pattern that matched
+	Pattern string
+
+	// Line is the 1-based line the secret starts on
+	Line int
+}
+
+// compiledPattern is a Pattern with its expression compiled
+type compiledPattern struct {
+	name         string
+	re           *regexp.Regexp
+	group        int
+	checkEntropy bool
+}
+
+// Scanner finds and masks secrets. It is immutable and safe for
+// concurrent use.
+type Scanner struct {
+	action     Action
+	patterns   []compiledPattern
+	minEntropy float64
+}
+
+// ParseAction validates an action, defaulting to off
+func ParseAction(action string) (Action, error) {
+	switch Action(action) {
+	case "", ActionOff:
+		return ActionOff, nil
+	case ActionMask, ActionRefuse:
+		return Action(action), nil
+	default:
+		return "", fmt.Errorf("invalid secret scanning action: %s (must be off, mask or refuse)", action)
+	}
+}
+
+// New compiles cfg. It returns nil when scanning is off.
+func New(cfg Config) (*Scanner, error) {
+	action, err := ParseAction(string(cfg.Action))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MinEntropy < 0 {
+		return nil, fmt.Errorf("invalid secret scanning min_entropy: %v", cfg.MinEntropy)
+	}
+
+	patterns := cfg.Patterns
+	if !cfg.DisableBuiltin {
+		patterns = append(append([]Pattern(nil), BuiltinPatterns...), cfg.Patterns...)
+	}
+
+	s := &Scanner{action: action, minEntropy: cfg.MinEntropy}
+	if s.minEntropy == 0 {
+		s.minEntropy = DefaultMinEntropy
+	}
+	for _, p := range patterns {
+		if p.Name == "" {
+			return nil, fmt.Errorf("secret pattern name is required")
+		}
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("secret pattern %s: %w", p.Name, err)
+		}
+		s.patterns = append(s.patterns, compiledPattern{
+			name:         p.Name,
+			re:           re,
+			group:        re.SubexpIndex(secretGroup),
+			checkEntropy: p.Name == assignmentPattern && !cfg.DisableBuiltin,
+		})
+	}
+
+	if action == ActionOff {
+		return nil, nil
+	}
+	return s, nil
+}
+
+// Refuses reports whether reads containing secrets should fail
+func (s *Scanner) Refuses() bool {
+	return s != nil && s.action == ActionRefuse
+}
+
+// span is a byte range to mask
+type span struct {
+	start, end int
+	name       string
+}
+
+// Scan returns content with every secret replaced by [REDACTED:<pattern>]
+// and the findings in order of position. A nil Scanner finds nothing.
+func (s *Scanner) Scan(content string) (string, []Finding) {
+	if s == nil || content == "" {
+		return content, nil
+	}
+
+	var spans []span
+	for _, p := range s.patterns {
+		if len(spans) >= maxFindings {
+			break
+		}
+		for _, m := range p.re.FindAllStringSubmatchIndex(content, -1) {
+			start, end := m[0], m[1]
+			if p.group > 0 && m[2*p.group] >= 0 {
+				start, end = m[2*p.group], m[2*p.group+1]
+			}
+			if p.checkEntropy && entropy(content[start:end]) < s.minEntropy {
+				continue
+			}
+			spans = append(spans, span{start: start, end: end, name: p.name})
+			if len(spans) >= maxFindings {
+				break
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return content, nil
+	}
+
+	// Mask left to right, skipping spans already covered by an earlier one
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end > spans[j].end
+	})
+
+	var b strings.Builder
+	findings := make([]Finding, 0, len(spans))
+	pos, line := 0, 1
+	for _, sp := range spans {
+		if sp.start < pos {
+			continue
+		}
+		line += strings.Count(content[pos:sp.start], "\n")
+		findings = append(findings, Finding{Pattern: sp.name, Line: line})
+		b.WriteString(content[pos:sp.start])
+		b.WriteString("[REDACTED:" + sp.name + "]")
+		line += strings.Count(content[sp.start:sp.end], "\n")
+		pos = sp.end
+	}
+	b.WriteString(content[pos:])
+	return b.String(), findings
+}
+
+// entropy returns the Shannon entropy of value in bits per character
+func entropy(value string) float64 {
+	if value == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range value {
+		counts[r]++
+		total++
+	}
+	var h float64
+	for _, n := range counts {
+		p := float64(n) / float64(total)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
@@ -0,0 +1,144 @@
+package security
+
+import (
+	"fmt"
+	"time"
+)
+
+// AccessMode describes which operations an allowed directory permits
+type AccessMode string
+
+const (
+	// ModeReadWrite permits reading, writing and deleting
+	ModeReadWrite AccessMode = "read-write"
+
+	// ModeReadOnly permits reading and listing only
+	ModeReadOnly AccessMode = "read-only"
+
+	// ModeWriteOnly permits creating and overwriting but not reading or deleting
+	ModeWriteOnly AccessMode = "write-only"
+
+	// ModeNoDelete permits reading and writing but not removing or renaming away entries
+	ModeNoDelete AccessMode = "no-delete"
+)
+
+// Operation identifies the kind of access being attempted on a path
+type Operation int
+
+const (
+	// OpRead covers reading file contents, listing and stat calls
+	OpRead Operation = iota
+
+	// OpWrite covers creating and modifying files and directories
+	OpWrite
+
+	// OpDelete covers removing an entry, including the source of a move
+	OpDelete
+)
+
+// String returns a human readable name for the operation
+func (op Operation) String() string {
+	switch op {
+	case OpRead:
+		return "read"
+	case OpWrite:
+		return "write"
+	case OpDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseAccessMode validates an access mode string, defaulting to read-write when empty
+func ParseAccessMode(mode string) (AccessMode, error) {
+	switch AccessMode(mode) {
+	case "":
+		return ModeReadWrite, nil
+	case ModeReadWrite, ModeReadOnly, ModeWriteOnly, ModeNoDelete:
+		return AccessMode(mode), nil
+	default:
+		return "", fmt.Errorf("invalid access mode: %s", mode)
+	}
+}
+
+// Permits reports whether the access mode allows the given operation
+func (m AccessMode) Permits(op Operation) bool {
+	switch m {
+	case ModeReadWrite:
+		return op == OpRead || op == OpWrite || op == OpDelete
+	case ModeReadOnly:
+		return op == OpRead
+	case ModeWriteOnly:
+		return op == OpWrite
+	case ModeNoDelete:
+		return op == OpRead || op == OpWrite
+	default:
+		return false
+	}
+}
+
+// Intersect returns the mode permitting only the operations both modes
+// permit, and false when they have none in common
+func (m AccessMode) Intersect(other AccessMode) (AccessMode, bool) {
+	ops := []Operation{OpRead, OpWrite, OpDelete}
+	for _, mode := range []AccessMode{ModeReadWrite, ModeNoDelete, ModeReadOnly, ModeWriteOnly} {
+		same := true
+		for _, op := range ops {
+			if mode.Permits(op) != (m.Permits(op) && other.Permits(op)) {
+				same = false
+				break
+			}
+		}
+		if same {
+			return mode, true
+		}
+	}
+	return "", false
+}
+
+// Root is an allowed directory together with the access mode it grants
+type Root struct {
+	Path     string
+	Mode     AccessMode
+	Symlinks SymlinkPolicy
+
+	// Expires is when a runtime grant ends; zero means never
+	Expires time.Time
+
+	// realPath is Path with symlinks resolved, used to detect links
+	// traversed beneath the root
+	realPath string
+
+	// granted marks roots added at runtime rather than configured
+	granted bool
+}
+
+// Granted reports whether the root was added at runtime
+func (r Root) Granted() bool {
+	return r.granted
+}
+
+// expired reports whether a runtime grant has ended at now
+func (r Root) expired(now time.Time) bool {
+	return !r.Expires.IsZero() && !now.Before(r.Expires)
+}
+
+// Options configures a PathValidator
+type Options struct {
+	// Roots lists the allowed directories and their access modes
+	Roots []Root
+
+	// DenyPatterns lists doublestar globs for paths that are never accessible
+	DenyPatterns []string
+
+	// HardlinkPolicy decides whether regular files with several links are accessible
+	HardlinkPolicy HardlinkPolicy
+
+	// Grants holds directories added at runtime; nil disables AddRoot
+	Grants *Grants
+
+	// GrantScope limits the runtime grants to these directories, such as
+	// the roots advertised by an MCP client; nil leaves them unlimited
+	GrantScope []string
+}
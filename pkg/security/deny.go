@@ -0,0 +1,57 @@
+package security
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// isDenied reports whether a path matches any configured deny pattern.
+// Relative patterns are matched against the path relative to its allowed
+// directory, absolute patterns against the full path.
+func (pv *PathValidator) isDenied(path string) bool {
+	if len(pv.denyPatterns) == 0 {
+		return false
+	}
+
+	absSlash := filepath.ToSlash(filepath.Clean(path))
+	relSlash := ""
+	if root := pv.rootFor(path); root != nil {
+		if rel, err := filepath.Rel(root.Path, path); err == nil && rel != "." {
+			relSlash = filepath.ToSlash(rel)
+		}
+	}
+
+	// Check each pattern
+	for _, pattern := range pv.denyPatterns {
+		target := relSlash
+		if strings.HasPrefix(pattern, "/") {
+			target = absSlash
+		}
+		if target == "" {
+			continue
+		}
+		if matched, err := doublestar.Match(pattern, target); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ShouldList reports whether a path may appear in directory listings, trees
+// and search results. Paths matching a deny pattern, directly or through a
+// symlink, are hidden, as are links under a never-follow symlink policy.
+func (pv *PathValidator) ShouldList(path string) bool {
+	absPath := filepath.Clean(path)
+	if pv.isDenied(absPath) || pv.isHiddenSymlink(absPath) {
+		return false
+	}
+	if realPath, err := filepath.EvalSymlinks(absPath); err == nil && realPath != absPath {
+		if pv.isDenied(realPath) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors of the validator, matched with errors.Is. Refusals keep their own
+// messages, which begin with the error's text where it reads naturally.
+var (
+	// ErrAccessDenied refuses a path outside the allowed directories or
+	// otherwise not permitted
+	ErrAccessDenied = errors.New("access denied")
+
+	// ErrParentNotFound refuses a new path whose parent does not exist
+	ErrParentNotFound = errors.New("parent directory does not exist")
+
+	// ErrInvalidName refuses a name the naming policy does not allow
+	ErrInvalidName = errors.New("invalid name")
+
+	// ErrInvalidGrant refuses a directory grant or revocation the caller
+	// asked for wrongly
+	ErrInvalidGrant = errors.New("invalid directory grant")
+)
+
+// refusal is an error with its own message that matches kind
+type refusal struct {
+	kind error
+	msg  string
+}
+
+// refuse returns an error formatted like fmt.Errorf that matches kind with
+// errors.Is without repeating its text
+func refuse(kind error, format string, args ...interface{}) error {
+	return &refusal{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
+
+// Error implements the error interface
+func (r *refusal) Error() string {
+	return r.msg
+}
+
+// Is reports whether target is the kind of the refusal
+func (r *refusal) Is(target error) bool {
+	return target == r.kind
+}
@@ -0,0 +1,278 @@
+package security
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxGrants bounds the directories granted at runtime per Rule 2
+const maxGrants = 64
+
+// Grants holds allowed directories added at runtime. One set is shared by
+// the path validators of successive configurations so grants survive a
+// reload. It is safe for concurrent use.
+type Grants struct {
+	// mu guards roots and timers. roots is replaced rather than modified,
+	// so a slice returned by active stays valid.
+	mu     sync.RWMutex
+	roots  []Root
+	timers map[string]*time.Timer
+
+	onChange func()
+	logger   *slog.Logger
+	now      func() time.Time
+}
+
+// NewGrants creates an empty grant set. onChange, when not nil, runs after
+// a grant is added, removed or expires.
+func NewGrants(onChange func(), logger *slog.Logger) *Grants {
+	return &Grants{
+		timers:   make(map[string]*time.Timer),
+		onChange: onChange,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// active returns the unexpired grants. A nil set has none.
+func (g *Grants) active() []Root {
+	if g == nil {
+		return nil
+	}
+	g.mu.RLock()
+	roots := g.roots
+	g.mu.RUnlock()
+
+	now := g.now()
+	for i, root := range roots {
+		if root.expired(now) {
+			// Drop expired grants from the copy without waiting for the timer
+			live := make([]Root, 0, len(roots))
+			live = append(live, roots[:i]...)
+			for _, r := range roots[i+1:] {
+				if !r.expired(now) {
+					live = append(live, r)
+				}
+			}
+			return live
+		}
+	}
+	return roots
+}
+
+// add normalizes root and grants it, replacing an earlier grant of the
+// same directory so its expiry can be extended
+func (g *Grants) add(root Root) (Root, error) {
+	info, err := os.Stat(root.Path)
+	if err != nil {
+		return Root{}, fmt.Errorf("directory %s is not accessible: %w", root.Path, err)
+	}
+	if !info.IsDir() {
+		return Root{}, refuse(ErrInvalidGrant, "path %s is not a directory", root.Path)
+	}
+
+	root.granted = true
+	entries := normalizeRoot(root, g.logger)
+	key := entries[0].realPath
+
+	g.mu.Lock()
+	roots := make([]Root, 0, len(g.roots)+len(entries))
+	count := 0
+	for _, r := range g.roots {
+		if r.realPath == key {
+			continue
+		}
+		if r.Path == r.realPath {
+			count++
+		}
+		roots = append(roots, r)
+	}
+	if count >= maxGrants {
+		g.mu.Unlock()
+		return Root{}, refuse(ErrInvalidGrant, "too many granted directories (limit %d)", maxGrants)
+	}
+	g.roots = append(roots, entries...)
+
+	if timer, ok := g.timers[key]; ok {
+		timer.Stop()
+		delete(g.timers, key)
+	}
+	if !root.Expires.IsZero() {
+		expires := root.Expires
+		g.timers[key] = time.AfterFunc(expires.Sub(g.now()), func() {
+			g.expire(key, expires)
+		})
+	}
+	g.mu.Unlock()
+
+	g.changed()
+	return entries[0], nil
+}
+
+// remove revokes the grant of the directory at path, given by either its
+// granted or its real path. It reports whether a grant was found.
+func (g *Grants) remove(path string) bool {
+	path = filepath.Clean(path)
+
+	g.mu.Lock()
+	key := ""
+	for _, r := range g.roots {
+		if r.Path == path || r.realPath == path {
+			key = r.realPath
+			break
+		}
+	}
+	if key == "" {
+		g.mu.Unlock()
+		return false
+	}
+	g.dropLocked(func(r Root) bool { return r.realPath == key })
+	g.mu.Unlock()
+
+	g.changed()
+	return true
+}
+
+// Retain revokes every grant for which keep returns false and returns the
+// revoked grants
+func (g *Grants) Retain(keep func(Root) bool) []Root {
+	g.mu.Lock()
+	revoke := make(map[string]bool)
+	for _, r := range g.roots {
+		if !keep(r) {
+			revoke[r.realPath] = true
+		}
+	}
+	dropped := g.dropLocked(func(r Root) bool { return revoke[r.realPath] })
+	g.mu.Unlock()
+
+	if len(dropped) > 0 {
+		g.changed()
+	}
+	return dropped
+}
+
+// expire revokes the grant keyed by realPath if it still ends at expires
+func (g *Grants) expire(key string, expires time.Time) {
+	g.mu.Lock()
+	dropped := g.dropLocked(func(r Root) bool { return r.realPath == key && r.Expires.Equal(expires) })
+	g.mu.Unlock()
+
+	for _, root := range dropped {
+		g.logger.Info("Directory grant expired", "path", root.Path)
+	}
+	if len(dropped) > 0 {
+		g.changed()
+	}
+}
+
+// dropLocked removes the grants matching drop, stopping their timers, and
+// returns each once under its granted path
+func (g *Grants) dropLocked(drop func(Root) bool) []Root {
+	var dropped []Root
+	roots := make([]Root, 0, len(g.roots))
+	for _, r := range g.roots {
+		if !drop(r) {
+			roots = append(roots, r)
+			continue
+		}
+		if timer, ok := g.timers[r.realPath]; ok {
+			timer.Stop()
+			delete(g.timers, r.realPath)
+		}
+		// A symlinked grant's alias follows the entry under its granted path
+		if len(dropped) == 0 || dropped[len(dropped)-1].realPath != r.realPath {
+			dropped = append(dropped, r)
+		}
+	}
+	g.roots = roots
+	return dropped
+}
+
+// changed runs the change hook
+func (g *Grants) changed() {
+	if g.onChange != nil {
+		g.onChange()
+	}
+}
+
+// GrantPolicy limits the directories that may be granted at runtime
+type GrantPolicy struct {
+	// Parents are the directories beneath which access may be granted.
+	// A grant takes the mode and symlink policy of its parent.
+	Parents []Root
+
+	// MaxTTL caps how long a grant lasts; zero allows grants that never
+	// expire
+	MaxTTL time.Duration
+}
+
+// Grant returns the root to add for a request to access the absolute path
+// dir for ttl, where zero asks for the longest grant allowed. The directory
+// must exist and resolve beneath one of the parents.
+func (p *GrantPolicy) Grant(dir string, ttl time.Duration, now time.Time) (Root, error) {
+	// Input validation per Rule 7
+	if p == nil {
+		return Root{}, refuse(ErrInvalidGrant, "runtime directory grants are not enabled")
+	}
+	if !filepath.IsAbs(dir) {
+		return Root{}, refuse(ErrInvalidGrant, "path must be absolute: %s", dir)
+	}
+	if ttl < 0 {
+		return Root{}, refuse(ErrInvalidGrant, "invalid expiry: %s", ttl)
+	}
+	if p.MaxTTL > 0 {
+		if ttl == 0 {
+			ttl = p.MaxTTL
+		}
+		if ttl > p.MaxTTL {
+			return Root{}, refuse(ErrInvalidGrant, "expiry %s exceeds the maximum of %s", ttl, p.MaxTTL)
+		}
+	}
+
+	dir = filepath.Clean(dir)
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return Root{}, fmt.Errorf("directory %s is not accessible: %w", dir, err)
+	}
+	parent, ok := p.parentOf(realDir)
+	if !ok {
+		return Root{}, fmt.Errorf("%w - %s is not beneath a directory that allows grants", ErrAccessDenied, dir)
+	}
+
+	root := Root{Path: dir, Mode: parent.Mode, Symlinks: parent.Symlinks}
+	if ttl > 0 {
+		root.Expires = now.Add(ttl)
+	}
+	return root, nil
+}
+
+// Permits reports whether an existing grant is still beneath a parent
+func (p *GrantPolicy) Permits(root Root) bool {
+	if p == nil {
+		return false
+	}
+	_, ok := p.parentOf(root.realPath)
+	return ok
+}
+
+// parentOf returns the most specific parent containing the resolved path
+func (p *GrantPolicy) parentOf(realPath string) (Root, bool) {
+	var best Root
+	found := false
+	for _, parent := range p.Parents {
+		dir := resolveDir(parent.Path)
+		if !within(realPath, dir) {
+			continue
+		}
+		if !found || len(dir) > len(best.Path) {
+			best = Root{Path: dir, Mode: parent.Mode, Symlinks: parent.Symlinks}
+			found = true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+// +build !windows
+
+package security
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileIdentity identifies an inode and its link count
+type fileIdentity struct {
+	dev   uint64
+	ino   uint64
+	nlink uint64
+}
+
+// fileIdentityOf extracts the device, inode and link count from file information
+func fileIdentityOf(info os.FileInfo) (fileIdentity, bool) {
+	sys, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileIdentity{}, false
+	}
+	return fileIdentity{
+		dev:   uint64(sys.Dev),
+		ino:   uint64(sys.Ino),
+		nlink: uint64(sys.Nlink),
+	}, true
+}
@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package security
+
+import "os"
+
+// fileIdentity identifies an inode and its link count
+type fileIdentity struct {
+	dev   uint64
+	ino   uint64
+	nlink uint64
+}
+
+// fileIdentityOf reports that link counts are unavailable; os.FileInfo on
+// Windows does not expose them, so the hardlink policy cannot apply
+func fileIdentityOf(info os.FileInfo) (fileIdentity, bool) {
+	return fileIdentity{}, false
+}
@@ -0,0 +1,107 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// windowsReservedNames are device names Windows refuses as file names,
+// with or without an extension
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// windowsReservedChars are characters Windows does not allow in names
+const windowsReservedChars = `<>:"|?*\`
+
+// NamingPolicy restricts the names of files and directories created or
+// renamed through the server. The zero value allows any name.
+type NamingPolicy struct {
+	// RejectControlCharacters refuses names containing control characters,
+	// including newlines and tabs
+	RejectControlCharacters bool `yaml:"reject_control_characters"`
+
+	// RejectTrailingSpaceOrDot refuses names ending in a space or a dot
+	RejectTrailingSpaceOrDot bool `yaml:"reject_trailing_space_or_dot"`
+
+	// RejectWindowsReserved refuses device names such as CON or LPT1 and
+	// the characters < > : " | ? * \
+	RejectWindowsReserved bool `yaml:"reject_windows_reserved"`
+
+	// MaxSegmentLength is the longest name in bytes; zero means no limit
+	MaxSegmentLength int `yaml:"max_segment_length"`
+
+	// RejectCaseCollisions refuses names that differ from an existing
+	// sibling only by case
+	RejectCaseCollisions bool `yaml:"reject_case_collisions"`
+}
+
+// Enabled reports whether any rule is active
+func (p NamingPolicy) Enabled() bool {
+	return p != NamingPolicy{}
+}
+
+// Validate checks the policy settings
+func (p NamingPolicy) Validate() error {
+	if p.MaxSegmentLength < 0 {
+		return fmt.Errorf("invalid max_segment_length: %d", p.MaxSegmentLength)
+	}
+	return nil
+}
+
+// CheckName applies every rule that needs only the name itself. Sibling
+// collisions are checked by the caller, which can list the directory.
+func (p NamingPolicy) CheckName(name string) error {
+	if p.RejectControlCharacters {
+		for _, r := range name {
+			if unicode.IsControl(r) || r == '\u2028' || r == '\u2029' {
+				return fmt.Errorf("%w %q: contains control character %U", ErrInvalidName, name, r)
+			}
+		}
+	}
+
+	if p.RejectTrailingSpaceOrDot {
+		if strings.HasSuffix(name, " ") {
+			return fmt.Errorf("%w %q: ends with a space", ErrInvalidName, name)
+		}
+		if strings.HasSuffix(name, ".") {
+			return fmt.Errorf("%w %q: ends with a dot", ErrInvalidName, name)
+		}
+	}
+
+	if p.RejectWindowsReserved {
+		if i := strings.IndexAny(name, windowsReservedChars); i >= 0 {
+			return fmt.Errorf("%w %q: contains %q, which Windows does not allow", ErrInvalidName, name, name[i])
+		}
+		base, _, _ := strings.Cut(name, ".")
+		base = strings.ToUpper(strings.TrimRight(base, " "))
+		if windowsReservedNames[base] {
+			return fmt.Errorf("%w %q: %s is a reserved device name on Windows", ErrInvalidName, name, base)
+		}
+	}
+
+	if p.MaxSegmentLength > 0 && len(name) > p.MaxSegmentLength {
+		return fmt.Errorf("%w %q: %d bytes long, limit is %d", ErrInvalidName, name, len(name), p.MaxSegmentLength)
+	}
+
+	return nil
+}
+
+// CaseCollision returns an error when name differs from one of siblings
+// only by case
+func (p NamingPolicy) CaseCollision(name string, siblings []string) error {
+	if !p.RejectCaseCollisions {
+		return nil
+	}
+	for _, sibling := range siblings {
+		if sibling != name && strings.EqualFold(sibling, name) {
+			return fmt.Errorf("%w %q: differs only by case from existing %q", ErrInvalidName, name, sibling)
+		}
+	}
+	return nil
+}
@@ -10,42 +10,88 @@ import (
 
 // PathValidator provides secure path validation and access control
 type PathValidator struct {
-	allowedDirectories []string
-	logger             *slog.Logger
+	roots          []Root
+	denyPatterns   []string
+	hardlinkPolicy HardlinkPolicy
+	grants         *Grants
+	grantScope     []string
+	logger         *slog.Logger
 }
 
-// NewPathValidator creates a new path validator with allowed directories
+// NewPathValidator creates a new path validator granting read-write access to allowed directories
 func NewPathValidator(allowedDirs []string, logger *slog.Logger) *PathValidator {
+	roots := make([]Root, 0, len(allowedDirs))
+	for _, d := range allowedDirs {
+		roots = append(roots, Root{Path: d, Mode: ModeReadWrite})
+	}
+	return NewPathValidatorWithOptions(Options{Roots: roots}, logger)
+}
+
+// NewPathValidatorWithOptions creates a new path validator from per-root options
+func NewPathValidatorWithOptions(opts Options, logger *slog.Logger) *PathValidator {
 	// Pre-allocate with known size per Rule 3 (no dynamic allocation after init)
-	normalizedDirs := make([]string, 0, len(allowedDirs))
+	normalizedRoots := make([]Root, 0, len(opts.Roots))
 
 	// Normalize all allowed directories and resolve symlinks
-	for _, d := range allowedDirs {
-		dir := filepath.Clean(d)
-		// Try to resolve symlinks for allowed directories too
-		realDir, err := filepath.EvalSymlinks(dir)
-		if err != nil {
-			// If symlink resolution fails, use cleaned path
-			logger.Debug("Cannot resolve symlinks for allowed directory, using original", "dir", dir, "error", err)
-			normalizedDirs = append(normalizedDirs, dir)
-		} else {
-			// Use both the original and real path for better compatibility
-			normalizedDirs = append(normalizedDirs, dir)
-			if realDir != dir {
-				normalizedDirs = append(normalizedDirs, realDir)
-			}
-		}
+	for _, r := range opts.Roots {
+		normalizedRoots = append(normalizedRoots, normalizeRoot(r, logger)...)
+	}
+
+	// Copy deny patterns to prevent later modification by the caller
+	denyPatterns := make([]string, len(opts.DenyPatterns))
+	copy(denyPatterns, opts.DenyPatterns)
+
+	hardlinkPolicy := opts.HardlinkPolicy
+	if hardlinkPolicy == "" {
+		hardlinkPolicy = HardlinkAllow
 	}
 
 	return &PathValidator{
-		allowedDirectories: normalizedDirs,
-		logger:             logger,
+		roots:          normalizedRoots,
+		denyPatterns:   denyPatterns,
+		hardlinkPolicy: hardlinkPolicy,
+		grants:         opts.Grants,
+		grantScope:     opts.GrantScope,
+		logger:         logger,
 	}
 }
 
+// normalizeRoot cleans a root, applies the default mode and symlink policy
+// and resolves symlinks. A root reached through a symlink is returned under
+// both its original and its real path.
+func normalizeRoot(r Root, logger *slog.Logger) []Root {
+	dir := filepath.Clean(r.Path)
+	mode := r.Mode
+	if mode == "" {
+		mode = ModeReadWrite
+	}
+	symlinks := r.Symlinks
+	if symlinks == "" {
+		symlinks = SymlinkFollow
+	}
+
+	// Try to resolve symlinks for allowed directories too
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		// If symlink resolution fails, use cleaned path
+		logger.Debug("Cannot resolve symlinks for allowed directory, using original", "dir", dir, "error", err)
+		realDir = dir
+	}
+
+	root := Root{Path: dir, Mode: mode, Symlinks: symlinks, Expires: r.Expires, realPath: realDir, granted: r.granted}
+	if realDir == dir {
+		return []Root{root}
+	}
+	// Use both the original and real path for better compatibility
+	alias := root
+	alias.Path = realDir
+	return []Root{root, alias}
+}
+
 // ValidatePath securely validates a requested path against allowed directories
+// and the access mode of the directory that contains it.
 // Returns the real absolute path if valid, error otherwise
-func (pv *PathValidator) ValidatePath(requestedPath string) (string, error) {
+func (pv *PathValidator) ValidatePath(requestedPath string, op Operation) (string, error) {
 	// Input validation per Rule 7 (check parameter validity)
 	if requestedPath == "" {
 		pv.logger.Warn("Empty path provided for validation")
@@ -73,8 +119,8 @@ func (pv *PathValidator) ValidatePath(requestedPath string) (string, error) {
 		pv.logger.Warn("Access denied to path outside allowed directories",
 			"requested_path", requestedPath,
 			"absolute_path", absolutePath,
-			"allowed_dirs", pv.allowedDirectories)
-		return "", fmt.Errorf("access denied - path outside allowed directories: %s", absolutePath)
+			"allowed_dirs", pv.GetAllowedDirectories())
+		return "", fmt.Errorf("%w - path outside allowed directories: %s", ErrAccessDenied, absolutePath)
 	}
 
 	// Handle symlinks by checking their real path
@@ -83,27 +129,82 @@ func (pv *PathValidator) ValidatePath(requestedPath string) (string, error) {
 		return "", err
 	}
 
+	// Reject sensitive files, including when reached through a symlink
+	if pv.isDenied(absolutePath) || pv.isDenied(realPath) {
+		pv.logger.Warn("Access denied to path matching deny pattern",
+			"requested_path", requestedPath,
+			"real_path", realPath)
+		return "", fmt.Errorf("%w - path matches a deny pattern: %s", ErrAccessDenied, absolutePath)
+	}
+
+	// Both the requested location and its resolved target must permit the operation
+	if err := pv.checkAccess(absolutePath, op); err != nil {
+		return "", err
+	}
+	if realPath != absolutePath {
+		if err := pv.checkAccess(realPath, op); err != nil {
+			return "", err
+		}
+	}
+
 	pv.logger.Debug("Path validation successful",
 		"requested_path", requestedPath,
-		"real_path", realPath)
+		"real_path", realPath,
+		"operation", op.String())
 
 	return realPath, nil
 }
 
 // isPathAllowed checks if a path is within any allowed directory
 func (pv *PathValidator) isPathAllowed(absolutePath string) bool {
+	return pv.rootFor(absolutePath) != nil
+}
+
+// rootFor returns the most specific allowed directory containing the path, or nil
+func (pv *PathValidator) rootFor(absolutePath string) *Root {
 	normalizedPath := filepath.Clean(absolutePath)
 
+	roots := pv.allRoots()
+	var best *Root
 	// Check against each allowed directory
-	for _, allowedDir := range pv.allowedDirectories {
+	for i := range roots {
+		root := &roots[i]
 
-		// Check if path starts with allowed directory
-		if pv.isPathUnderDirectory(normalizedPath, allowedDir) {
-			return true
+		// Prefer the longest matching directory so nested roots override their parents
+		if pv.isPathUnderDirectory(normalizedPath, root.Path) {
+			if best == nil || len(root.Path) > len(best.Path) {
+				best = root
+			}
 		}
 	}
 
-	return false
+	return best
+}
+
+// RootOf returns the most specific allowed directory containing path
+func (pv *PathValidator) RootOf(path string) (string, bool) {
+	root := pv.rootFor(path)
+	if root == nil {
+		return "", false
+	}
+	return root.Path, true
+}
+
+// checkAccess verifies the access mode of the directory containing path permits op
+func (pv *PathValidator) checkAccess(path string, op Operation) error {
+	root := pv.rootFor(path)
+	if root == nil {
+		return fmt.Errorf("%w - path outside allowed directories: %s", ErrAccessDenied, path)
+	}
+	if !root.Mode.Permits(op) {
+		pv.logger.Warn("Operation not permitted by directory access mode",
+			"path", path,
+			"operation", op.String(),
+			"root", root.Path,
+			"mode", root.Mode)
+		return fmt.Errorf("%w - %s not permitted in %s directory: %s", ErrAccessDenied, op, root.Mode, root.Path)
+	}
+	return nil
 }
 
 // isPathUnderDirectory checks if a path is under a given directory
@@ -127,14 +228,19 @@ func (pv *PathValidator) validateRealPath(absolutePath string) (string, error) {
 			pv.logger.Debug("Parent directory does not exist",
 				"parent_dir", parentDir,
 				"error", parentErr)
-			return "", fmt.Errorf("parent directory does not exist: %s", parentDir)
+			return "", fmt.Errorf("%w: %s", ErrParentNotFound, parentDir)
 		}
 
 		// Validate parent directory is allowed
 		if !pv.isPathAllowed(realParentPath) {
 			pv.logger.Warn("Parent directory outside allowed directories",
 				"parent_dir", realParentPath)
-			return "", fmt.Errorf("access denied - parent directory outside allowed directories")
+			return "", fmt.Errorf("%w - parent directory outside allowed directories", ErrAccessDenied)
+		}
+		if pv.isPathAllowed(parentDir) {
+			if err := pv.checkSymlinkPolicy(parentDir, realParentPath); err != nil {
+				return "", err
+			}
 		}
 
 		// Return the original absolute path for new files
@@ -145,7 +251,10 @@ func (pv *PathValidator) validateRealPath(absolutePath string) (string, error) {
 	if !pv.isPathAllowed(realPath) {
 		pv.logger.Warn("Symlink target outside allowed directories",
 			"symlink_target", realPath)
-		return "", fmt.Errorf("access denied - symlink target outside allowed directories")
+		return "", fmt.Errorf("%w - symlink target outside allowed directories", ErrAccessDenied)
+	}
+	if err := pv.checkSymlinkPolicy(absolutePath, realPath); err != nil {
+		return "", err
 	}
 
 	return realPath, nil
@@ -154,7 +263,103 @@ func (pv *PathValidator) validateRealPath(absolutePath string) (string, error) {
 // GetAllowedDirectories returns a copy of allowed directories
 func (pv *PathValidator) GetAllowedDirectories() []string {
 	// Return copy to prevent modification per Rule 6 (data hiding)
-	dirs := make([]string, len(pv.allowedDirectories))
-	copy(dirs, pv.allowedDirectories)
+	roots := pv.allRoots()
+	dirs := make([]string, 0, len(roots))
+	for _, root := range roots {
+		dirs = append(dirs, root.Path)
+	}
 	return dirs
 }
+
+// GetRoots returns a copy of allowed directories together with their access modes
+func (pv *PathValidator) GetRoots() []Root {
+	all := pv.allRoots()
+	roots := make([]Root, len(all))
+	copy(roots, all)
+	return roots
+}
+
+// AddRoot grants access to an existing directory until root.Expires,
+// replacing an earlier grant of the same directory. Configured directories
+// cannot be granted again.
+func (pv *PathValidator) AddRoot(root Root) (Root, error) {
+	// Input validation per Rule 7
+	if pv.grants == nil {
+		return Root{}, refuse(ErrInvalidGrant, "runtime directory grants are not enabled")
+	}
+	if root.Path == "" {
+		return Root{}, fmt.Errorf("path cannot be empty")
+	}
+
+	dir := filepath.Clean(root.Path)
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		realDir = dir
+	}
+	for _, r := range pv.roots {
+		if r.Path == dir || r.realPath == realDir {
+			return Root{}, refuse(ErrInvalidGrant, "%s is already an allowed directory", dir)
+		}
+	}
+
+	granted, err := pv.grants.add(root)
+	if err != nil {
+		return Root{}, err
+	}
+	pv.logger.Info("Directory access granted",
+		"path", granted.Path,
+		"mode", granted.Mode,
+		"expires", granted.Expires)
+	return granted, nil
+}
+
+// RemoveRoot revokes a directory granted with AddRoot. Configured
+// directories cannot be removed.
+func (pv *PathValidator) RemoveRoot(path string) error {
+	// Input validation per Rule 7
+	if pv.grants == nil {
+		return refuse(ErrInvalidGrant, "runtime directory grants are not enabled")
+	}
+	if path == "" {
+		return fmt.Errorf("path cannot be empty")
+	}
+
+	dir := filepath.Clean(path)
+	for _, r := range pv.roots {
+		if r.Path == dir {
+			return refuse(ErrInvalidGrant, "%s is a configured allowed directory and cannot be removed", dir)
+		}
+	}
+	if !pv.grants.remove(dir) {
+		return refuse(ErrInvalidGrant, "%s is not a granted directory", dir)
+	}
+	pv.logger.Info("Directory access revoked", "path", dir)
+	return nil
+}
+
+// allRoots returns the configured roots followed by the unexpired runtime
+// grants within the grant scope. The result must not be modified.
+func (pv *PathValidator) allRoots() []Root {
+	granted := pv.scopeGrants(pv.grants.active())
+	if len(granted) == 0 {
+		return pv.roots
+	}
+	roots := make([]Root, 0, len(pv.roots)+len(granted))
+	roots = append(roots, pv.roots...)
+	return append(roots, granted...)
+}
+
+// scopeGrants narrows granted to the grant scope, so a grant made by or for
+// another session cannot widen one limited to its client roots
+func (pv *PathValidator) scopeGrants(granted []Root) []Root {
+	if pv.grantScope == nil || len(granted) == 0 {
+		return granted
+	}
+	scoped := IntersectRoots(granted, pv.grantScope)
+	roots := make([]Root, 0, len(scoped))
+	for _, r := range scoped {
+		r.granted = true
+		roots = append(roots, normalizeRoot(r, pv.logger)...)
+	}
+	return roots
+}
@@ -5,7 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func newValidator(t *testing.T) (*PathValidator, string) {
@@ -23,7 +25,7 @@ func TestValidatePathWithinAllowed(t *testing.T) {
 		t.Fatalf("prep file: %v", err)
 	}
 
-	p, err := pv.ValidatePath(file)
+	p, err := pv.ValidatePath(file, OpRead)
 	if err != nil {
 		t.Fatalf("validate error: %v", err)
 	}
@@ -35,7 +37,7 @@ func TestValidatePathWithinAllowed(t *testing.T) {
 func TestValidatePathOutsideAllowed(t *testing.T) {
 	pv, _ := newValidator(t)
 	outside := filepath.Join(os.TempDir(), "outside.txt")
-	if _, err := pv.ValidatePath(outside); err == nil {
+	if _, err := pv.ValidatePath(outside, OpRead); err == nil {
 		t.Fatalf("expected error for outside path")
 	}
 }
@@ -51,7 +53,7 @@ func TestValidateSymlinkTarget(t *testing.T) {
 		t.Fatalf("symlink: %v", err)
 	}
 
-	p, err := pv.ValidatePath(link)
+	p, err := pv.ValidatePath(link, OpRead)
 	if err != nil {
 		t.Fatalf("validate symlink: %v", err)
 	}
@@ -74,7 +76,7 @@ func TestValidateSymlinkOutside(t *testing.T) {
 		t.Fatalf("symlink: %v", err)
 	}
 
-	if _, err := pv.ValidatePath(link); err == nil {
+	if _, err := pv.ValidatePath(link, OpRead); err == nil {
 		t.Fatalf("expected error for outside symlink")
 	}
 }
@@ -141,3 +143,404 @@ func TestIsPathUnderDirectoryRelativeWindows(t *testing.T) {
 		t.Fatalf("expected false for outside path")
 	}
 }
+
+func TestValidatePathAccessModes(t *testing.T) {
+	base := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	ro := filepath.Join(base, "ro")
+	wo := filepath.Join(base, "wo")
+	nd := filepath.Join(base, "nd")
+	for _, d := range []string{ro, wo, nd} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	pv := NewPathValidatorWithOptions(Options{Roots: []Root{
+		{Path: base, Mode: ModeReadWrite},
+		{Path: ro, Mode: ModeReadOnly},
+		{Path: wo, Mode: ModeWriteOnly},
+		{Path: nd, Mode: ModeNoDelete},
+	}}, logger)
+
+	cases := []struct {
+		path    string
+		op      Operation
+		allowed bool
+	}{
+		{filepath.Join(base, "a.txt"), OpWrite, true},
+		{filepath.Join(base, "a.txt"), OpDelete, true},
+		{filepath.Join(ro, "a.txt"), OpRead, true},
+		{filepath.Join(ro, "a.txt"), OpWrite, false},
+		{filepath.Join(ro, "a.txt"), OpDelete, false},
+		{filepath.Join(wo, "a.txt"), OpWrite, true},
+		{filepath.Join(wo, "a.txt"), OpRead, false},
+		{filepath.Join(nd, "a.txt"), OpWrite, true},
+		{filepath.Join(nd, "a.txt"), OpDelete, false},
+	}
+	for _, c := range cases {
+		_, err := pv.ValidatePath(c.path, c.op)
+		if c.allowed && err != nil {
+			t.Fatalf("%s %s: unexpected error: %v", c.op, c.path, err)
+		}
+		if !c.allowed && err == nil {
+			t.Fatalf("%s %s: expected access denied", c.op, c.path)
+		}
+	}
+}
+
+func TestValidateSymlinkIntoReadOnlyRoot(t *testing.T) {
+	rw := t.TempDir()
+	ro := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	pv := NewPathValidatorWithOptions(Options{Roots: []Root{
+		{Path: rw, Mode: ModeReadWrite},
+		{Path: ro, Mode: ModeReadOnly},
+	}}, logger)
+
+	target := filepath.Join(ro, "target.txt")
+	if err := os.WriteFile(target, []byte("x"), 0644); err != nil {
+		t.Fatalf("prep target: %v", err)
+	}
+	link := filepath.Join(rw, "link.txt")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	if _, err := pv.ValidatePath(link, OpRead); err != nil {
+		t.Fatalf("read through symlink: %v", err)
+	}
+	if _, err := pv.ValidatePath(link, OpWrite); err == nil {
+		t.Fatalf("expected write through symlink into read-only root to fail")
+	}
+}
+
+func TestValidatePathDenyPatterns(t *testing.T) {
+	base := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	pv := NewPathValidatorWithOptions(Options{
+		Roots:        []Root{{Path: base, Mode: ModeReadWrite}},
+		DenyPatterns: []string{"**/.env", "**/.ssh/**"},
+	}, logger)
+
+	if err := os.MkdirAll(filepath.Join(base, "app", ".ssh"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	env := filepath.Join(base, "app", ".env")
+	key := filepath.Join(base, "app", ".ssh", "id_rsa")
+	for _, p := range []string{env, key} {
+		if err := os.WriteFile(p, []byte("secret"), 0644); err != nil {
+			t.Fatalf("prep: %v", err)
+		}
+	}
+
+	if _, err := pv.ValidatePath(env, OpRead); err == nil {
+		t.Fatalf("expected .env to be denied")
+	}
+	if _, err := pv.ValidatePath(key, OpRead); err == nil {
+		t.Fatalf("expected .ssh contents to be denied")
+	}
+	if _, err := pv.ValidatePath(filepath.Join(base, "app", "main.go"), OpWrite); err != nil {
+		t.Fatalf("unexpected denial: %v", err)
+	}
+
+	// A symlink with an innocent name must not expose a denied target
+	link := filepath.Join(base, "config.txt")
+	if err := os.Symlink(env, link); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	if _, err := pv.ValidatePath(link, OpRead); err == nil {
+		t.Fatalf("expected symlink to denied file to be rejected")
+	}
+	if pv.ShouldList(link) || pv.ShouldList(env) {
+		t.Fatalf("denied paths should not be listed")
+	}
+}
+
+func TestValidatePathSymlinkPolicies(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	tests := []struct {
+		policy      SymlinkPolicy
+		sameRootOK  bool
+		otherRootOK bool
+		listed      bool
+	}{
+		{SymlinkFollow, true, true, true},
+		{SymlinkSameRoot, true, false, true},
+		{SymlinkNever, false, false, false},
+		{SymlinkListOnly, false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			rootA := t.TempDir()
+			rootB := t.TempDir()
+			pv := NewPathValidatorWithOptions(Options{Roots: []Root{
+				{Path: rootA, Symlinks: tt.policy},
+				{Path: rootB},
+			}}, logger)
+
+			for _, dir := range []string{rootA, rootB} {
+				if err := os.WriteFile(filepath.Join(dir, "target.txt"), []byte("x"), 0644); err != nil {
+					t.Fatalf("prep target: %v", err)
+				}
+			}
+			sameLink := filepath.Join(rootA, "same.txt")
+			otherLink := filepath.Join(rootA, "other.txt")
+			if err := os.Symlink(filepath.Join(rootA, "target.txt"), sameLink); err != nil {
+				t.Fatalf("symlink: %v", err)
+			}
+			if err := os.Symlink(filepath.Join(rootB, "target.txt"), otherLink); err != nil {
+				t.Fatalf("symlink: %v", err)
+			}
+
+			if _, err := pv.ValidatePath(sameLink, OpRead); (err == nil) != tt.sameRootOK {
+				t.Errorf("same-root link: got err %v, want ok=%t", err, tt.sameRootOK)
+			}
+			if _, err := pv.ValidatePath(otherLink, OpRead); (err == nil) != tt.otherRootOK {
+				t.Errorf("other-root link: got err %v, want ok=%t", err, tt.otherRootOK)
+			}
+			if got := pv.ShouldList(sameLink); got != tt.listed {
+				t.Errorf("ShouldList = %t, want %t", got, tt.listed)
+			}
+
+			// Regular files are unaffected by the policy
+			if _, err := pv.ValidatePath(filepath.Join(rootA, "target.txt"), OpRead); err != nil {
+				t.Errorf("regular file: %v", err)
+			}
+			if _, err := pv.ValidatePath(filepath.Join(rootA, "new.txt"), OpWrite); err != nil {
+				t.Errorf("new file: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckOpenedFileHardlinkPolicies(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("link counts are not available on windows")
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	inside := filepath.Join(root, "inside.txt")
+	if err := os.WriteFile(inside, []byte("x"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.Link(inside, filepath.Join(root, "inside-link.txt")); err != nil {
+		t.Skipf("hard links not supported: %v", err)
+	}
+	escaped := filepath.Join(outside, "secret.txt")
+	if err := os.WriteFile(escaped, []byte("s"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	escapeLink := filepath.Join(root, "escape.txt")
+	if err := os.Link(escaped, escapeLink); err != nil {
+		t.Skipf("hard links not supported: %v", err)
+	}
+
+	tests := []struct {
+		policy    HardlinkPolicy
+		insideOK  bool
+		escapedOK bool
+	}{
+		{HardlinkAllow, true, true},
+		{HardlinkVerify, true, false},
+		{HardlinkDeny, false, false},
+	}
+	for _, tt := range tests {
+		pv := NewPathValidatorWithOptions(Options{Roots: []Root{{Path: root}}, HardlinkPolicy: tt.policy}, logger)
+		for path, want := range map[string]bool{inside: tt.insideOK, escapeLink: tt.escapedOK} {
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("stat: %v", err)
+			}
+			if err := pv.CheckOpenedFile(path, info); (err == nil) != want {
+				t.Errorf("%s %s: got err %v, want ok=%t", tt.policy, filepath.Base(path), err, want)
+			}
+		}
+	}
+}
+
+func TestNamingPolicyCheckName(t *testing.T) {
+	policy := NamingPolicy{
+		RejectControlCharacters:  true,
+		RejectTrailingSpaceOrDot: true,
+		RejectWindowsReserved:    true,
+		MaxSegmentLength:         16,
+	}
+	rejected := map[string]string{
+		"foo.go\n":                 "control character U+000A",
+		"tab\there":                "control character U+0009",
+		"notes ":                   "ends with a space",
+		"archive.":                 "ends with a dot",
+		"CON.txt":                  "reserved device name",
+		"lpt1":                     "reserved device name",
+		"a:b":                      "Windows does not allow",
+		"a_very_long_file_name.go": "limit is 16",
+	}
+	for name, want := range rejected {
+		err := policy.CheckName(name)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("CheckName(%q) = %v; want error containing %q", name, err, want)
+		}
+	}
+	for _, name := range []string{"main.go", ".gitignore", "CONFIG.txt", "console"} {
+		if err := policy.CheckName(name); err != nil {
+			t.Errorf("CheckName(%q) = %v; want nil", name, err)
+		}
+	}
+	if err := (NamingPolicy{}).CheckName("CON\n."); err != nil {
+		t.Errorf("zero policy should allow any name: %v", err)
+	}
+
+	collisions := NamingPolicy{RejectCaseCollisions: true}
+	if err := collisions.CaseCollision("readme.md", []string{"README.md"}); err == nil {
+		t.Errorf("expected case collision error")
+	}
+	if err := collisions.CaseCollision("README.md", []string{"README.md"}); err != nil {
+		t.Errorf("identical names are not a collision: %v", err)
+	}
+}
+
+func TestRuntimeGrants(t *testing.T) {
+	base, parent := t.TempDir(), t.TempDir()
+	granted := filepath.Join(parent, "project")
+	if err := os.Mkdir(granted, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(parent, "escape")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	changes := 0
+	grants := NewGrants(func() { changes++ }, logger)
+	now := time.Now()
+	grants.now = func() time.Time { return now }
+	pv := NewPathValidatorWithOptions(Options{Roots: []Root{{Path: base}}, Grants: grants}, logger)
+	grantPolicy := &GrantPolicy{Parents: []Root{{Path: parent, Mode: ModeReadOnly}}, MaxTTL: time.Hour}
+
+	if _, err := grantPolicy.Grant(filepath.Join(parent, "escape"), 0, now); err == nil {
+		t.Fatalf("expected a symlink out of the parent to be refused")
+	}
+	if _, err := grantPolicy.Grant(granted, 2*time.Hour, now); err == nil {
+		t.Fatalf("expected an expiry beyond max_ttl to be refused")
+	}
+
+	root, err := grantPolicy.Grant(granted, 0, now)
+	if err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+	if root.Mode != ModeReadOnly || !root.Expires.Equal(now.Add(time.Hour)) {
+		t.Fatalf("grant should take the parent mode and max_ttl: %+v", root)
+	}
+	if _, err := pv.AddRoot(root); err != nil {
+		t.Fatalf("add root: %v", err)
+	}
+	if changes != 1 {
+		t.Fatalf("expected one change notification, got %d", changes)
+	}
+
+	file := filepath.Join(granted, "a.txt")
+	if _, err := pv.ValidatePath(file, OpRead); err != nil {
+		t.Fatalf("granted directory should be readable: %v", err)
+	}
+	if _, err := pv.ValidatePath(file, OpWrite); err == nil {
+		t.Fatalf("granted directory should be read-only")
+	}
+
+	if err := pv.RemoveRoot(base); err == nil {
+		t.Fatalf("configured directories must not be removable")
+	}
+	if _, err := pv.AddRoot(Root{Path: base}); err == nil {
+		t.Fatalf("configured directories must not be granted again")
+	}
+
+	// Expired grants stop applying before their timer fires
+	now = now.Add(time.Hour)
+	if _, err := pv.ValidatePath(file, OpRead); err == nil {
+		t.Fatalf("expired grant should no longer allow access")
+	}
+
+	now = now.Add(-time.Minute)
+	if err := pv.RemoveRoot(granted); err != nil {
+		t.Fatalf("remove root: %v", err)
+	}
+	if _, err := pv.ValidatePath(file, OpRead); err == nil {
+		t.Fatalf("removed grant should no longer allow access")
+	}
+	if err := pv.RemoveRoot(granted); err == nil {
+		t.Fatalf("expected error removing a grant twice")
+	}
+}
+
+func TestIntersectRoots(t *testing.T) {
+	base, other := t.TempDir(), t.TempDir()
+	sdk := filepath.Join(base, "sdk")
+	project := filepath.Join(base, "project")
+	for _, dir := range []string{sdk, project} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	roots := []Root{
+		{Path: base, Mode: ModeReadWrite, Symlinks: SymlinkFollow},
+		{Path: sdk, Mode: ModeReadOnly, Symlinks: SymlinkNever},
+	}
+
+	got := IntersectRoots(roots, []string{project, other})
+	if len(got) != 1 || got[0].Path != project || got[0].Mode != ModeReadWrite {
+		t.Fatalf("expected only %s read-write, got %+v", project, got)
+	}
+
+	// A client root above the configured roots keeps them as they are
+	got = IntersectRoots(roots, []string{filepath.Dir(base)})
+	if len(got) != 2 || got[0].Path != base || got[1].Path != sdk || got[1].Mode != ModeReadOnly {
+		t.Fatalf("expected both configured roots unchanged, got %+v", got)
+	}
+
+	// The most specific configured root decides the mode
+	got = IntersectRoots(roots, []string{filepath.Join(sdk, "include")})
+	if len(got) != 1 || got[0].Mode != ModeReadOnly || got[0].Symlinks != SymlinkNever {
+		t.Fatalf("expected the sdk settings, got %+v", got)
+	}
+
+	if got := IntersectRoots(roots, nil); len(got) != 0 {
+		t.Fatalf("no client roots should grant nothing, got %+v", got)
+	}
+}
+
+func TestRestrictRoots(t *testing.T) {
+	base := t.TempDir()
+	sdk := filepath.Join(base, "sdk")
+	outbox := filepath.Join(base, "outbox")
+	for _, dir := range []string{sdk, outbox} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	roots := []Root{
+		{Path: base, Mode: ModeNoDelete, Symlinks: SymlinkSameRoot},
+		{Path: sdk, Mode: ModeReadOnly, Symlinks: SymlinkNever},
+	}
+
+	// Each directory permits what both the root and the token permit
+	got := RestrictRoots(roots, []Root{{Path: base, Mode: ModeReadWrite}})
+	if len(got) != 2 || got[0].Mode != ModeNoDelete || got[1].Path != sdk || got[1].Mode != ModeReadOnly {
+		t.Fatalf("expected the configured modes, got %+v", got)
+	}
+	got = RestrictRoots(roots, []Root{{Path: outbox, Mode: ModeWriteOnly}})
+	if len(got) != 1 || got[0].Mode != ModeWriteOnly || got[0].Symlinks != SymlinkSameRoot {
+		t.Fatalf("expected %s write-only, got %+v", outbox, got)
+	}
+
+	// A write-only token gets nothing from a read-only root
+	got = RestrictRoots(roots, []Root{{Path: base, Mode: ModeWriteOnly}})
+	if len(got) != 1 || got[0].Path != base {
+		t.Fatalf("expected only %s, got %+v", base, got)
+	}
+	if _, ok := ModeReadOnly.Intersect(ModeWriteOnly); ok {
+		t.Fatalf("read-only and write-only share no operation")
+	}
+}
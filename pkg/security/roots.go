@@ -0,0 +1,79 @@
+package security
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// IntersectRoots narrows roots to the directories in paths, such as the
+// roots advertised by an MCP client. A path inside a root becomes a root
+// with that root's mode and symlink policy, and a root inside a path is
+// kept as it is. Paths outside every root grant nothing.
+func IntersectRoots(roots []Root, paths []string) []Root {
+	scope := make([]Root, 0, len(paths))
+	for _, p := range paths {
+		scope = append(scope, Root{Path: p, Mode: ModeReadWrite})
+	}
+	return RestrictRoots(roots, scope)
+}
+
+// RestrictRoots narrows roots to the directories of scope, such as those
+// of an authentication token, as IntersectRoots does. Each result permits
+// only the operations both its root and its scope entry permit; a pair
+// with none in common grants nothing. Symlink policies and expiry times
+// come from roots.
+func RestrictRoots(roots []Root, scope []Root) []Root {
+	result := make([]Root, 0, len(scope))
+	seen := make(map[string]bool, len(scope))
+	add := func(path string, root Root, limit AccessMode) {
+		mode, ok := root.Mode.Intersect(limit)
+		if !ok || seen[path] {
+			return
+		}
+		seen[path] = true
+		result = append(result, Root{Path: path, Mode: mode, Symlinks: root.Symlinks, Expires: root.Expires})
+	}
+
+	for _, s := range scope {
+		dir := resolveDir(s.Path)
+
+		// The most specific root containing the directory decides its mode
+		var best *Root
+		bestLen := -1
+		for i := range roots {
+			rootDir := resolveDir(roots[i].Path)
+			if within(dir, rootDir) && len(rootDir) > bestLen {
+				best, bestLen = &roots[i], len(rootDir)
+			}
+		}
+		if best != nil {
+			add(dir, *best, s.Mode)
+		}
+
+		// Roots nested inside the directory keep their own settings
+		for _, root := range roots {
+			if rootDir := resolveDir(root.Path); rootDir != dir && within(rootDir, dir) {
+				add(root.Path, root, s.Mode)
+			}
+		}
+	}
+	return result
+}
+
+// resolveDir cleans dir and resolves symlinks when it exists
+func resolveDir(dir string) string {
+	dir = filepath.Clean(dir)
+	if realDir, err := filepath.EvalSymlinks(dir); err == nil {
+		return realDir
+	}
+	return dir
+}
+
+// within reports whether path is dir or lies beneath it
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,150 @@
+package security
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// HardlinkPolicy describes how regular files with more than one link are treated
+type HardlinkPolicy string
+
+const (
+	// HardlinkAllow permits files regardless of their link count
+	HardlinkAllow HardlinkPolicy = "allow"
+
+	// HardlinkVerify permits multiply linked files only when every link is
+	// found inside the allowed directories and none matches a deny pattern
+	HardlinkVerify HardlinkPolicy = "verify"
+
+	// HardlinkDeny refuses every regular file with more than one link
+	HardlinkDeny HardlinkPolicy = "deny"
+)
+
+// maxHardlinkScanEntries bounds the directory walk used to locate links per Rule 2
+const maxHardlinkScanEntries = 100000
+
+// ParseHardlinkPolicy validates a hardlink policy string, defaulting to allow when empty
+func ParseHardlinkPolicy(policy string) (HardlinkPolicy, error) {
+	switch HardlinkPolicy(policy) {
+	case "":
+		return HardlinkAllow, nil
+	case HardlinkAllow, HardlinkVerify, HardlinkDeny:
+		return HardlinkPolicy(policy), nil
+	default:
+		return "", fmt.Errorf("invalid hardlink policy: %s", policy)
+	}
+}
+
+// CheckFileType refuses FIFOs, sockets and device nodes, which can block a
+// reader forever or reach outside the filesystem
+func (pv *PathValidator) CheckFileType(path string, info os.FileInfo) error {
+	// Input validation per Rule 7
+	if info == nil {
+		return fmt.Errorf("file information is required")
+	}
+
+	if kind := specialFileKind(info.Mode()); kind != "" {
+		pv.logger.Warn("Refusing special file", "path", path, "type", kind)
+		return fmt.Errorf("%w - %s is a %s", ErrAccessDenied, path, kind)
+	}
+	return nil
+}
+
+// CheckOpenedFile inspects an opened file before its contents are used. In
+// addition to the file type check, regular files with several links are
+// checked against the hardlink policy.
+func (pv *PathValidator) CheckOpenedFile(path string, info os.FileInfo) error {
+	if err := pv.CheckFileType(path, info); err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() || pv.hardlinkPolicy == HardlinkAllow {
+		return nil
+	}
+
+	id, ok := fileIdentityOf(info)
+	if !ok || id.nlink <= 1 {
+		return nil
+	}
+
+	if pv.hardlinkPolicy == HardlinkVerify && pv.linksWithinRoots(id) {
+		return nil
+	}
+
+	pv.logger.Warn("Refusing file with multiple hard links",
+		"path", path,
+		"links", id.nlink,
+		"policy", pv.hardlinkPolicy)
+	return fmt.Errorf("%w - %s has %d hard links", ErrAccessDenied, path, id.nlink)
+}
+
+// specialFileKind names the type of a non-regular, non-directory file, or
+// returns an empty string for regular files, directories and symlinks
+func specialFileKind(mode fs.FileMode) string {
+	switch {
+	case mode&fs.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeCharDevice != 0:
+		return "character device"
+	case mode&fs.ModeDevice != 0:
+		return "device file"
+	case mode&fs.ModeIrregular != 0:
+		return "special file"
+	default:
+		return ""
+	}
+}
+
+// linksWithinRoots searches the allowed directories for every link to the
+// file and reports whether all of them were found at permitted paths. The
+// search gives up, refusing the file, after maxHardlinkScanEntries entries.
+func (pv *PathValidator) linksWithinRoots(target fileIdentity) bool {
+	links := make(map[string]bool)
+	scanned := 0
+	denied := false
+
+	for _, root := range pv.allRoots() {
+		err := filepath.WalkDir(root.realPath, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			scanned++
+			if scanned > maxHardlinkScanEntries {
+				return filepath.SkipAll
+			}
+			if !d.Type().IsRegular() || links[path] {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			id, ok := fileIdentityOf(info)
+			if !ok || id.dev != target.dev || id.ino != target.ino {
+				return nil
+			}
+			// A link at a denied path must not be reachable under another name
+			if pv.isDenied(path) {
+				denied = true
+				return filepath.SkipAll
+			}
+			links[path] = true
+			if uint64(len(links)) >= target.nlink {
+				return filepath.SkipAll
+			}
+			return nil
+		})
+		if err != nil || denied || scanned > maxHardlinkScanEntries {
+			return false
+		}
+		if uint64(len(links)) >= target.nlink {
+			return true
+		}
+	}
+
+	return false
+}
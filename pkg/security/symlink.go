@@ -0,0 +1,105 @@
+package security
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SymlinkPolicy describes how symlinks beneath an allowed directory are treated
+type SymlinkPolicy string
+
+const (
+	// SymlinkFollow follows links whose target is in any allowed directory
+	SymlinkFollow SymlinkPolicy = "follow"
+
+	// SymlinkSameRoot follows links only when the target is in the same allowed directory
+	SymlinkSameRoot SymlinkPolicy = "same-root"
+
+	// SymlinkNever hides links and refuses any path that traverses one
+	SymlinkNever SymlinkPolicy = "never"
+
+	// SymlinkListOnly shows links in listings but refuses to dereference them
+	SymlinkListOnly SymlinkPolicy = "list-only"
+)
+
+// ParseSymlinkPolicy validates a symlink policy string, defaulting to follow when empty
+func ParseSymlinkPolicy(policy string) (SymlinkPolicy, error) {
+	switch SymlinkPolicy(policy) {
+	case "":
+		return SymlinkFollow, nil
+	case SymlinkFollow, SymlinkSameRoot, SymlinkNever, SymlinkListOnly:
+		return SymlinkPolicy(policy), nil
+	default:
+		return "", fmt.Errorf("invalid symlink policy: %s", policy)
+	}
+}
+
+// Follows reports whether links may be dereferenced under the policy
+func (p SymlinkPolicy) Follows() bool {
+	return p == SymlinkFollow || p == SymlinkSameRoot
+}
+
+// Lists reports whether links appear in listings under the policy
+func (p SymlinkPolicy) Lists() bool {
+	return p != SymlinkNever
+}
+
+// SymlinkPolicyFor returns the symlink policy of the allowed directory
+// containing path, or SymlinkNever if it is outside all of them
+func (pv *PathValidator) SymlinkPolicyFor(path string) SymlinkPolicy {
+	root := pv.rootFor(filepath.Clean(path))
+	if root == nil {
+		return SymlinkNever
+	}
+	return root.Symlinks
+}
+
+// checkSymlinkPolicy verifies that resolving absolutePath to realPath did
+// not traverse a link the containing root's policy forbids
+func (pv *PathValidator) checkSymlinkPolicy(absolutePath, realPath string) error {
+	root := pv.rootFor(absolutePath)
+	if root == nil {
+		return fmt.Errorf("%w - path outside allowed directories: %s", ErrAccessDenied, absolutePath)
+	}
+
+	// A link was traversed when the resolved path differs from the path
+	// inside the resolved root
+	rel, err := filepath.Rel(root.Path, absolutePath)
+	if err != nil {
+		return fmt.Errorf("%w - cannot resolve path: %s", ErrAccessDenied, absolutePath)
+	}
+	if filepath.Join(root.realPath, rel) == realPath {
+		return nil
+	}
+
+	switch root.Symlinks {
+	case SymlinkFollow:
+		return nil
+	case SymlinkSameRoot:
+		if pv.isPathUnderDirectory(realPath, root.Path) || pv.isPathUnderDirectory(realPath, root.realPath) {
+			return nil
+		}
+		pv.logger.Warn("Symlink target outside the link's allowed directory",
+			"path", absolutePath,
+			"symlink_target", realPath,
+			"root", root.Path)
+		return fmt.Errorf("%w - symlink target outside %s", ErrAccessDenied, root.Path)
+	default:
+		pv.logger.Warn("Symlink refused by directory symlink policy",
+			"path", absolutePath,
+			"root", root.Path,
+			"policy", root.Symlinks)
+		return fmt.Errorf("%w - symlinks are not followed in %s", ErrAccessDenied, root.Path)
+	}
+}
+
+// isHiddenSymlink reports whether path is a link that the policy of its
+// allowed directory hides from listings
+func (pv *PathValidator) isHiddenSymlink(path string) bool {
+	if pv.SymlinkPolicyFor(path).Lists() {
+		return false
+	}
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
@@ -0,0 +1,145 @@
+// Package toolresult builds tool call results for both people and programs.
+// Each result carries its text first and then the same outcome as a JSON
+// object in a second text block, as MCP suggests for structured content.
+// Failures carry a machine-readable error code so callers need not parse
+// the English message.
+package toolresult
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"filesystem/pkg/filesystem"
+	"filesystem/pkg/policy"
+	"filesystem/pkg/security"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Error codes of failed calls
+const (
+	// CodeInvalidArgument: the arguments are missing or malformed
+	CodeInvalidArgument = "INVALID_ARGUMENT"
+
+	// CodeAccessDenied: the path is outside the allowed directories or
+	// not permitted there
+	CodeAccessDenied = "ACCESS_DENIED"
+
+	// CodePolicyDenied: a policy rule refused the call
+	CodePolicyDenied = "POLICY_DENIED"
+
+	// CodeNotFound: the file or directory does not exist
+	CodeNotFound = "NOT_FOUND"
+
+	// CodeExists: the destination already exists
+	CodeExists = "EXISTS"
+
+	// CodeTooLarge: a file, content or walk is over a size or depth limit
+	CodeTooLarge = "TOO_LARGE"
+
+	// CodeNoMatch: an edit's old text is not in the file
+	CodeNoMatch = "NO_MATCH"
+
+	// CodeQuotaExceeded: a session or root budget is used up for now
+	CodeQuotaExceeded = "QUOTA_EXCEEDED"
+
+	// CodeTimeout: the call ran out of time
+	CodeTimeout = "TIMEOUT"
+
+	// CodeCancelled: the client cancelled the call
+	CodeCancelled = "CANCELLED"
+
+	// CodeUnavailable: the server cannot take the call now, such as while
+	// shutting down
+	CodeUnavailable = "UNAVAILABLE"
+
+	// CodeInternal: any other failure
+	CodeInternal = "INTERNAL"
+)
+
+// errorPrefix begins the text of failed calls
+const errorPrefix = "Error: "
+
+// Failure is the payload of a failed call
+type Failure struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Text returns a successful result with text for people followed by
+// payload encoded as JSON
+func Text(text string, payload interface{}) *mcp.CallToolResult {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Errorf(CodeInternal, "Error: failed to encode result: %s", err)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{mcp.NewTextContent(text), mcp.NewTextContent(string(data))},
+	}
+}
+
+// Error returns a failed result for err, reading "Error: " and its message,
+// with the code given by Code
+func Error(err error) *mcp.CallToolResult {
+	return Errorf(Code(err), "%s%s", errorPrefix, err.Error())
+}
+
+// Errorf returns a failed result with the formatted text and code. The
+// payload's message is the text without its "Error: " prefix.
+func Errorf(code, format string, args ...interface{}) *mcp.CallToolResult {
+	text := fmt.Sprintf(format, args...)
+	data, err := json.Marshal(struct {
+		Error Failure `json:"error"`
+	}{Failure{Code: code, Message: strings.TrimPrefix(text, errorPrefix)}})
+	if err != nil {
+		return mcp.NewToolResultError(text)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{mcp.NewTextContent(text), mcp.NewTextContent(string(data))},
+		IsError: true,
+	}
+}
+
+// Payload returns the JSON payload of a result built by this package
+func Payload(result *mcp.CallToolResult) (json.RawMessage, bool) {
+	if result == nil || len(result.Content) < 2 {
+		return nil, false
+	}
+	text, ok := result.Content[len(result.Content)-1].(mcp.TextContent)
+	if !ok || !json.Valid([]byte(text.Text)) {
+		return nil, false
+	}
+	return json.RawMessage(text.Text), true
+}
+
+// Code classifies err by the sentinel errors of the filesystem packages
+func Code(err error) string {
+	var denied *policy.DeniedError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &denied):
+		return CodePolicyDenied
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	case errors.Is(err, context.Canceled):
+		return CodeCancelled
+	case errors.Is(err, security.ErrAccessDenied), errors.Is(err, fs.ErrPermission):
+		return CodeAccessDenied
+	case errors.Is(err, security.ErrParentNotFound), errors.Is(err, fs.ErrNotExist):
+		return CodeNotFound
+	case errors.Is(err, filesystem.ErrExists), errors.Is(err, fs.ErrExist):
+		return CodeExists
+	case errors.Is(err, filesystem.ErrTooLarge), errors.Is(err, filesystem.ErrTooDeep):
+		return CodeTooLarge
+	case errors.Is(err, filesystem.ErrNoMatch):
+		return CodeNoMatch
+	case errors.Is(err, security.ErrInvalidName), errors.Is(err, security.ErrInvalidGrant):
+		return CodeInvalidArgument
+	}
+	return CodeInternal
+}
@@ -0,0 +1,79 @@
+package toolresult
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"filesystem/pkg/filesystem"
+	"filesystem/pkg/policy"
+	"filesystem/pkg/security"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestCode(t *testing.T) {
+	_, statErr := os.Stat("/nonexistent/toolresult")
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{fmt.Errorf("%w - path outside allowed directories", security.ErrAccessDenied), CodeAccessDenied},
+		{fmt.Errorf("failed to stat file: %w", statErr), CodeNotFound},
+		{fmt.Errorf("%w: /a/b", security.ErrParentNotFound), CodeNotFound},
+		{fmt.Errorf("wrap: %w", filesystem.ErrExists), CodeExists},
+		{fmt.Errorf("file %w", filesystem.ErrTooLarge), CodeTooLarge},
+		{filesystem.ErrNoMatch, CodeNoMatch},
+		{fmt.Errorf("%w %q: reserved", security.ErrInvalidName, "CON"), CodeInvalidArgument},
+		{&policy.DeniedError{Rule: "deny-all", Tool: "write_file", Path: "/a"}, CodePolicyDenied},
+		{fmt.Errorf("walk: %w", context.DeadlineExceeded), CodeTimeout},
+		{context.Canceled, CodeCancelled},
+		{errors.New("disk on fire"), CodeInternal},
+	}
+	for _, tt := range tests {
+		if got := Code(tt.err); got != tt.want {
+			t.Errorf("Code(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestTextAndErrorPayloads(t *testing.T) {
+	res := Text("Successfully wrote to /a", map[string]interface{}{"path": "/a", "bytesWritten": 3})
+	if res.IsError || len(res.Content) != 2 || res.Content[0].(mcp.TextContent).Text != "Successfully wrote to /a" {
+		t.Fatalf("unexpected success result: %+v", res)
+	}
+	payload, ok := Payload(res)
+	if !ok {
+		t.Fatal("expected a JSON payload")
+	}
+	var written struct {
+		Path         string `json:"path"`
+		BytesWritten int    `json:"bytesWritten"`
+	}
+	if err := json.Unmarshal(payload, &written); err != nil || written.Path != "/a" || written.BytesWritten != 3 {
+		t.Fatalf("unexpected payload %s: %v", payload, err)
+	}
+
+	res = Error(fmt.Errorf("failed to stat file: %w", os.ErrNotExist))
+	if !res.IsError || res.Content[0].(mcp.TextContent).Text != "Error: failed to stat file: file does not exist" {
+		t.Fatalf("unexpected error result: %+v", res)
+	}
+	payload, _ = Payload(res)
+	var failed struct {
+		Error Failure `json:"error"`
+	}
+	if err := json.Unmarshal(payload, &failed); err != nil {
+		t.Fatalf("decode error payload: %v", err)
+	}
+	if failed.Error.Code != CodeNotFound || failed.Error.Message != "failed to stat file: file does not exist" {
+		t.Fatalf("unexpected error payload: %+v", failed.Error)
+	}
+
+	if _, ok := Payload(mcp.NewToolResultText("plain")); ok {
+		t.Fatal("a result without a payload should report none")
+	}
+}